@@ -2,17 +2,24 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"leave-management-system/internal/config"
 	"leave-management-system/internal/cron"
 	"leave-management-system/internal/database"
 	"leave-management-system/internal/handlers"
 	"leave-management-system/internal/middleware"
+	"leave-management-system/internal/realtime"
 	"leave-management-system/internal/services"
 	"leave-management-system/pkg/auth"
 	"leave-management-system/pkg/logger"
+	"leave-management-system/pkg/oidc"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -51,54 +58,162 @@ func main() {
 		appLogger.Fatal("Failed to migrate database", zap.Error(err))
 	}
 
-	// Create super admin if not exists
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("P@ssw0rd"), bcrypt.DefaultCost)
-	db.CreateSuperAdmin("superadmin@example.com", string(hashedPassword), "Super", "Admin")
-
 	// Initialize services
-	jwtManager := auth.NewJWTManager(cfg.JWT.SecretKey, cfg.JWT.AccessTokenTTL)
+	jwtManager, err := newJWTManager(cfg, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to load token signing keys", zap.Error(err))
+	}
 	auditLogger := logger.NewAuditLogger(appLogger)
 
 	holidayService := services.NewHolidayService(db.DB)
 	leaveTypeConfigService := services.NewLeaveTypeConfigService(db.DB)
+	blackoutService := services.NewBlackoutService(db.DB)
 
 	// Seed default leave type configs if none exist
 	if err := leaveTypeConfigService.SeedDefaultConfigs(); err != nil {
 		appLogger.Error("Failed to seed leave type configs", zap.Error(err))
 	}
 
-	leaveCalculator := services.NewLeaveCalculator(holidayService, leaveTypeConfigService)
-	leaveService := services.NewLeaveService(db.DB, leaveCalculator, auditLogger, holidayService, leaveTypeConfigService)
-	userService := services.NewUserService(db.DB, auditLogger, leaveTypeConfigService, leaveCalculator)
-	configService := services.NewConfigService(db.DB) // Initialize config service with DB
-	auditService := services.NewAuditService(db.DB)   // Initialize audit service with DB
-
+	templateService := services.NewTemplateService(db.DB, cfg.Email.TemplateDir, cfg.Email.AppURL)
 	emailService := services.NewEmailService(
 		cfg.Email.Host,
 		cfg.Email.Port,
 		cfg.Email.Username,
 		cfg.Email.Password,
 		cfg.Email.From,
+		templateService,
 	)
+	configService := services.NewConfigService(db.DB) // Initialize config service with DB
+	permissionService := services.NewPermissionService(db.DB)
+	if err := permissionService.SeedDefaults(); err != nil {
+		appLogger.Error("Failed to seed role permissions", zap.Error(err))
+	}
+	eventHub := realtime.NewHub(1000)
+
+	actionSecret, err := newActionSecret(cfg, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to load the action link secret", zap.Error(err))
+	}
+	actionTokenService := services.NewActionTokenService(db.DB, actionSecret, cfg.Actions.TTL, cfg.Server.PublicURL)
+
+	notificationService := services.NewNotificationService(db.DB, emailService, configService, actionTokenService, eventHub, permissionService)
+	if err := notificationService.SeedDefaultRules(cfg.Email.ManagerInvites); err != nil {
+		appLogger.Error("Failed to seed notification rules", zap.Error(err))
+	}
+
+	webhookService := services.NewWebhookService(db.DB, actionTokenService)
+
+	leaveCalculator := services.NewLeaveCalculator(holidayService, leaveTypeConfigService, blackoutService)
+	leaveService := services.NewLeaveService(db.DB, leaveCalculator, auditLogger, holidayService, leaveTypeConfigService,
+		configService, notificationService, webhookService, permissionService)
+	userStateCache := services.NewUserStateCache(db.DB, cfg.JWT.UserStateTTL)
+	passwordService := services.NewPasswordService(db.DB, configService)
+
+	// Create super admin if not exists, with a temporary password from config or a generated one
+	seedPassword := cfg.SuperAdmin.Password
+	if seedPassword == "" {
+		seedPassword = generateSeedPassword()
+	} else if err := passwordService.Policy().Validate(seedPassword); err != nil {
+		appLogger.Fatal("The configured super admin password is too weak", zap.Error(err))
+	}
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(seedPassword), bcrypt.DefaultCost)
+	created, err := db.CreateSuperAdmin(cfg.SuperAdmin.Email, string(hashedPassword), "Super", "Admin")
+	if err != nil {
+		appLogger.Error("Failed to create super admin", zap.Error(err))
+	} else if created && cfg.SuperAdmin.Password == "" {
+		// Printed once outside the structured log, so the password doesn't end up in log storage
+		fmt.Fprintf(os.Stderr, "Super admin %s created with temporary password: %s\n", cfg.SuperAdmin.Email, seedPassword)
+		appLogger.Warn("Created super admin with a generated temporary password printed to standard error, change it at first sign in",
+			zap.String("email", cfg.SuperAdmin.Email))
+	}
+
+	userService := services.NewUserService(db.DB, auditLogger, leaveTypeConfigService, leaveCalculator, userStateCache, passwordService)
+	auditService := services.NewAuditService(db.DB) // Initialize audit service with DB
+	calendarService := services.NewCalendarService(db.DB, holidayService, permissionService)
+	calendarFeedService := services.NewCalendarFeedService(db.DB, cfg.Server.PublicURL)
+	sessionService := services.NewSessionService(db.DB, jwtManager, userStateCache, cfg.JWT.RefreshTokenTTL)
+	lockoutService := services.NewLockoutService(db.DB, cfg.Login.MaxFailedAttempts, cfg.Login.LockoutDuration)
+	twoFactorKey := cfg.TwoFactor.EncryptionKey
+	if twoFactorKey == "" {
+		twoFactorKey = cfg.JWT.SecretKey
+	}
+	if twoFactorKey == "" && cfg.Server.Env == "production" {
+		appLogger.Fatal("No two-factor encryption key configured, set two_factor.encryption_key")
+	}
+	twoFactorService := services.NewTwoFactorService(db.DB, configService, lockoutService, cfg.TwoFactor.Issuer,
+		twoFactorKey, cfg.TwoFactor.ChallengeTTL)
+	ssoSettings := services.SSOSettings{
+		Enabled:      cfg.OIDC.Enabled,
+		ProviderName: cfg.OIDC.ProviderName,
+		OIDC: oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		},
+		MatchByEmail:         cfg.OIDC.MatchByEmail,
+		RequireVerifiedEmail: cfg.OIDC.RequireVerifiedEmail,
+		ProvisionUsers:       cfg.OIDC.ProvisionUsers,
+		DefaultRole:          models.UserRole(cfg.OIDC.DefaultRole),
+		GroupsClaim:          cfg.OIDC.GroupsClaim,
+		SyncRoles:            cfg.OIDC.SyncRoles,
+		DisablePasswordLogin: cfg.OIDC.DisablePasswordLogin,
+		TrustProviderMFA:     cfg.OIDC.TrustProviderMFA,
+		MFAACRValues:         cfg.OIDC.MFAACRValues,
+	}
+	if ssoSettings.OIDC.RedirectURL == "" {
+		ssoSettings.OIDC.RedirectURL = strings.TrimRight(cfg.Server.PublicURL, "/") + "/api/v1/sso/callback"
+	}
+	for _, mapping := range cfg.OIDC.RoleMappings {
+		ssoSettings.RoleMappings = append(ssoSettings.RoleMappings,
+			services.SSORoleMapping{Group: mapping.Group, Role: models.UserRole(mapping.Role)})
+	}
+	if err := ssoSettings.Validate(); err != nil {
+		appLogger.Fatal("Invalid single sign-on configuration", zap.Error(err))
+	}
+	ssoService := services.NewSSOService(db.DB, userService, ssoSettings)
+	apiKeyService := services.NewAPIKeyService(db.DB, cfg.APIKeys.DefaultTTL, cfg.APIKeys.MaxTTL)
+	passwordResetService := services.NewPasswordResetService(db.DB, emailService, notificationService, userStateCache,
+		passwordService, cfg.PasswordReset.TTL, cfg.Server.PublicURL, appLogger)
 
 	// Initialize cron jobs
-	cronJobs := cron.NewCronJobs(leaveService, emailService, appLogger)
+	cronJobs := cron.NewCronJobs(leaveService, notificationService, webhookService, sessionService, passwordResetService,
+		twoFactorService, ssoService, appLogger)
 	if err := cronJobs.Start(); err != nil {
 		appLogger.Error("Failed to start cron jobs", zap.Error(err))
 	}
 	defer cronJobs.Stop()
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService, jwtManager)
+	authHandler := handlers.NewAuthHandler(userService, sessionService, passwordService, lockoutService, twoFactorService,
+		ssoService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, userService)
+	ssoHandler := handlers.NewSSOHandler(ssoService, cfg.Email.AppURL)
+	jwksHandler := handlers.NewJWKSHandler(jwtManager)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(apiKeyService)
+	permissionHandler := handlers.NewPermissionHandler(permissionService)
 	leaveHandler := handlers.NewLeaveHandler(leaveService)
-	hrHandler := handlers.NewHRHandler(userService, leaveService)
+	hrHandler := handlers.NewHRHandler(userService, leaveService, passwordResetService, permissionService)
 
-	adminHandler := handlers.NewAdminHandler(holidayService, configService, leaveService, auditService, leaveTypeConfigService)
+	adminHandler := handlers.NewAdminHandler(holidayService, configService, leaveService, auditService, leaveTypeConfigService, blackoutService)
 	uploadHandler := handlers.NewUploadHandler()
+	calendarHandler := handlers.NewCalendarHandler(calendarService, calendarFeedService)
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(templateService, leaveService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	actionHandler := handlers.NewActionHandler(actionTokenService, leaveService)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService, cfg.Email.AppURL)
+	eventHandler := handlers.NewEventHandler(eventHub, notificationService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, sessionService, permissionService)
+	apiKeyMiddleware := middleware.NewAPIKeyMiddleware(apiKeyService)
 	auditMiddleware := middleware.NewAuditMiddleware(auditLogger, auditService)
+	// Separate budgets, so asking for links can't use up the attempts to spend one
+	forgotPasswordLimiter := middleware.NewRateLimiter(cfg.PasswordReset.RateLimit, time.Hour)
+	resetPasswordLimiter := middleware.NewRateLimiter(cfg.PasswordReset.RateLimit, time.Hour)
+	loginLimiter := middleware.NewRateLimiter(cfg.Login.RateLimit, time.Minute)
 
 	// Setup Gin router
 	if cfg.Server.Env == "production" {
@@ -106,21 +221,61 @@ func main() {
 	}
 
 	router := gin.New()
+	// Only trusted proxies may set the client IP, which rate limits and audit logs rely on
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		appLogger.Fatal("Invalid server.trusted_proxies", zap.Error(err))
+	}
 	router.Use(gin.Recovery())
 	router.Use(auditMiddleware.AuditLog())
 
 	// Static files
 	router.Static("/uploads", "./uploads")
 
+	// Keys other services verify our access tokens with
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	// Public routes
 	public := router.Group("/api/v1")
 	{
-		public.POST("/login", authHandler.Login)
+		public.POST("/login", loginLimiter.LimitByIP(), authHandler.Login)
+		public.POST("/login/2fa", loginLimiter.LimitByIP(), authHandler.LoginTwoFactor)
+		public.POST("/login/2fa/setup", loginLimiter.LimitByIP(), authHandler.LoginTwoFactorSetup)
+		public.GET("/sso/config", ssoHandler.GetConfig)
+		public.GET("/sso/login", loginLimiter.LimitByIP(), ssoHandler.Login)
+		public.GET("/sso/callback", ssoHandler.Callback)
+		public.POST("/sso/exchange", loginLimiter.LimitByIP(), authHandler.LoginSSO)
+		public.POST("/refresh", authHandler.Refresh)
+		public.POST("/logout", authHandler.Logout)
+		public.GET("/forgot-password", passwordResetHandler.ShowForgotPassword)
+		public.POST("/forgot-password", forgotPasswordLimiter.LimitByIP(), passwordResetHandler.ForgotPassword)
+		public.GET("/reset-password", passwordResetHandler.ShowResetPassword)
+		public.POST("/reset-password", resetPasswordLimiter.LimitByIP(), passwordResetHandler.ResetPassword)
+		// iCalendar subscription feeds authenticate with the token in the URL
+		public.GET("/calendar/feeds/:token", calendarHandler.GetCalendarFeed)
+		public.GET("/actions/:token", actionHandler.ShowAction)
+		public.POST("/actions/:token", actionHandler.PerformAction)
 		public.GET("/health", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 		})
 	}
 
+	// Server-Sent Events; EventSource cannot send headers so the token may be in the query string
+	router.GET("/api/v1/events", authMiddleware.AuthenticateStream(), eventHandler.Stream)
+
+	// Integration routes, for service accounts authenticating with a scoped API key
+	integrations := router.Group("/api/v1/integrations")
+	integrations.Use(apiKeyMiddleware.Authenticate())
+	{
+		integrations.GET("/reports/payroll", apiKeyMiddleware.RequireScope("reports:read"), hrHandler.ExportPayrollReport)
+		integrations.GET("/leave-requests", apiKeyMiddleware.RequireScope("leave:read"), hrHandler.GetLeaveRequests)
+		integrations.GET("/users", apiKeyMiddleware.RequireScope("users:read"), hrHandler.GetAllUsers)
+		integrations.GET("/users/:id", apiKeyMiddleware.RequireScope("users:read"), hrHandler.GetUser)
+		integrations.GET("/holidays", apiKeyMiddleware.RequireScope("holidays:read"), adminHandler.GetPublicHolidays)
+		integrations.POST("/holidays", apiKeyMiddleware.RequireScope("holidays:write"), adminHandler.CreatePublicHoliday)
+		integrations.PUT("/holidays/:id", apiKeyMiddleware.RequireScope("holidays:write"), adminHandler.UpdatePublicHoliday)
+		integrations.DELETE("/holidays/:id", apiKeyMiddleware.RequireScope("holidays:write"), adminHandler.DeletePublicHoliday)
+	}
+
 	// Protected routes
 	protected := router.Group("/api/v1")
 	protected.Use(authMiddleware.Authenticate())
@@ -137,78 +292,138 @@ func main() {
 		})
 		protected.PUT("/profile", authHandler.UpdateProfile)
 		protected.PUT("/change-password", authHandler.ChangePassword)
+		protected.POST("/logout-all", authHandler.LogoutAll)
+
+		// Two-factor authentication
+		protected.GET("/2fa", twoFactorHandler.GetStatus)
+		protected.POST("/2fa/setup", twoFactorHandler.StartSetup)
+		protected.POST("/2fa/confirm", twoFactorHandler.ConfirmSetup)
+		protected.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+		protected.POST("/2fa/disable", twoFactorHandler.Disable)
 
 		// Leave requests
 		protected.POST("/leave-requests", leaveHandler.CreateLeaveRequest)
 		protected.GET("/leave-requests", leaveHandler.GetMyLeaveRequests)
 		protected.GET("/leave-requests/:id", leaveHandler.GetLeaveRequest)
+		protected.PUT("/leave-requests/:id", leaveHandler.AmendLeaveRequest)
 		protected.GET("/leave-requests/:id/chronology", leaveHandler.GetLeaveRequestChronology)
 		protected.PUT("/leave-requests/:id/cancel", leaveHandler.CancelLeaveRequest)
 
 		protected.GET("/leave-balance", leaveHandler.GetLeaveBalance)
+		protected.GET("/calendar", calendarHandler.GetLeaveCalendar)
+		protected.POST("/calendar/feeds", calendarHandler.CreateCalendarFeed)
+		protected.GET("/calendar/feeds", calendarHandler.GetCalendarFeeds)
+		protected.DELETE("/calendar/feeds/:id", calendarHandler.RevokeCalendarFeed)
 		protected.POST("/upload", uploadHandler.UploadFile)
 
+		// In-app notifications
+		protected.GET("/notifications", notificationHandler.GetNotifications)
+		protected.GET("/notifications/unread-count", notificationHandler.GetUnreadCount)
+		protected.PUT("/notifications/read-all", notificationHandler.MarkAllNotificationsRead)
+		protected.PUT("/notifications/:id/read", notificationHandler.MarkNotificationRead)
+		protected.GET("/notifications/preferences", notificationHandler.GetNotificationPreferences)
+		protected.PUT("/notifications/preferences", notificationHandler.UpdateNotificationPreferences)
+
 		// Public holidays (accessible by all authenticated users for leave calculation)
 		protected.GET("/public-holidays", adminHandler.GetPublicHolidays)
 		// Leave type configs (accessible by all authenticated users)
 		protected.GET("/leave-type-configs", adminHandler.GetLeaveTypeConfigs)
+		// Upcoming blackout periods that apply to the current user
+		protected.GET("/blackout-periods", adminHandler.GetUpcomingBlackoutPeriods)
 
-		// Manager routes
-		manager := protected.Group("")
-		manager.Use(authMiddleware.RequireAnyRole([]models.UserRole{
-			models.RoleManager, models.RoleHOD, models.RoleHR, models.RoleAdmin, models.RoleSysAdmin,
-		}))
-		{
-			manager.GET("/team/leave-requests", leaveHandler.GetTeamLeaveRequests)
-			manager.PUT("/leave-requests/:id/approve", leaveHandler.ApproveLeaveRequest)
-			manager.PUT("/leave-requests/:id/reject", leaveHandler.RejectLeaveRequest)
-		}
+		// Leave approval
+		protected.GET("/team/leave-requests", authMiddleware.RequirePermission(models.PermLeaveApprove), leaveHandler.GetTeamLeaveRequests)
+		protected.PUT("/leave-requests/:id/approve", authMiddleware.RequirePermission(models.PermLeaveApprove), leaveHandler.ApproveLeaveRequest)
+		protected.PUT("/leave-requests/:id/reject", authMiddleware.RequirePermission(models.PermLeaveApprove), leaveHandler.RejectLeaveRequest)
 
 		// HR routes
 		hr := protected.Group("/hr")
-		hr.Use(authMiddleware.RequireAnyRole([]models.UserRole{
-			models.RoleHR, models.RoleAdmin, models.RoleSysAdmin,
-		}))
 		{
-			hr.GET("/users", hrHandler.GetAllUsers)
-			hr.GET("/users/:id", hrHandler.GetUser)
-			hr.POST("/users", hrHandler.CreateUser)
-			hr.PUT("/users/:id", hrHandler.UpdateUser)
-			hr.PUT("/users/:id/status", hrHandler.ToggleUserActive)
-			hr.PUT("/users/:id/probation", hrHandler.ConfirmProbation)
-			hr.PUT("/users/:id/leave-balance", hrHandler.UpdateLeaveBalance)
-			hr.GET("/leave-requests", hrHandler.GetLeaveRequests)
-			hr.GET("/payroll-report", hrHandler.ExportPayrollReport)
+			hr.GET("/users", authMiddleware.RequirePermission(models.PermUserView), hrHandler.GetAllUsers)
+			hr.GET("/users/:id", authMiddleware.RequirePermission(models.PermUserView), hrHandler.GetUser)
+			hr.POST("/users", authMiddleware.RequirePermission(models.PermUserCreate), hrHandler.CreateUser)
+			hr.PUT("/users/:id", authMiddleware.RequirePermission(models.PermUserUpdate), hrHandler.UpdateUser)
+			hr.PUT("/users/:id/status", authMiddleware.RequirePermission(models.PermUserUpdate), hrHandler.ToggleUserActive)
+			hr.POST("/users/:id/send-reset-link", authMiddleware.RequirePermission(models.PermUserUnlock), passwordResetHandler.SendResetLink)
+			hr.POST("/users/:id/unlock", authMiddleware.RequirePermission(models.PermUserUnlock), authHandler.UnlockAccount)
+			hr.PUT("/users/:id/probation", authMiddleware.RequirePermission(models.PermUserUpdate), hrHandler.ConfirmProbation)
+			hr.PUT("/users/:id/leave-balance", authMiddleware.RequirePermission(models.PermBalanceAdjust), hrHandler.UpdateLeaveBalance)
+			hr.GET("/leave-requests", authMiddleware.RequirePermission(models.PermLeaveViewAll), hrHandler.GetLeaveRequests)
+			hr.GET("/payroll-report", authMiddleware.RequirePermission(models.PermReportView), hrHandler.ExportPayrollReport)
 		}
 
 		// Admin routes
 		admin := protected.Group("/admin")
-		admin.Use(authMiddleware.RequireAnyRole([]models.UserRole{
-			models.RoleAdmin, models.RoleSysAdmin,
-		}))
 		{
-			admin.POST("/holidays", adminHandler.CreatePublicHoliday)
-			admin.GET("/holidays", adminHandler.GetPublicHolidays)
-			admin.PUT("/holidays/:id", adminHandler.UpdatePublicHoliday)
-			admin.DELETE("/holidays/:id", adminHandler.DeletePublicHoliday)
-			admin.GET("/config", adminHandler.GetSystemConfig)
-			admin.PUT("/config", adminHandler.UpdateSystemConfig)
-			admin.POST("/year-end-process", adminHandler.TriggerYearEndProcess)
-			admin.GET("/audit-logs", adminHandler.GetAuditLogs)
-			admin.GET("/leave-type-configs", adminHandler.GetLeaveTypeConfigs)
-			admin.PUT("/leave-type-configs/:type", adminHandler.UpdateLeaveTypeConfig)
+			holidays := admin.Group("", authMiddleware.RequirePermission(models.PermHolidayManage))
+			holidays.POST("/holidays", adminHandler.CreatePublicHoliday)
+			holidays.GET("/holidays", adminHandler.GetPublicHolidays)
+			holidays.PUT("/holidays/:id", adminHandler.UpdatePublicHoliday)
+			holidays.DELETE("/holidays/:id", adminHandler.DeletePublicHoliday)
+
+			settings := admin.Group("", authMiddleware.RequirePermission(models.PermConfigManage))
+			settings.GET("/config", adminHandler.GetSystemConfig)
+			settings.PUT("/config", adminHandler.UpdateSystemConfig)
+			settings.GET("/leave-type-configs", adminHandler.GetLeaveTypeConfigs)
+			settings.PUT("/leave-type-configs/:type", adminHandler.UpdateLeaveTypeConfig)
+
+			admin.POST("/year-end-process", authMiddleware.RequirePermission(models.PermYearEndRun), adminHandler.TriggerYearEndProcess)
+			admin.GET("/audit-logs", authMiddleware.RequirePermission(models.PermAuditView), adminHandler.GetAuditLogs)
+
+			blackouts := admin.Group("", authMiddleware.RequirePermission(models.PermBlackoutManage))
+			blackouts.POST("/blackout-periods", adminHandler.CreateBlackoutPeriod)
+			blackouts.GET("/blackout-periods", adminHandler.GetBlackoutPeriods)
+			blackouts.PUT("/blackout-periods/:id", adminHandler.UpdateBlackoutPeriod)
+			blackouts.DELETE("/blackout-periods/:id", adminHandler.DeleteBlackoutPeriod)
+
+			notifications := admin.Group("", authMiddleware.RequirePermission(models.PermNotificationManage))
+			notifications.GET("/email-templates", emailTemplateHandler.GetEmailTemplates)
+			notifications.GET("/email-templates/:name/:locale", emailTemplateHandler.GetEmailTemplate)
+			notifications.PUT("/email-templates/:name/:locale", emailTemplateHandler.UpdateEmailTemplate)
+			notifications.DELETE("/email-templates/:name/:locale", emailTemplateHandler.ResetEmailTemplate)
+			notifications.POST("/email-templates/:name/:locale/preview", emailTemplateHandler.PreviewEmailTemplate)
+			notifications.GET("/notification-rules", notificationHandler.GetNotificationRules)
+			notifications.PUT("/notification-rules/:event/:recipient", notificationHandler.UpdateNotificationRule)
+			notifications.GET("/notifications/outbox", notificationHandler.GetOutbox)
+			notifications.POST("/notifications/outbox/:id/resend", notificationHandler.ResendOutboxMessage)
+
+			webhooks := admin.Group("", authMiddleware.RequirePermission(models.PermWebhookManage))
+			webhooks.POST("/webhooks", webhookHandler.CreateWebhook)
+			webhooks.GET("/webhooks", webhookHandler.GetWebhooks)
+			webhooks.GET("/webhooks/:id", webhookHandler.GetWebhook)
+			webhooks.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+			webhooks.POST("/webhooks/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+			webhooks.POST("/webhooks/:id/ping", webhookHandler.PingWebhook)
+			webhooks.GET("/webhooks/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+			webhooks.GET("/webhook-deliveries/:id", webhookHandler.GetWebhookDelivery)
+			webhooks.POST("/webhook-deliveries/:id/redeliver", webhookHandler.RedeliverWebhook)
+
+			admin.DELETE("/users/:id/2fa", authMiddleware.RequirePermission(models.PermUserResetTwoFactor), twoFactorHandler.ResetUser)
+
+			serviceAccounts := admin.Group("", authMiddleware.RequirePermission(models.PermServiceAccountManage))
+			serviceAccounts.POST("/service-accounts", serviceAccountHandler.CreateServiceAccount)
+			serviceAccounts.GET("/service-accounts", serviceAccountHandler.GetServiceAccounts)
+			serviceAccounts.GET("/service-accounts/:id", serviceAccountHandler.GetServiceAccount)
+			serviceAccounts.PUT("/service-accounts/:id", serviceAccountHandler.UpdateServiceAccount)
+			serviceAccounts.POST("/service-accounts/:id/keys", serviceAccountHandler.CreateAPIKey)
+			serviceAccounts.DELETE("/service-accounts/:id/keys/:key_id", serviceAccountHandler.RevokeAPIKey)
 		}
 
 		// SysAdmin routes
 		sysadmin := protected.Group("/sysadmin")
-		sysadmin.Use(authMiddleware.RequireRole(models.RoleSysAdmin))
 		{
+			// Which permissions each role holds
+			sysadmin.GET("/permissions", authMiddleware.RequirePermission(models.PermPermissionManage), permissionHandler.GetRolePermissions)
+			sysadmin.PUT("/permissions/:role", authMiddleware.RequirePermission(models.PermPermissionManage), permissionHandler.UpdateRolePermissions)
+
 			// System maintenance endpoints
-			sysadmin.POST("/force-escalation-check", func(c *gin.Context) {
+			maintenance := sysadmin.Group("", authMiddleware.RequirePermission(models.PermSystemMaintain))
+			maintenance.POST("/force-escalation-check", func(c *gin.Context) {
 				cronJobs.TriggerEscalationCheck()
 				c.JSON(http.StatusOK, gin.H{"message": "Escalation check triggered"})
 			})
-			sysadmin.GET("/system-metrics", func(c *gin.Context) {
+			maintenance.GET("/system-metrics", func(c *gin.Context) {
 				// Return system metrics
 				c.JSON(http.StatusOK, gin.H{"metrics": "system_metrics_here"})
 			})
@@ -249,3 +464,80 @@ func main() {
 
 	appLogger.Info("Server exited properly")
 }
+
+// generateSeedPassword returns a random temporary password for the first super admin
+func generateSeedPassword() string {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newActionSecret returns the key that signs emailed approve and reject links. Development without
+// one gets a random key, so links stop working when the server restarts; production refuses to
+// start without it.
+func newActionSecret(cfg *config.Config, appLogger *zap.Logger) (string, error) {
+	secret := cfg.Actions.SecretKey
+	if secret == "" {
+		secret = cfg.JWT.SecretKey
+	}
+	if secret != "" {
+		return secret, nil
+	}
+
+	if cfg.Server.Env == "production" {
+		return "", errors.New("no action link secret configured, set actions.secret_key")
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	appLogger.Warn("No action link secret configured, using a temporary key; emailed action links stop working when the server restarts")
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// newJWTManager loads the token signing keys. Development without keys gets a temporary one, so
+// tokens stop working when the server restarts; production refuses to start without them.
+func newJWTManager(cfg *config.Config, appLogger *zap.Logger) (*auth.JWTManager, error) {
+	issuer := cfg.JWT.Issuer
+	if issuer == "" {
+		issuer = cfg.Server.PublicURL
+	}
+
+	if len(cfg.JWT.Keys) == 0 {
+		if cfg.Server.Env == "production" {
+			return nil, errors.New("no signing keys configured, set jwt.keys and jwt.active_key_id")
+		}
+		key, err := auth.GenerateSigningKey("dev-" + uuid.NewString()[:8])
+		if err != nil {
+			return nil, err
+		}
+		appLogger.Warn("No token signing keys configured, using a temporary key; sessions end when the server restarts",
+			zap.String("kid", key.ID))
+		return auth.NewJWTManager([]*auth.SigningKey{key}, key.ID, issuer, cfg.JWT.AccessTokenTTL)
+	}
+
+	keys := make([]*auth.SigningKey, 0, len(cfg.JWT.Keys))
+	for _, kc := range cfg.JWT.Keys {
+		var key *auth.SigningKey
+		var err error
+		switch {
+		case kc.PrivateKey != "":
+			key, err = auth.ParseSigningKey(kc.ID, []byte(kc.PrivateKey))
+		case kc.PrivateKeyFile != "":
+			key, err = auth.LoadSigningKey(kc.ID, kc.PrivateKeyFile)
+		case kc.PublicKey != "":
+			key, err = auth.ParseSigningKey(kc.ID, []byte(kc.PublicKey))
+		case kc.PublicKeyFile != "":
+			key, err = auth.LoadSigningKey(kc.ID, kc.PublicKeyFile)
+		default:
+			err = fmt.Errorf("signing key %q has no key material", kc.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return auth.NewJWTManager(keys, cfg.JWT.ActiveKeyID, issuer, cfg.JWT.AccessTokenTTL)
+}
@@ -0,0 +1,72 @@
+// Command webhook-receiver is a local endpoint for trying out outgoing webhooks.
+// It verifies each delivery's signature and logs the payload.
+//
+//	WEBHOOK_SECRET=<secret> go run ./cmd/webhook-receiver -addr :9090
+//
+// Register http://host.docker.internal:9090/webhook (or http://localhost:9090/webhook when the API
+// runs on the host) as the webhook URL. Pass -fail to answer with 500 and exercise retries.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"leave-management-system/pkg/webhook"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "signing secret returned when the webhook was created")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum allowed clock skew of the signature timestamp")
+	fail := flag.Bool("fail", false, "respond with 500 to every delivery")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Println("No secret configured, signatures will not be verified")
+	}
+
+	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		event := r.Header.Get(webhook.EventHeader)
+		delivery := r.Header.Get(webhook.DeliveryHeader)
+
+		if *secret != "" {
+			err := webhook.Verify(*secret, r.Header.Get(webhook.SignatureHeader), r.Header.Get(webhook.TimestampHeader), body, *tolerance)
+			if err != nil {
+				log.Printf("Rejected %s delivery %s: %v", event, delivery, err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			pretty.Write(body)
+		}
+		log.Printf("Received %s delivery %s\n%s", event, delivery, pretty.String())
+
+		if *fail {
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
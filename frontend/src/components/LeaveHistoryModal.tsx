@@ -12,7 +12,7 @@ interface ChronologyEntry {
         first_name: string;
         last_name: string;
         email: string;
-    };
+    } | null;
     comment: string;
     created_at: string;
 }
@@ -102,7 +102,7 @@ const LeaveHistoryModal: React.FC<LeaveHistoryModalProps> = ({ isOpen, onClose,
                                                 </span>
                                             </div>
                                             <p className="text-sm font-medium text-slate-900">
-                                                {entry.actor?.first_name} {entry.actor?.last_name}
+                                                {entry.actor ? `${entry.actor.first_name} ${entry.actor.last_name}` : 'System'}
                                             </p>
                                             {entry.comment && (
                                                 <p className="text-sm text-slate-600 mt-1 italic">
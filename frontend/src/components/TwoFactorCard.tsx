@@ -0,0 +1,161 @@
+import React, { useEffect, useState } from 'react';
+import { ShieldCheck } from 'lucide-react';
+import api from '../services/api';
+import { Card } from './ui/Card';
+import { Button } from './ui/Button';
+import { Input } from './ui/Input';
+
+interface TwoFactorStatus {
+    enabled: boolean;
+    required: boolean;
+    recovery_codes_remaining: number;
+}
+
+interface TwoFactorEnrollment {
+    secret: string;
+    provisioning_uri: string;
+}
+
+const TwoFactorCard: React.FC = () => {
+    const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+    const [enrollment, setEnrollment] = useState<TwoFactorEnrollment | null>(null);
+    const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+    const [code, setCode] = useState('');
+    const [password, setPassword] = useState('');
+    const [disabling, setDisabling] = useState(false);
+    const [error, setError] = useState('');
+    const [isSubmitting, setIsSubmitting] = useState(false);
+
+    const fetchStatus = async () => {
+        try {
+            const response = await api.get('/2fa');
+            setStatus(response.data);
+        } catch (err) {
+            console.error("Failed to fetch two-factor status", err);
+        }
+    };
+
+    useEffect(() => {
+        fetchStatus();
+    }, []);
+
+    const run = async (action: () => Promise<void>) => {
+        setError('');
+        setIsSubmitting(true);
+        try {
+            await action();
+        } catch (err: any) {
+            setError(err.response?.data?.error || "Request failed");
+        } finally {
+            setIsSubmitting(false);
+            setCode('');
+        }
+    };
+
+    const startSetup = () => run(async () => {
+        const response = await api.post('/2fa/setup');
+        setEnrollment(response.data);
+        setRecoveryCodes(null);
+    });
+
+    const confirmSetup = () => run(async () => {
+        const response = await api.post('/2fa/confirm', { code });
+        setEnrollment(null);
+        setRecoveryCodes(response.data.recovery_codes);
+        await fetchStatus();
+    });
+
+    const regenerateCodes = () => run(async () => {
+        const response = await api.post('/2fa/recovery-codes', { code });
+        setRecoveryCodes(response.data.recovery_codes);
+        await fetchStatus();
+    });
+
+    const disable = () => run(async () => {
+        await api.post('/2fa/disable', { password, code });
+        setDisabling(false);
+        setPassword('');
+        setRecoveryCodes(null);
+        await fetchStatus();
+    });
+
+    if (!status) return null;
+
+    return (
+        <Card className="p-6">
+            <h3 className="text-lg font-semibold text-slate-900 mb-6 flex items-center gap-2 pb-4 border-b border-slate-100">
+                <ShieldCheck className="h-5 w-5 text-brand-500" />
+                Two-Factor Authentication
+            </h3>
+
+            <div className="space-y-4 max-w-lg">
+                {error && <div className="p-3 bg-red-50 text-red-700 rounded-lg border border-red-200 text-sm">{error}</div>}
+
+                {recoveryCodes && (
+                    <div className="space-y-3">
+                        <p className="text-sm text-amber-800 p-3 bg-amber-50 rounded-lg border border-amber-200">
+                            Save these recovery codes somewhere safe. Each one signs you in once if you lose your
+                            authenticator, and they won't be shown again.
+                        </p>
+                        <div className="grid grid-cols-2 gap-2 font-mono text-sm text-slate-800">
+                            {recoveryCodes.map((c) => <div key={c} className="p-2 bg-slate-50 rounded">{c}</div>)}
+                        </div>
+                    </div>
+                )}
+
+                {!status.enabled && !enrollment && (
+                    <>
+                        <p className="text-sm text-slate-600">
+                            {status.required
+                                ? "Your role requires two-factor authentication."
+                                : "Protect your account with a code from an authenticator app when you sign in."}
+                        </p>
+                        <div className="flex justify-end">
+                            <Button onClick={startSetup} isLoading={isSubmitting}>Set Up</Button>
+                        </div>
+                    </>
+                )}
+
+                {!status.enabled && enrollment && (
+                    <>
+                        <p className="text-sm text-slate-600">
+                            Add this account to your authenticator app, then enter the code it shows.
+                        </p>
+                        <a href={enrollment.provisioning_uri} className="text-sm font-medium text-brand-600 hover:underline">
+                            Open in authenticator app
+                        </a>
+                        <p className="font-mono text-sm text-slate-900 break-all p-2 bg-slate-50 rounded">{enrollment.secret}</p>
+                        <Input value={code} onChange={(e) => setCode(e.target.value)} placeholder="123456" autoComplete="one-time-code" />
+                        <div className="flex justify-end">
+                            <Button onClick={confirmSetup} isLoading={isSubmitting} disabled={!code}>Turn On</Button>
+                        </div>
+                    </>
+                )}
+
+                {status.enabled && (
+                    <>
+                        <p className="text-sm text-slate-600">
+                            Two-factor authentication is on. {status.recovery_codes_remaining} recovery codes remaining.
+                        </p>
+                        {disabling && (
+                            <Input type="password" value={password} onChange={(e) => setPassword(e.target.value)} placeholder="Current password" />
+                        )}
+                        <Input value={code} onChange={(e) => setCode(e.target.value)} placeholder="Authenticator or recovery code" autoComplete="one-time-code" />
+                        <div className="flex justify-end gap-3">
+                            {!status.required && (
+                                disabling
+                                    ? <Button variant="outline" onClick={disable} isLoading={isSubmitting} disabled={!code || !password}>Turn Off</Button>
+                                    : <Button variant="outline" onClick={() => setDisabling(true)}>Turn Off</Button>
+                            )}
+                            {!disabling && (
+                                <Button onClick={regenerateCodes} isLoading={isSubmitting} disabled={!code}>New Recovery Codes</Button>
+                            )}
+                        </div>
+                    </>
+                )}
+            </div>
+        </Card>
+    );
+};
+
+export default TwoFactorCard;
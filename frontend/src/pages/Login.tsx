@@ -1,10 +1,10 @@
-import React, { useState } from 'react';
+import React, { useEffect, useRef, useState } from 'react';
 import { useForm } from 'react-hook-form';
 import { zodResolver } from '@hookform/resolvers/zod';
 import { z } from 'zod';
-import { useNavigate } from 'react-router-dom';
+import { useNavigate, useSearchParams } from 'react-router-dom';
 import { motion } from 'framer-motion';
-import { Lock, Mail } from 'lucide-react';
+import { KeyRound, Lock, Mail } from 'lucide-react';
 import { useAuth } from '../context/AuthContext';
 import api from '../services/api';
 import { Button } from '../components/ui/Button';
@@ -18,11 +18,29 @@ const loginSchema = z.object({
 
 type LoginFormData = z.infer<typeof loginSchema>;
 
+interface TwoFactorChallenge {
+    token: string;
+    enrollmentRequired: boolean;
+}
+
+interface TwoFactorEnrollment {
+    secret: string;
+    provisioning_uri: string;
+}
+
 const Login: React.FC = () => {
     const { login } = useAuth();
     const navigate = useNavigate();
     const [error, setError] = useState<string | null>(null);
     const [isLoading, setIsLoading] = useState(false);
+    const [challenge, setChallenge] = useState<TwoFactorChallenge | null>(null);
+    const [enrollment, setEnrollment] = useState<TwoFactorEnrollment | null>(null);
+    const [code, setCode] = useState('');
+    const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+    const [nextPath, setNextPath] = useState('/dashboard');
+    const [sso, setSso] = useState<{ enabled: boolean; provider_name: string } | null>(null);
+    const [searchParams, setSearchParams] = useSearchParams();
+    const ssoExchanged = useRef(false);
 
     const { register, handleSubmit, formState: { errors } } = useForm<LoginFormData>({
         resolver: zodResolver(loginSchema),
@@ -33,8 +51,7 @@ const Login: React.FC = () => {
         setError(null);
         try {
             const response = await api.post('/login', data);
-            login(response.data.token, response.data.user);
-            navigate('/dashboard');
+            await handleSignIn(response.data);
         } catch (err: any) {
             setError(err.response?.data?.error || 'Failed to login');
         } finally {
@@ -42,6 +59,73 @@ const Login: React.FC = () => {
         }
     };
 
+    // The first factor was right; tokens come now or after the second factor
+    const handleSignIn = async (data: any) => {
+        if (data.two_factor_required) {
+            setChallenge({ token: data.challenge_token, enrollmentRequired: data.enrollment_required });
+            if (data.enrollment_required) {
+                const setup = await api.post('/login/2fa/setup', { challenge_token: data.challenge_token });
+                setEnrollment(setup.data);
+            }
+            return;
+        }
+        completeLogin(data);
+    };
+
+    useEffect(() => {
+        api.get('/sso/config').then((response) => setSso(response.data)).catch(() => setSso(null));
+    }, []);
+
+    // Coming back from the identity provider with a one-time handoff token, or an error
+    useEffect(() => {
+        const handoff = searchParams.get('sso');
+        const ssoError = searchParams.get('sso_error');
+        if (ssoError) {
+            setError(ssoError);
+            setSearchParams({}, { replace: true });
+        }
+        if (!handoff || ssoExchanged.current) return;
+        ssoExchanged.current = true;
+        setSearchParams({}, { replace: true });
+        setIsLoading(true);
+        api.post('/sso/exchange', { handoff_token: handoff })
+            .then((response) => handleSignIn(response.data))
+            .catch((err: any) => setError(err.response?.data?.error || 'Single sign-on failed'))
+            .finally(() => setIsLoading(false));
+    }, [searchParams]);
+
+    const completeLogin = (data: any) => {
+        login(data.token, data.user, data.refresh_token);
+        const path = data.user.must_change_password ? '/profile' : '/dashboard';
+        if (data.recovery_codes?.length) {
+            // Shown once, so hold the user here until they have saved them
+            setRecoveryCodes(data.recovery_codes);
+            setNextPath(path);
+            return;
+        }
+        navigate(path);
+    };
+
+    const onSubmitCode = async (e: React.FormEvent) => {
+        e.preventDefault();
+        if (!challenge) return;
+        setIsLoading(true);
+        setError(null);
+        try {
+            const response = await api.post('/login/2fa', { challenge_token: challenge.token, code });
+            completeLogin(response.data);
+        } catch (err: any) {
+            if (err.response?.status === 401 && err.response?.data?.error?.includes('expired')) {
+                setChallenge(null);
+                setEnrollment(null);
+            }
+            setError(err.response?.data?.error || 'Failed to verify code');
+        } finally {
+            setIsLoading(false);
+            setCode('');
+        }
+    };
+
     return (
         <div className="min-h-screen grid lg:grid-cols-2 bg-white">
             {/* Left: Brand & Pattern */}
@@ -101,67 +185,143 @@ const Login: React.FC = () => {
                         </p>
                     </div>
 
-                    <form onSubmit={handleSubmit(onSubmit)} className="space-y-8">
-                        {error && (
-                            <motion.div
-                                initial={{ opacity: 0, height: 0 }}
-                                animate={{ opacity: 1, height: 'auto' }}
-                                className="p-4 rounded-lg bg-red-50 text-red-600 text-sm border border-red-100 flex items-center gap-3"
-                            >
-                                <div className="h-2 w-2 rounded-full bg-red-600 flex-shrink-0" />
-                                {error}
-                            </motion.div>
-                        )}
-
+                    {recoveryCodes ? (
                         <div className="space-y-6">
+                            <div className="p-4 rounded-lg bg-amber-50 text-amber-800 text-sm border border-amber-200">
+                                Two-factor authentication is on. Save these recovery codes somewhere safe: each one
+                                signs you in once if you lose your authenticator, and they won't be shown again.
+                            </div>
+                            <div className="grid grid-cols-2 gap-2 font-mono text-sm text-slate-800">
+                                {recoveryCodes.map((c) => <div key={c} className="p-2 bg-slate-50 rounded">{c}</div>)}
+                            </div>
+                            <Button className="w-full h-12 rounded-xl" size="lg" onClick={() => navigate(nextPath)}>
+                                Continue
+                            </Button>
+                        </div>
+                    ) : challenge ? (
+                        <form onSubmit={onSubmitCode} className="space-y-8">
+                            {error && (
+                                <div className="p-4 rounded-lg bg-red-50 text-red-600 text-sm border border-red-100">
+                                    {error}
+                                </div>
+                            )}
+
+                            {challenge.enrollmentRequired ? (
+                                <div className="space-y-3 text-sm text-slate-600">
+                                    <p>Your role requires two-factor authentication. Add this account to your authenticator app, then enter the code it shows.</p>
+                                    {enrollment && (
+                                        <>
+                                            <a href={enrollment.provisioning_uri} className="font-medium text-brand-600 hover:underline break-all">
+                                                Open in authenticator app
+                                            </a>
+                                            <p>Or enter this key manually:</p>
+                                            <p className="font-mono text-slate-900 break-all p-2 bg-slate-50 rounded">{enrollment.secret}</p>
+                                        </>
+                                    )}
+                                </div>
+                            ) : (
+                                <p className="text-sm text-slate-600">
+                                    Enter the code from your authenticator app, or one of your recovery codes.
+                                </p>
+                            )}
+
                             <div className="space-y-2">
-                                <label className="text-sm font-medium text-slate-700">
-                                    Email Address
-                                </label>
+                                <label className="text-sm font-medium text-slate-700">Authentication Code</label>
                                 <div className="relative group">
-                                    <Mail className="absolute left-3.5 top-3.5 h-5 w-5 text-slate-400 group-focus-within:text-brand-600 transition-colors" />
+                                    <KeyRound className="absolute left-3.5 top-3.5 h-5 w-5 text-slate-400 group-focus-within:text-brand-600 transition-colors" />
                                     <Input
-                                        placeholder="name@company.com"
+                                        autoFocus
+                                        autoComplete="one-time-code"
+                                        placeholder="123456"
+                                        value={code}
+                                        onChange={(e) => setCode(e.target.value)}
                                         className="pl-11 h-12 bg-white border-slate-200 focus:border-brand-500 focus:ring-4 focus:ring-brand-500/10 transition-all duration-200 rounded-xl"
-                                        error={errors.email?.message}
-                                        {...register('email')}
                                     />
                                 </div>
                             </div>
 
-                            <div className="space-y-2">
-                                <div className="flex items-center justify-between">
+                            <Button
+                                type="submit"
+                                className="w-full h-12 text-base font-semibold shadow-xl shadow-brand-500/20 hover:shadow-brand-500/30 transition-all duration-300 rounded-xl"
+                                size="lg"
+                                isLoading={isLoading}
+                                disabled={!code}
+                            >
+                                Verify
+                            </Button>
+                        </form>
+                    ) : (
+                        <form onSubmit={handleSubmit(onSubmit)} className="space-y-8">
+                            {error && (
+                                <motion.div
+                                    initial={{ opacity: 0, height: 0 }}
+                                    animate={{ opacity: 1, height: 'auto' }}
+                                    className="p-4 rounded-lg bg-red-50 text-red-600 text-sm border border-red-100 flex items-center gap-3"
+                                >
+                                    <div className="h-2 w-2 rounded-full bg-red-600 flex-shrink-0" />
+                                    {error}
+                                </motion.div>
+                            )}
+
+                            <div className="space-y-6">
+                                <div className="space-y-2">
                                     <label className="text-sm font-medium text-slate-700">
-                                        Password
+                                        Email Address
                                     </label>
+                                    <div className="relative group">
+                                        <Mail className="absolute left-3.5 top-3.5 h-5 w-5 text-slate-400 group-focus-within:text-brand-600 transition-colors" />
+                                        <Input
+                                            placeholder="name@company.com"
+                                            className="pl-11 h-12 bg-white border-slate-200 focus:border-brand-500 focus:ring-4 focus:ring-brand-500/10 transition-all duration-200 rounded-xl"
+                                            error={errors.email?.message}
+                                            {...register('email')}
+                                        />
+                                    </div>
                                 </div>
-                                <div className="relative group">
-                                    <Lock className="absolute left-3.5 top-3.5 h-5 w-5 text-slate-400 group-focus-within:text-brand-600 transition-colors" />
-                                    <Input
-                                        type="password"
-                                        placeholder="••••••••"
-                                        className="pl-11 h-12 bg-white border-slate-200 focus:border-brand-500 focus:ring-4 focus:ring-brand-500/10 transition-all duration-200 rounded-xl"
-                                        error={errors.password?.message}
-                                        {...register('password')}
-                                    />
-                                </div>
-                                <div className="flex justify-end">
-                                    <a href="#" className="text-sm font-medium text-brand-600 hover:text-brand-700 hover:underline">
-                                        Forgot password?
-                                    </a>
+
+                                <div className="space-y-2">
+                                    <div className="flex items-center justify-between">
+                                        <label className="text-sm font-medium text-slate-700">
+                                            Password
+                                        </label>
+                                    </div>
+                                    <div className="relative group">
+                                        <Lock className="absolute left-3.5 top-3.5 h-5 w-5 text-slate-400 group-focus-within:text-brand-600 transition-colors" />
+                                        <Input
+                                            type="password"
+                                            placeholder="••••••••"
+                                            className="pl-11 h-12 bg-white border-slate-200 focus:border-brand-500 focus:ring-4 focus:ring-brand-500/10 transition-all duration-200 rounded-xl"
+                                            error={errors.password?.message}
+                                            {...register('password')}
+                                        />
+                                    </div>
+                                    <div className="flex justify-end">
+                                        <a href="/api/v1/forgot-password" className="text-sm font-medium text-brand-600 hover:text-brand-700 hover:underline">
+                                            Forgot password?
+                                        </a>
+                                    </div>
                                 </div>
                             </div>
-                        </div>
 
-                        <Button
-                            type="submit"
-                            className="w-full h-12 text-base font-semibold shadow-xl shadow-brand-500/20 hover:shadow-brand-500/30 transition-all duration-300 rounded-xl"
-                            size="lg"
-                            isLoading={isLoading}
-                        >
-                            Sign In
-                        </Button>
-                    </form>
+                            <Button
+                                type="submit"
+                                className="w-full h-12 text-base font-semibold shadow-xl shadow-brand-500/20 hover:shadow-brand-500/30 transition-all duration-300 rounded-xl"
+                                size="lg"
+                                isLoading={isLoading}
+                            >
+                                Sign In
+                            </Button>
+
+                            {sso?.enabled && (
+                                <a
+                                    href="/api/v1/sso/login"
+                                    className="flex items-center justify-center w-full h-12 text-base font-semibold rounded-xl border border-slate-200 text-slate-700 hover:bg-slate-50 transition-colors"
+                                >
+                                    Sign in with {sso.provider_name}
+                                </a>
+                            )}
+                        </form>
+                    )}
 
                     <p className="text-center text-sm text-slate-500">
                         Don't have an account?{' '}
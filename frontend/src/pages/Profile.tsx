@@ -5,6 +5,7 @@ import api from '../services/api';
 import { Card } from '../components/ui/Card';
 import { Button } from '../components/ui/Button';
 import { Input } from '../components/ui/Input';
+import TwoFactorCard from '../components/TwoFactorCard';
 import { format } from 'date-fns';
 import type { User as UserType } from '../types';
 
@@ -75,10 +76,15 @@ const Profile: React.FC = () => {
         }
 
         try {
-            await api.put('/change-password', {
+            const response = await api.put('/change-password', {
                 current_password: data.old_password,
                 new_password: data.new_password
             });
+            // Changing the password ends every session, so keep the fresh one it returns
+            localStorage.setItem('token', response.data.token);
+            localStorage.setItem('refresh_token', response.data.refresh_token);
+            localStorage.setItem('user', JSON.stringify(response.data.user));
+            setUser(response.data.user);
             setMessage("Password changed successfully");
             resetPassword();
         } catch (err: any) {
@@ -96,6 +102,11 @@ const Profile: React.FC = () => {
                 <p className="text-slate-500 mt-1">Manage your account settings</p>
             </div>
 
+            {user.must_change_password && (
+                <div className="p-4 bg-amber-50 text-amber-800 rounded-lg border border-amber-200">
+                    Your password is temporary or has expired. Choose a new password to continue.
+                </div>
+            )}
             {message && <div className="p-4 bg-green-50 text-green-700 rounded-lg border border-green-200">{message}</div>}
             {error && <div className="p-4 bg-red-50 text-red-700 rounded-lg border border-red-200">{error}</div>}
 
@@ -207,7 +218,7 @@ const Profile: React.FC = () => {
                     </Card>
                 </div>
 
-                <div className="md:col-span-2">
+                <div className="md:col-span-2 space-y-6">
                     <Card className="p-6">
                         <h3 className="text-lg font-semibold text-slate-900 mb-6 flex items-center gap-2 pb-4 border-b border-slate-100">
                             <Lock className="h-5 w-5 text-brand-500" />
@@ -247,6 +258,7 @@ const Profile: React.FC = () => {
                             </div>
                         </form>
                     </Card>
+                    <TwoFactorCard />
                 </div>
             </div>
         </div>
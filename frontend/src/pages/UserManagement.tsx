@@ -10,6 +10,7 @@ import { Modal } from '../components/ui/Modal';
 import { Badge } from '../components/ui/Badge';
 import { format } from 'date-fns';
 import { useToast } from '../components/ui/Toast';
+import { useAuth } from '../context/AuthContext';
 
 const UserManagement: React.FC = () => {
     const [users, setUsers] = useState<User[]>([]);
@@ -383,6 +384,47 @@ const EditUserModal = ({ user, isOpen, onClose, onSuccess }: { user: User, isOpe
         }
     };
 
+    const handleSendResetLink = async () => {
+        setError('');
+        setMessage('');
+        try {
+            await api.post(`/hr/users/${user.id}/send-reset-link`);
+            setMessage(`Password reset link sent to ${user.email}`);
+        } catch (err: any) {
+            setError(err.response?.data?.error || "Failed to send reset link");
+        }
+    };
+
+    const { user: currentUser } = useAuth();
+    const isLocked = !!fullUser.locked_until && new Date(fullUser.locked_until) > new Date();
+    // Only a sysadmin can reset another sysadmin's second factor
+    const canResetTwoFactor = fullUser.two_factor_enabled && (currentUser?.role === 'sysadmin'
+        || (currentUser?.role === 'admin' && fullUser.role !== 'sysadmin'));
+
+    const handleResetTwoFactor = async () => {
+        setError('');
+        setMessage('');
+        try {
+            await api.delete(`/admin/users/${user.id}/2fa`);
+            setFullUser({ ...fullUser, two_factor_enabled: false });
+            setMessage("Two-factor authentication reset");
+        } catch (err: any) {
+            setError(err.response?.data?.error || "Failed to reset two-factor authentication");
+        }
+    };
+
+    const handleUnlock = async () => {
+        setError('');
+        setMessage('');
+        try {
+            await api.post(`/hr/users/${user.id}/unlock`);
+            setFullUser({ ...fullUser, locked_until: undefined, failed_login_count: 0 });
+            setMessage("Account unlocked");
+        } catch (err: any) {
+            setError(err.response?.data?.error || "Failed to unlock account");
+        }
+    };
+
     const handleConfirmProbation = async (data: any) => {
         setError('');
         setMessage('');
@@ -541,6 +583,56 @@ const EditUserModal = ({ user, isOpen, onClose, onSuccess }: { user: User, isOpe
                                 {isActive ? 'Deactivate' : 'Activate'}
                             </button>
                         </div>
+                        <div className="flex items-center justify-between p-3 bg-slate-50 rounded-lg">
+                            <div>
+                                <div className="text-sm font-medium text-slate-700">Password</div>
+                                <div className="text-xs text-slate-500">
+                                    Email the user a one-time link to choose a new password
+                                </div>
+                            </div>
+                            <button
+                                type="button"
+                                onClick={handleSendResetLink}
+                                disabled={!isActive}
+                                className="px-4 py-2 rounded-lg text-sm font-medium transition-colors bg-slate-200 text-slate-700 hover:bg-slate-300 disabled:opacity-50"
+                            >
+                                Send Reset Link
+                            </button>
+                        </div>
+                        {canResetTwoFactor && (
+                            <div className="flex items-center justify-between p-3 bg-slate-50 rounded-lg">
+                                <div>
+                                    <div className="text-sm font-medium text-slate-700">Two-factor authentication</div>
+                                    <div className="text-xs text-slate-500">
+                                        Remove the user's authenticator and recovery codes if they have lost them
+                                    </div>
+                                </div>
+                                <button
+                                    type="button"
+                                    onClick={handleResetTwoFactor}
+                                    className="px-4 py-2 rounded-lg text-sm font-medium transition-colors bg-slate-200 text-slate-700 hover:bg-slate-300"
+                                >
+                                    Reset 2FA
+                                </button>
+                            </div>
+                        )}
+                        {isLocked && (
+                            <div className="flex items-center justify-between p-3 bg-amber-50 rounded-lg">
+                                <div>
+                                    <div className="text-sm font-medium text-amber-800">Account locked</div>
+                                    <div className="text-xs text-amber-700">
+                                        Locked after {fullUser.failed_login_count} failed sign-in attempts
+                                    </div>
+                                </div>
+                                <button
+                                    type="button"
+                                    onClick={handleUnlock}
+                                    className="px-4 py-2 rounded-lg text-sm font-medium transition-colors bg-amber-100 text-amber-800 hover:bg-amber-200"
+                                >
+                                    Unlock
+                                </button>
+                            </div>
+                        )}
                         <button
                             type="submit"
                             className="w-full py-2.5 bg-brand-600 text-white rounded-lg hover:bg-brand-700 transition-colors font-medium"
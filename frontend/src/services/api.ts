@@ -18,15 +18,57 @@ api.interceptors.request.use(
     (error) => Promise.reject(error)
 );
 
+const clearSession = () => {
+    localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+    localStorage.removeItem('user');
+};
+
+// Concurrent 401s share one refresh, since a refresh token can only be used once
+let refreshing: Promise<string> | null = null;
+
+const refreshAccessToken = () => {
+    if (!refreshing) {
+        const refreshToken = localStorage.getItem('refresh_token');
+        refreshing = (refreshToken
+            ? axios.post('/api/v1/refresh', { refresh_token: refreshToken }).then((response) => {
+                localStorage.setItem('token', response.data.token);
+                localStorage.setItem('refresh_token', response.data.refresh_token);
+                localStorage.setItem('user', JSON.stringify(response.data.user));
+                return response.data.token as string;
+            })
+            : Promise.reject(new Error('No refresh token'))
+        ).finally(() => {
+            refreshing = null;
+        });
+    }
+    return refreshing;
+};
+
 api.interceptors.response.use(
     (response) => response,
-    (error) => {
-        // Don't redirect if it's a login failure (401 on /login)
-        if (error.response?.status === 401 && !error.config.url?.includes('/login')) {
-            localStorage.removeItem('token');
-            localStorage.removeItem('user');
+    async (error) => {
+        const config = error.config;
+        // Don't redirect if it's a login failure (401 on /login or /sso/exchange)
+        if (error.response?.status === 401 && !config.url?.includes('/login') && !config.url?.includes('/sso/')) {
+            if (!config._retried) {
+                config._retried = true;
+                try {
+                    const token = await refreshAccessToken();
+                    config.headers.Authorization = `Bearer ${token}`;
+                    return api(config);
+                } catch {
+                    // Fall through to signing out
+                }
+            }
+            clearSession();
             window.location.href = '/login';
         }
+        // A temporary or expired password has to be replaced before anything else works
+        if (error.response?.status === 403 && error.response.data?.code === 'password_change_required'
+            && window.location.pathname !== '/profile') {
+            window.location.href = '/profile';
+        }
         return Promise.reject(error);
     }
 );
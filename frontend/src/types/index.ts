@@ -12,6 +12,10 @@ export interface User {
     joined_date: string;
     is_active: boolean;
     is_confirmed?: boolean;
+    must_change_password?: boolean;
+    failed_login_count?: number;
+    locked_until?: string;
+    two_factor_enabled?: boolean;
     leave_entitlements?: LeaveBalance[];
 }
 
@@ -15,6 +15,19 @@ type Config struct {
 	JWT      JWTConfig
 	Leave    LeaveConfig
 	Email    EmailConfig
+	Actions  ActionConfig
+	// Password reset links emailed by the forgot-password flow
+	PasswordReset PasswordResetConfig `mapstructure:"password_reset"`
+	// Failed sign-in lockout and login throttling
+	Login LoginConfig
+	// TOTP two-factor sign-in
+	TwoFactor TwoFactorConfig `mapstructure:"two_factor"`
+	// OpenID Connect single sign-on
+	OIDC OIDCConfig `mapstructure:"oidc"`
+	// Service account API keys for integrations
+	APIKeys APIKeyConfig `mapstructure:"api_keys"`
+	// First sysadmin account, created when no sysadmin exists
+	SuperAdmin SuperAdminConfig `mapstructure:"superadmin"`
 }
 
 type ServerConfig struct {
@@ -23,6 +36,10 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	PublicURL    string        `mapstructure:"public_url"` // Base URL used in links sent to users
+	// Proxies whose X-Forwarded-For header is believed, as IPs or CIDRs. Empty trusts none, so
+	// the client IP used for rate limits and audit logs is the connecting address.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -35,8 +52,26 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	SecretKey      string        `mapstructure:"secret_key"`
-	AccessTokenTTL time.Duration `mapstructure:"access_token_ttl"`
+	// Access tokens are signed with the active key. Other keys only verify, so a key can be
+	// published before it becomes active and kept after it retires.
+	ActiveKeyID string         `mapstructure:"active_key_id"`
+	Keys        []JWTKeyConfig `mapstructure:"keys"`
+	Issuer      string         `mapstructure:"issuer"` // Defaults to server.public_url
+	// Not used for tokens; the fallback for actions.secret_key and two_factor.encryption_key
+	SecretKey       string        `mapstructure:"secret_key"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+	UserStateTTL    time.Duration `mapstructure:"user_state_ttl"` // How long a user's role and status are cached
+}
+
+// JWTKeyConfig is a PEM encoded RSA or Ed25519 key, given as a file or inline. A public key
+// alone only verifies tokens.
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+	PrivateKey     string `mapstructure:"private_key"`
+	PublicKey      string `mapstructure:"public_key"`
 }
 
 type LeaveConfig struct {
@@ -51,6 +86,79 @@ type EmailConfig struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	From     string `mapstructure:"from"`
+	// Default for the manager FYI calendar invite notification rules when they are first seeded
+	ManagerInvites bool `mapstructure:"manager_invites"`
+	// Directory of <locale>/<name>.tmpl files overriding the built-in templates
+	TemplateDir string `mapstructure:"template_dir"`
+	// Base URL of the web application linked from emails
+	AppURL string `mapstructure:"app_url"`
+}
+
+// ActionConfig controls the signed approve/reject links sent to approvers
+type ActionConfig struct {
+	SecretKey string        `mapstructure:"secret_key"` // Falls back to the JWT secret when empty
+	TTL       time.Duration `mapstructure:"ttl"`
+}
+
+// PasswordResetConfig controls the emailed password reset links
+type PasswordResetConfig struct {
+	TTL       time.Duration `mapstructure:"ttl"`
+	RateLimit int           `mapstructure:"rate_limit"` // Requests allowed per client IP per hour on each of the two endpoints
+}
+
+// LoginConfig controls how failed sign-ins are throttled
+type LoginConfig struct {
+	MaxFailedAttempts int           `mapstructure:"max_failed_attempts"` // Failures in a row that lock the account
+	LockoutDuration   time.Duration `mapstructure:"lockout_duration"`
+	RateLimit         int           `mapstructure:"rate_limit"` // Attempts allowed per client IP per minute
+}
+
+// TwoFactorConfig controls TOTP two-factor sign-in
+type TwoFactorConfig struct {
+	Issuer        string        `mapstructure:"issuer"`         // Name authenticator apps show for the account
+	EncryptionKey string        `mapstructure:"encryption_key"` // Encrypts stored secrets; falls back to the JWT secret when empty
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`  // Time allowed between the password and the code
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider
+type OIDCConfig struct {
+	Enabled              bool              `mapstructure:"enabled"`
+	ProviderName         string            `mapstructure:"provider_name"` // Shown on the sign-in button
+	Issuer               string            `mapstructure:"issuer"`
+	ClientID             string            `mapstructure:"client_id"`
+	ClientSecret         string            `mapstructure:"client_secret"`
+	RedirectURL          string            `mapstructure:"redirect_url"` // Defaults to <public_url>/api/v1/sso/callback
+	Scopes               []string          `mapstructure:"scopes"`
+	MatchByEmail         bool              `mapstructure:"match_by_email"`         // Link existing users by email on first sign in
+	RequireVerifiedEmail bool              `mapstructure:"require_verified_email"` // Refuse emails the provider hasn't verified
+	ProvisionUsers       bool              `mapstructure:"provision_users"`        // Create unknown users just in time
+	DefaultRole          string            `mapstructure:"default_role"`
+	GroupsClaim          string            `mapstructure:"groups_claim"`
+	RoleMappings         []OIDCRoleMapping `mapstructure:"role_mappings"`
+	SyncRoles            bool              `mapstructure:"sync_roles"`             // Apply the role mapping at every sign in
+	DisablePasswordLogin bool              `mapstructure:"disable_password_login"` // SSO users can't use local passwords
+	TrustProviderMFA     bool              `mapstructure:"trust_provider_mfa"`     // Skip local 2FA when the ID token shows MFA
+	MFAACRValues         []string          `mapstructure:"mfa_acr_values"`         // acr values the provider uses for MFA
+}
+
+// OIDCRoleMapping gives members of a provider group a role
+type OIDCRoleMapping struct {
+	Group string `mapstructure:"group"`
+	Role  string `mapstructure:"role"`
+}
+
+// APIKeyConfig bounds how long service account API keys last
+type APIKeyConfig struct {
+	DefaultTTL time.Duration `mapstructure:"default_ttl"` // For keys created without an expiry
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`
+}
+
+// SuperAdminConfig seeds the first sysadmin. Its password is temporary and must be changed at
+// first sign in and meet the password policy; leave it empty to have one generated and printed
+// to standard error.
+type SuperAdminConfig struct {
+	Email    string `mapstructure:"email"`
+	Password string `mapstructure:"password"`
 }
 
 func LoadConfig(logger *zap.Logger) (*Config, error) {
@@ -62,10 +170,31 @@ func LoadConfig(logger *zap.Logger) (*Config, error) {
 
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.env", "development")
+	viper.SetDefault("server.public_url", "http://localhost:8080")
+	viper.SetDefault("email.app_url", "http://localhost:5173")
 	viper.SetDefault("database.sslmode", "disable")
 	viper.SetDefault("leave.escalation_days", 7)
 	viper.SetDefault("leave.max_carry_forward_days", 5)
-	viper.SetDefault("jwt.access_token_ttl", "24h")
+	viper.SetDefault("jwt.access_token_ttl", "15m")
+	viper.SetDefault("jwt.refresh_token_ttl", "720h")
+	viper.SetDefault("jwt.user_state_ttl", "30s")
+	viper.SetDefault("actions.ttl", "72h")
+	viper.SetDefault("password_reset.ttl", "1h")
+	viper.SetDefault("password_reset.rate_limit", 10)
+	viper.SetDefault("login.max_failed_attempts", 5)
+	viper.SetDefault("login.lockout_duration", "15m")
+	viper.SetDefault("login.rate_limit", 20)
+	viper.SetDefault("two_factor.issuer", "Leave Management System")
+	viper.SetDefault("two_factor.challenge_ttl", "5m")
+	viper.SetDefault("oidc.provider_name", "Single Sign-On")
+	viper.SetDefault("oidc.scopes", []string{"openid", "email", "profile"})
+	viper.SetDefault("oidc.match_by_email", true)
+	viper.SetDefault("oidc.require_verified_email", true)
+	viper.SetDefault("oidc.default_role", "staff")
+	viper.SetDefault("oidc.groups_claim", "groups")
+	viper.SetDefault("api_keys.default_ttl", "2160h")
+	viper.SetDefault("api_keys.max_ttl", "8760h")
+	viper.SetDefault("superadmin.email", "superadmin@example.com")
 
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
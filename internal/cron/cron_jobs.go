@@ -10,19 +10,31 @@ import (
 )
 
 type CronJobs struct {
-	leaveService *services.LeaveService
-	emailService *services.EmailService
-	logger       *zap.Logger
-	cron         *cron.Cron
+	leaveService        *services.LeaveService
+	notificationService *services.NotificationService
+	webhookService      *services.WebhookService
+	sessionService      *services.SessionService
+	passwordResets      *services.PasswordResetService
+	twoFactor           *services.TwoFactorService
+	sso                 *services.SSOService
+	logger              *zap.Logger
+	cron                *cron.Cron
 }
 
 func NewCronJobs(leaveService *services.LeaveService,
-	emailService *services.EmailService, logger *zap.Logger) *CronJobs {
+	notificationService *services.NotificationService, webhookService *services.WebhookService,
+	sessionService *services.SessionService, passwordResets *services.PasswordResetService,
+	twoFactor *services.TwoFactorService, sso *services.SSOService, logger *zap.Logger) *CronJobs {
 	return &CronJobs{
-		leaveService: leaveService,
-		emailService: emailService,
-		logger:       logger,
-		cron:         cron.New(cron.WithSeconds()),
+		leaveService:        leaveService,
+		notificationService: notificationService,
+		webhookService:      webhookService,
+		sessionService:      sessionService,
+		passwordResets:      passwordResets,
+		twoFactor:           twoFactor,
+		sso:                 sso,
+		logger:              logger,
+		cron:                cron.New(cron.WithSeconds()),
 	}
 }
 
@@ -45,12 +57,41 @@ func (cj *CronJobs) Start() error {
 		return fmt.Errorf("failed to add year-end job: %w", err)
 	}
 
+	// Run every day at 2 AM to warn about and expire carried-forward leave
+	_, err = cj.cron.AddFunc("0 0 2 * * *", cj.processCarryForwardExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to add carry-forward expiry job: %w", err)
+	}
+
+	// Run every 30 seconds to deliver queued notifications, skipped while the last run is still sending
+	_, err = cj.cron.AddJob("*/30 * * * * *", cj.skipIfStillRunning(cj.dispatchNotifications))
+	if err != nil {
+		return fmt.Errorf("failed to add notification dispatch job: %w", err)
+	}
+
+	// Run every 30 seconds to deliver queued webhooks, skipped while the last run is still posting
+	_, err = cj.cron.AddJob("*/30 * * * * *", cj.skipIfStillRunning(cj.dispatchWebhooks))
+	if err != nil {
+		return fmt.Errorf("failed to add webhook dispatch job: %w", err)
+	}
+
+	// Run every day at 3 AM to delete expired refresh and password reset tokens
+	_, err = cj.cron.AddFunc("0 0 3 * * *", cj.cleanupSessions)
+	if err != nil {
+		return fmt.Errorf("failed to add session cleanup job: %w", err)
+	}
+
 	cj.cron.Start()
 	cj.logger.Info("Cron jobs started")
 
 	return nil
 }
 
+// skipIfStillRunning wraps a job so a tick is skipped while the previous run hasn't finished
+func (cj *CronJobs) skipIfStillRunning(job func()) cron.Job {
+	return cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(cron.FuncJob(job))
+}
+
 func (cj *CronJobs) Stop() {
 	cj.cron.Stop()
 	cj.logger.Info("Cron jobs stopped")
@@ -59,17 +100,42 @@ func (cj *CronJobs) Stop() {
 func (cj *CronJobs) checkEscalatedRequests() {
 	cj.logger.Info("Checking for escalated leave requests")
 
-	// Get pending requests older than 7 days
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
+	// Settle requests whose leave has already started before escalating anything else
+	decided, err := cj.leaveService.ProcessLapsedRequests(time.Now())
+	if err != nil {
+		cj.logger.Error("Failed to process lapsed requests", zap.Error(err))
+	}
+	if decided > 0 {
+		cj.logger.Info("Lapsed requests decided", zap.Int("count", decided))
+	}
+
+	// Requests starting soon go straight to HR
+	imminent, err := cj.leaveService.GetImminentRequests(time.Now())
+	if err != nil {
+		cj.logger.Error("Failed to get imminent requests", zap.Error(err))
+	}
+	for _, request := range imminent {
+		if err := cj.leaveService.EscalateImminentRequest(request.ID); err != nil {
+			cj.logger.Error("Failed to escalate imminent request",
+				zap.String("request_id", request.ID.String()),
+				zap.Error(err))
+			continue
+		}
+
+		cj.logger.Info("Imminent request escalated",
+			zap.String("request_id", request.ID.String()),
+			zap.Time("start_date", request.StartDate))
+	}
 
-	requests, err := cj.leaveService.GetPendingRequestsOlderThan(sevenDaysAgo)
+	// Thresholds come from the system and leave type configuration, in working days
+	requests, err := cj.leaveService.GetRequestsDueForEscalation(time.Now())
 	if err != nil {
 		cj.logger.Error("Failed to get pending requests", zap.Error(err))
 		return
 	}
 
 	for _, request := range requests {
-		// Escalate the request
+		// Escalate the request; HR is notified through the outbox
 		if err := cj.leaveService.EscalateRequest(request.ID); err != nil {
 			cj.logger.Error("Failed to escalate request",
 				zap.String("request_id", request.ID.String()),
@@ -77,9 +143,6 @@ func (cj *CronJobs) checkEscalatedRequests() {
 			continue
 		}
 
-		// Send notification to HR
-		cj.emailService.SendEscalationNotification(&request)
-
 		cj.logger.Info("Request escalated",
 			zap.String("request_id", request.ID.String()),
 			zap.String("user_id", request.UserID.String()))
@@ -89,10 +152,8 @@ func (cj *CronJobs) checkEscalatedRequests() {
 func (cj *CronJobs) sendReminderEmails() {
 	cj.logger.Info("Sending reminder emails for pending requests")
 
-	// Get pending requests that need reminder (e.g., pending for 3 days)
-	threeDaysAgo := time.Now().AddDate(0, 0, -3)
-
-	requests, err := cj.leaveService.GetPendingRequestsOlderThan(threeDaysAgo)
+	// Requests past their reminder threshold that weren't reminded within the reminder interval
+	requests, err := cj.leaveService.GetRequestsDueForReminder(time.Now())
 	if err != nil {
 		cj.logger.Error("Failed to get pending requests for reminders", zap.Error(err))
 		return
@@ -100,7 +161,89 @@ func (cj *CronJobs) sendReminderEmails() {
 
 	for _, request := range requests {
 		// Send reminder to manager
-		cj.emailService.SendReminderNotification(&request)
+		if err := cj.leaveService.SendReminder(request.ID); err != nil {
+			cj.logger.Error("Failed to queue reminder",
+				zap.String("request_id", request.ID.String()),
+				zap.Error(err))
+		}
+	}
+}
+
+func (cj *CronJobs) processCarryForwardExpiry() {
+	cj.logger.Info("Processing carried-forward leave expiry")
+
+	if err := cj.leaveService.ProcessCarryForwardExpiry(); err != nil {
+		cj.logger.Error("Failed to process carry-forward expiry", zap.Error(err))
+	}
+}
+
+func (cj *CronJobs) dispatchNotifications() {
+	sent, failed, err := cj.notificationService.DispatchPending()
+	if err != nil {
+		cj.logger.Error("Failed to dispatch notifications", zap.Error(err))
+		return
+	}
+
+	if sent > 0 || failed > 0 {
+		cj.logger.Info("Dispatched notifications",
+			zap.Int("sent", sent),
+			zap.Int("failed", failed))
+	}
+}
+
+func (cj *CronJobs) dispatchWebhooks() {
+	succeeded, failed, err := cj.webhookService.DispatchPending()
+	if err != nil {
+		cj.logger.Error("Failed to dispatch webhooks", zap.Error(err))
+		return
+	}
+
+	if succeeded > 0 || failed > 0 {
+		cj.logger.Info("Dispatched webhooks",
+			zap.Int("succeeded", succeeded),
+			zap.Int("failed", failed))
+	}
+}
+
+func (cj *CronJobs) cleanupSessions() {
+	deleted, err := cj.sessionService.CleanupExpired()
+	if err != nil {
+		cj.logger.Error("Failed to clean up expired sessions", zap.Error(err))
+		return
+	}
+
+	if deleted > 0 {
+		cj.logger.Info("Deleted expired refresh tokens", zap.Int64("count", deleted))
+	}
+
+	deleted, err = cj.passwordResets.CleanupExpired()
+	if err != nil {
+		cj.logger.Error("Failed to clean up expired password reset tokens", zap.Error(err))
+		return
+	}
+
+	if deleted > 0 {
+		cj.logger.Info("Deleted expired password reset tokens", zap.Int64("count", deleted))
+	}
+
+	deleted, err = cj.twoFactor.CleanupExpired()
+	if err != nil {
+		cj.logger.Error("Failed to clean up sign-in challenges", zap.Error(err))
+		return
+	}
+
+	if deleted > 0 {
+		cj.logger.Info("Deleted finished sign-in challenges", zap.Int64("count", deleted))
+	}
+
+	deleted, err = cj.sso.CleanupExpired()
+	if err != nil {
+		cj.logger.Error("Failed to clean up single sign-on attempts", zap.Error(err))
+		return
+	}
+
+	if deleted > 0 {
+		cj.logger.Info("Deleted finished single sign-on attempts", zap.Int64("count", deleted))
 	}
 }
 
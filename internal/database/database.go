@@ -51,14 +51,19 @@ func (d *Database) Close() error {
 	return sqlDB.Close()
 }
 
-func (d *Database) CreateSuperAdmin(email, passwordHash, firstName, lastName string) error {
+// CreateSuperAdmin creates the first sysadmin with a temporary password when there is none,
+// and reports whether it did
+func (d *Database) CreateSuperAdmin(email, passwordHash, firstName, lastName string) (bool, error) {
 	var count int64
-	d.DB.Model(&models.User{}).Where("role = ?", models.RoleSysAdmin).Count(&count)
+	if err := d.DB.Model(&models.User{}).Where("role = ?", models.RoleSysAdmin).Count(&count).Error; err != nil {
+		return false, err
+	}
 
 	if count > 0 {
-		return nil
+		return false, nil
 	}
 
+	now := time.Now()
 	superAdmin := models.User{
 		Email:        email,
 		PasswordHash: passwordHash,
@@ -70,9 +75,15 @@ func (d *Database) CreateSuperAdmin(email, passwordHash, firstName, lastName str
 		JoinedDate:   time.Now(),
 		IsConfirmed:  true,
 		IsActive:     true,
+		// Whoever reads the seeded password from config or the log has to replace it
+		MustChangePassword: true,
+		PasswordChangedAt:  &now,
 	}
 
-	return d.DB.Create(&superAdmin).Error
+	if err := d.DB.Create(&superAdmin).Error; err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // Transaction wrapper for data integrity
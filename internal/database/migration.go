@@ -17,10 +17,31 @@ func Migrate(db *gorm.DB) error {
 	// Run migrations
 	err := db.AutoMigrate(
 		&models.User{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.PasswordHistory{},
+		&models.TwoFactorSecret{},
+		&models.RecoveryCode{},
+		&models.LoginChallenge{},
+		&models.SSOLogin{},
+		&models.ServiceAccount{},
+		&models.APIKey{},
+		&models.RolePermission{},
 		&models.LeaveRequest{},
 		&models.LeaveBalance{},
 		&models.Chronology{},
 		&models.PublicHoliday{},
+		&models.BlackoutPeriod{},
+		&models.CalendarFeedToken{},
+		&models.EmailTemplate{},
+		&models.NotificationOutbox{},
+		&models.NotificationRule{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+		&models.ActionToken{},
 		&models.LeaveTypeConfig{},
 		&models.AuditLog{},
 		&services.SystemConfig{},
@@ -34,6 +55,9 @@ func Migrate(db *gorm.DB) error {
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_leave_requests_user_id ON leave_requests(user_id)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_leave_requests_status ON leave_requests(status)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_leave_requests_approver_id ON leave_requests(approver_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_leave_requests_dates ON leave_requests(start_date, end_date)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_department ON users(department)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_manager_id ON users(manager_id)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_leave_balance_user_year ON leave_balances(user_id, year)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at)")
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActionHandler serves the approve/reject links sent in notifications. Opening a link only
+// shows a confirmation page, so link previews and mail scanners can't make the decision;
+// the decision is made by POSTing the form, or JSON from a chat integration.
+type ActionHandler struct {
+	actionTokens *services.ActionTokenService
+	leaveService *services.LeaveService
+}
+
+func NewActionHandler(actionTokens *services.ActionTokenService, leaveService *services.LeaveService) *ActionHandler {
+	return &ActionHandler{actionTokens: actionTokens, leaveService: leaveService}
+}
+
+var actionPage = template.Must(template.New("action").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Leave Management System</title>
+<style>
+body { font-family: sans-serif; max-width: 32rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+td { padding: 0.25rem 0.75rem 0.25rem 0; }
+textarea { width: 100%; min-height: 5rem; margin: 0.5rem 0 1rem; }
+button { padding: 0.5rem 1.25rem; font-size: 1rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+{{if .Error}}
+<h2>Unable to continue</h2>
+<p class="error">{{.Error}}</p>
+<p>You can still review the request in the Leave Management System.</p>
+{{else if .Done}}
+<h2>Leave request {{.Verb}}</h2>
+<p>The leave request from {{.Employee}} has been {{.Verb}}.</p>
+{{else}}
+<h2>{{if eq .Action "approve"}}Approve{{else}}Reject{{end}} leave request</h2>
+<table>
+  <tr><td><strong>Employee</strong></td><td>{{.Employee}}</td></tr>
+  <tr><td><strong>Leave Type</strong></td><td>{{.LeaveType}}</td></tr>
+  <tr><td><strong>Dates</strong></td><td>{{.Dates}}</td></tr>
+  <tr><td><strong>Duration</strong></td><td>{{printf "%.1f" .Duration}} days</td></tr>
+</table>
+<form method="post">
+  <label for="comment">Comment{{if eq .Action "approve"}} (optional){{end}}</label>
+  <textarea id="comment" name="comment"></textarea>
+  <button type="submit">{{if eq .Action "approve"}}Approve{{else}}Reject{{end}}</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+type actionPageData struct {
+	Action    string
+	Verb      string
+	Employee  string
+	LeaveType models.LeaveType
+	Dates     string
+	Duration  float64
+	Done      bool
+	Error     string
+}
+
+type PerformActionRequest struct {
+	Comment string `json:"comment" form:"comment"`
+}
+
+// ShowAction renders the confirmation page for an action link
+func (h *ActionHandler) ShowAction(c *gin.Context) {
+	claims, _, err := h.actionTokens.Verify(c.Param("token"))
+	if err != nil {
+		h.renderError(c, err)
+		return
+	}
+
+	request, err := h.leaveService.GetLeaveRequest(claims.RequestID)
+	if err != nil {
+		h.renderError(c, err)
+		return
+	}
+
+	h.render(c, http.StatusOK, actionPageData{
+		Action:    claims.Action,
+		Employee:  strings.TrimSpace(request.User.FirstName + " " + request.User.LastName),
+		LeaveType: request.LeaveType,
+		Dates:     request.StartDate.Format("2 Jan 2006") + " to " + request.EndDate.Format("2 Jan 2006"),
+		Duration:  request.DurationDays,
+	})
+}
+
+// PerformAction spends the link and approves or rejects the request as the link's approver
+func (h *ActionHandler) PerformAction(c *gin.Context) {
+	claims, token, err := h.actionTokens.Verify(c.Param("token"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	var req PerformActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if err := h.actionTokens.Consume(token, claims.Action); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if err := h.leaveService.ApplyAction(token, claims.Action, strings.TrimSpace(req.Comment)); err != nil {
+		// The decision didn't happen, so the link can be tried again
+		_ = h.actionTokens.Release(token)
+		h.respondError(c, err)
+		return
+	}
+
+	verb := "approved"
+	if claims.Action == services.ActionReject {
+		verb = "rejected"
+	}
+
+	if wantsJSON(c) {
+		c.JSON(http.StatusOK, gin.H{"message": "Leave request " + verb, "request_id": claims.RequestID})
+		return
+	}
+
+	request, err := h.leaveService.GetLeaveRequest(claims.RequestID)
+	if err != nil {
+		h.renderError(c, err)
+		return
+	}
+	h.render(c, http.StatusOK, actionPageData{
+		Action:   claims.Action,
+		Verb:     verb,
+		Employee: strings.TrimSpace(request.User.FirstName + " " + request.User.LastName),
+		Done:     true,
+	})
+}
+
+func (h *ActionHandler) respondError(c *gin.Context, err error) {
+	if wantsJSON(c) {
+		c.JSON(actionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.renderError(c, err)
+}
+
+func (h *ActionHandler) renderError(c *gin.Context, err error) {
+	h.render(c, actionErrorStatus(err), actionPageData{Error: err.Error()})
+}
+
+func (h *ActionHandler) render(c *gin.Context, status int, data actionPageData) {
+	// The link is the credential, so keep it out of caches and referrers
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = actionPage.Execute(c.Writer, data)
+}
+
+func actionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidActionToken):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrActionTokenExpired):
+		return http.StatusGone
+	case errors.Is(err, services.ErrActionTokenUsed):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotAuthorizedToDecide):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func wantsJSON(c *gin.Context) bool {
+	return c.ContentType() == gin.MIMEJSON || strings.Contains(c.GetHeader("Accept"), gin.MIMEJSON)
+}
@@ -17,19 +17,22 @@ type AdminHandler struct {
 	leaveService           *services.LeaveService
 	auditService           *services.AuditService
 	leaveTypeConfigService *services.LeaveTypeConfigService
+	blackoutService        *services.BlackoutService
 }
 
 func NewAdminHandler(holidayService *services.HolidayService,
 	configService *services.ConfigService,
 	leaveService *services.LeaveService,
 	auditService *services.AuditService,
-	leaveTypeConfigService *services.LeaveTypeConfigService) *AdminHandler {
+	leaveTypeConfigService *services.LeaveTypeConfigService,
+	blackoutService *services.BlackoutService) *AdminHandler {
 	return &AdminHandler{
 		holidayService:         holidayService,
 		configService:          configService,
 		leaveService:           leaveService,
 		auditService:           auditService,
 		leaveTypeConfigService: leaveTypeConfigService,
+		blackoutService:        blackoutService,
 	}
 }
 
@@ -124,9 +127,21 @@ func (h *AdminHandler) DeletePublicHoliday(c *gin.Context) {
 }
 
 type SystemConfigRequest struct {
-	MaxCarryForwardDays int      `json:"max_carry_forward_days"`
-	WorkingDays         []string `json:"working_days"`
-	EscalationDays      int      `json:"escalation_days"`
+	MaxCarryForwardDays     int                               `json:"max_carry_forward_days"`
+	WorkingDays             []string                          `json:"working_days"`
+	EscalationDays          int                               `json:"escalation_days"`
+	ReminderDays            *int                              `json:"reminder_days"`
+	ReminderIntervalDays    *int                              `json:"reminder_interval_days"`
+	ImminentEscalationDays  *int                              `json:"imminent_escalation_days"`
+	LapsedRequestAction     *services.LapsedRequestAction     `json:"lapsed_request_action"`
+	EscalationRecipientMode *services.EscalationRecipientMode `json:"escalation_recipient_mode"`
+	HRMailbox               *string                           `json:"hr_mailbox"`
+	DepartmentHRPartners    map[string]string                 `json:"department_hr_partners"`
+	PasswordMinLength       *int                              `json:"password_min_length"`
+	PasswordMinClasses      *int                              `json:"password_min_classes"`
+	PasswordHistoryCount    *int                              `json:"password_history_count"`
+	PasswordMaxAgeDays      *int                              `json:"password_max_age_days"`
+	TwoFactorRequiredRoles  []models.UserRole                 `json:"two_factor_required_roles"`
 }
 
 func (h *AdminHandler) UpdateSystemConfig(c *gin.Context) {
@@ -137,9 +152,21 @@ func (h *AdminHandler) UpdateSystemConfig(c *gin.Context) {
 	}
 
 	svcReq := services.SystemConfigRequest{
-		MaxCarryForwardDays: req.MaxCarryForwardDays,
-		WorkingDays:         req.WorkingDays,
-		EscalationDays:      req.EscalationDays,
+		MaxCarryForwardDays:     req.MaxCarryForwardDays,
+		WorkingDays:             req.WorkingDays,
+		EscalationDays:          req.EscalationDays,
+		ReminderDays:            req.ReminderDays,
+		ReminderIntervalDays:    req.ReminderIntervalDays,
+		ImminentEscalationDays:  req.ImminentEscalationDays,
+		LapsedRequestAction:     req.LapsedRequestAction,
+		EscalationRecipientMode: req.EscalationRecipientMode,
+		HRMailbox:               req.HRMailbox,
+		DepartmentHRPartners:    req.DepartmentHRPartners,
+		PasswordMinLength:       req.PasswordMinLength,
+		PasswordMinClasses:      req.PasswordMinClasses,
+		PasswordHistoryCount:    req.PasswordHistoryCount,
+		PasswordMaxAgeDays:      req.PasswordMaxAgeDays,
+		TwoFactorRequiredRoles:  req.TwoFactorRequiredRoles,
 	}
 
 	if err := h.configService.UpdateSystemConfig(svcReq); err != nil {
@@ -219,3 +246,113 @@ func (h *AdminHandler) UpdateLeaveTypeConfig(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Leave type configuration updated"})
 }
+
+type CreateBlackoutPeriodRequest struct {
+	Name                 string           `json:"name" binding:"required"`
+	Description          string           `json:"description"`
+	StartDate            time.Time        `json:"start_date" binding:"required"`
+	EndDate              time.Time        `json:"end_date" binding:"required"`
+	Department           string           `json:"department"`
+	Role                 models.UserRole  `json:"role"`
+	LeaveType            models.LeaveType `json:"leave_type"`
+	ExemptEmergencyLeave bool             `json:"exempt_emergency_leave"`
+}
+
+func (h *AdminHandler) CreateBlackoutPeriod(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req CreateBlackoutPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	period := &models.BlackoutPeriod{
+		Name:                 req.Name,
+		Description:          req.Description,
+		StartDate:            req.StartDate,
+		EndDate:              req.EndDate,
+		Department:           req.Department,
+		Role:                 req.Role,
+		LeaveType:            req.LeaveType,
+		ExemptEmergencyLeave: req.ExemptEmergencyLeave,
+		IsActive:             true,
+		CreatedByID:          &userID,
+	}
+
+	if err := h.blackoutService.CreateBlackoutPeriod(period); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, period)
+}
+
+func (h *AdminHandler) GetBlackoutPeriods(c *gin.Context) {
+	year := 0
+	if yearStr := c.Query("year"); yearStr != "" {
+		var err error
+		year, err = strconv.Atoi(yearStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+	}
+
+	periods, err := h.blackoutService.GetBlackoutPeriods(year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, periods)
+}
+
+// GetUpcomingBlackoutPeriods returns blackout periods that apply to the current user
+func (h *AdminHandler) GetUpcomingBlackoutPeriods(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	periods, err := h.blackoutService.GetUpcomingForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, periods)
+}
+
+func (h *AdminHandler) UpdateBlackoutPeriod(c *gin.Context) {
+	periodID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blackout period ID"})
+		return
+	}
+
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.blackoutService.UpdateBlackoutPeriod(periodID, updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blackout period updated"})
+}
+
+func (h *AdminHandler) DeleteBlackoutPeriod(c *gin.Context) {
+	periodID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blackout period ID"})
+		return
+	}
+
+	if err := h.blackoutService.DeleteBlackoutPeriod(periodID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blackout period deleted"})
+}
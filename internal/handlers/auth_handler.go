@@ -1,27 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"leave-management-system/internal/models"
 	"leave-management-system/internal/services"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"leave-management-system/pkg/auth"
-
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
-	userService *services.UserService
-	jwtManager  *auth.JWTManager
+	userService     *services.UserService
+	sessionService  *services.SessionService
+	passwordService *services.PasswordService
+	lockouts        *services.LockoutService
+	twoFactor       *services.TwoFactorService
+	sso             *services.SSOService
 }
 
-func NewAuthHandler(userService *services.UserService, jwtManager *auth.JWTManager) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, sessionService *services.SessionService,
+	passwordService *services.PasswordService, lockouts *services.LockoutService,
+	twoFactor *services.TwoFactorService, sso *services.SSOService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtManager:  jwtManager,
+		userService:     userService,
+		sessionService:  sessionService,
+		passwordService: passwordService,
+		lockouts:        lockouts,
+		twoFactor:       twoFactor,
+		sso:             sso,
 	}
 }
 
@@ -31,9 +43,9 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token     string      `json:"token"`
-	User      models.User `json:"user"`
-	ExpiresAt time.Time   `json:"expires_at"`
+	services.TokenPair
+	User          models.User `json:"user"`
+	RecoveryCodes []string    `json:"recovery_codes,omitempty"` // Set when two-factor set up was finished while signing in
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -43,48 +55,278 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	attempt := services.LoginAttempt{
+		Email:     req.Email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Endpoint:  c.Request.URL.Path,
+	}
+
 	// Get user
 	user, err := h.userService.GetUserByEmail(req.Email)
 	if err != nil {
+		h.lockouts.RecordRejected(nil, attempt, "unknown_email")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Locked accounts and attempts too soon after a failure aren't checked at all
+	if err := h.lockouts.Check(user, time.Now()); err != nil {
+		h.lockouts.RecordRejected(user, attempt, "throttled")
+		respondThrottled(c, err)
+		return
+	}
+
 	// Check password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		var throttled *services.LoginThrottledError
+		if err := h.lockouts.RecordFailure(user, attempt, "invalid_password"); errors.As(err, &throttled) {
+			respondThrottled(c, throttled)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	// Check if user is active
 	if !user.IsActive {
+		h.lockouts.RecordRejected(user, attempt, "deactivated")
 		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
 		return
 	}
 
-	// Generate JWT token
-	token, claims, err := h.jwtManager.Generate(user)
+	if !h.sso.PasswordLoginAllowed(user) {
+		h.lockouts.RecordRejected(user, attempt, "password_login_disabled")
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account signs in with single sign-on"})
+		return
+	}
+
+	// Tokens are only issued once the second factor has been given too
+	if h.twoFactor.NeedsSecondFactor(user) {
+		challenge, err := h.twoFactor.BeginLogin(user, attempt.IPAddress, attempt.UserAgent)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	h.completeLogin(c, user, nil)
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // Authenticator or recovery code
+}
+
+// LoginTwoFactor finishes a sign in with an authenticator or recovery code. For a user setting
+// up 2FA during sign in the code confirms their new authenticator.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attempt := services.LoginAttempt{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Endpoint:  c.Request.URL.Path,
+	}
+	var throttled *services.LoginThrottledError
+	user, recoveryCodes, err := h.twoFactor.CompleteLogin(req.ChallengeToken, req.Code, attempt)
+	if errors.As(err, &throttled) {
+		respondThrottled(c, throttled)
+		return
+	} else if errors.Is(err, services.ErrInvalidLoginChallenge) || errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, services.ErrTwoFactorSetupNotStarted) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	h.completeLogin(c, user, recoveryCodes)
+}
+
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// LoginTwoFactorSetup starts setting up an authenticator for a user whose role requires 2FA
+// and who is signing in without one
+func (h *AuthHandler) LoginTwoFactorSetup(c *gin.Context) {
+	var req LoginChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.twoFactor.ChallengeEnrollment(req.ChallengeToken)
+	if errors.Is(err, services.ErrInvalidLoginChallenge) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor set up"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+type LoginSSORequest struct {
+	HandoffToken string `json:"handoff_token" binding:"required"`
+}
+
+// LoginSSO starts a session for a user the identity provider has signed in. The local second
+// factor is still owed unless the provider is trusted for it and did it.
+func (h *AuthHandler) LoginSSO(c *gin.Context) {
+	var req LoginSSORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, providerMFA, err := h.sso.Exchange(req.HandoffToken)
+	if errors.Is(err, services.ErrInvalidSSOToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, services.ErrSSOUserInactive) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	if !providerMFA && h.twoFactor.NeedsSecondFactor(user) {
+		challenge, err := h.twoFactor.BeginLogin(user, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	h.completeLogin(c, user, nil)
+}
+
+// completeLogin starts a session for a user who has given every factor they owe
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, recoveryCodes []string) {
+	if err := h.lockouts.RecordSuccess(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	// Start a session with an access and refresh token
+	pair, err := h.sessionService.StartSession(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Update last login
+	// Update last login, and hold an expired password to a forced change
 	now := time.Now()
 	user.LastLoginAt = &now
+	if h.passwordService.Policy().Expired(user, now) {
+		user.MustChangePassword = true
+	}
 	h.userService.UpdateUser(user)
 
 	// Return response
 	c.JSON(http.StatusOK, LoginResponse{
-		Token:     token,
+		TokenPair:     *pair,
+		User:          *user,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// respondThrottled answers a sign-in attempt refused by the lockout policy
+func respondThrottled(c *gin.Context, err error) {
+	var throttled *services.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	status := http.StatusTooManyRequests
+	if throttled.Locked {
+		status = http.StatusLocked
+	}
+	c.JSON(status, gin.H{
+		"error":       throttled.Error(),
+		"retry_after": int(math.Ceil(throttled.RetryAfter.Seconds())),
+	})
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token. The presented refresh
+// token is spent; reusing it ends the session.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pair, user, err := h.sessionService.Refresh(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrSessionRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		TokenPair: *pair,
 		User:      *user,
-		ExpiresAt: claims.ExpiresAt.Time,
 	})
 }
 
+// Logout ends the session the refresh token belongs to. It needs no access token, so a client
+// whose access token has expired can still sign out.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sessionService.Logout(req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll ends every session of the current user, including this one
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	if err := h.sessionService.LogoutAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
-	NewPassword     string `json:"new_password" binding:"required,min=8"`
+	NewPassword     string `json:"new_password" binding:"required"` // Checked against the password policy
 }
 
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
@@ -96,18 +338,48 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	user, err := h.userService.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !h.sso.PasswordLoginAllowed(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account signs in with single sign-on"})
+		return
+	}
+
+	err = h.userService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	// Changing the password ended every session, so sign this client back in
+	user, err = h.userService.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pair, err := h.sessionService.StartSession(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Password changed successfully",
+		"user":               user,
+		"token":              pair.Token,
+		"expires_at":         pair.ExpiresAt,
+		"refresh_token":      pair.RefreshToken,
+		"refresh_expires_at": pair.RefreshExpiresAt,
+	})
 }
 
 type UpdateProfileRequest struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
+	Locale    string `json:"locale"`
 }
 
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
@@ -129,6 +401,9 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	// Update fields
 	user.FirstName = req.FirstName
 	user.LastName = req.LastName
+	if req.Locale != "" {
+		user.Locale = services.NormalizeLocale(req.Locale)
+	}
 
 	// Save changes
 	if err := h.userService.UpdateUser(user); err != nil {
@@ -138,3 +413,23 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// UnlockAccount lets HR lift a lockout before it expires
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	actorID := c.MustGet("user_id").(uuid.UUID)
+
+	if err := h.lockouts.Unlock(userID, actorID, c.ClientIP(), c.Request.UserAgent()); errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CalendarHandler struct {
+	calendarService     *services.CalendarService
+	calendarFeedService *services.CalendarFeedService
+}
+
+func NewCalendarHandler(calendarService *services.CalendarService,
+	calendarFeedService *services.CalendarFeedService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService:     calendarService,
+		calendarFeedService: calendarFeedService,
+	}
+}
+
+// GetLeaveCalendar returns who is out on each day of the requested range
+func (h *CalendarHandler) GetLeaveCalendar(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	startDate, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, expected YYYY-MM-DD"})
+		return
+	}
+
+	endDate, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, expected YYYY-MM-DD"})
+		return
+	}
+
+	scope := services.CalendarScope(c.DefaultQuery("scope", string(services.CalendarScopeTeam)))
+	department := c.Query("department")
+
+	calendar, err := h.calendarService.GetLeaveCalendar(userID, scope, department, startDate, endDate)
+	if err != nil {
+		if errors.Is(err, services.ErrCalendarScopeForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+type CreateCalendarFeedRequest struct {
+	Kind models.CalendarFeedKind `json:"kind" binding:"required"`
+}
+
+func (h *CalendarHandler) CreateCalendarFeed(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req CreateCalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	feed, err := h.calendarFeedService.CreateFeed(userID, req.Kind)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, feed)
+}
+
+func (h *CalendarHandler) GetCalendarFeeds(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	feeds, err := h.calendarFeedService.GetFeeds(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feeds)
+}
+
+func (h *CalendarHandler) RevokeCalendarFeed(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	feedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed ID"})
+		return
+	}
+
+	if err := h.calendarFeedService.RevokeFeed(userID, feedID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed revoked"})
+}
+
+// GetCalendarFeed serves the iCalendar document for a feed token. The token is the credential,
+// so this route is registered without the auth middleware.
+func (h *CalendarHandler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	calendar, err := h.calendarFeedService.RenderFeed(token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedToken) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"leave-management-system/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EmailTemplateHandler struct {
+	templateService *services.TemplateService
+	leaveService    *services.LeaveService
+}
+
+func NewEmailTemplateHandler(templateService *services.TemplateService, leaveService *services.LeaveService) *EmailTemplateHandler {
+	return &EmailTemplateHandler{
+		templateService: templateService,
+		leaveService:    leaveService,
+	}
+}
+
+func (h *EmailTemplateHandler) GetEmailTemplates(c *gin.Context) {
+	templates, err := h.templateService.ListTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *EmailTemplateHandler) GetEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+	locale := c.Param("locale")
+
+	body, source, err := h.templateService.GetTemplateSource(name, locale)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":   name,
+		"locale": locale,
+		"source": source,
+		"body":   body,
+	})
+}
+
+type UpdateEmailTemplateRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+func (h *EmailTemplateHandler) UpdateEmailTemplate(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req UpdateEmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.templateService.SaveOverride(c.Param("name"), c.Param("locale"), req.Body, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email template updated"})
+}
+
+// ResetEmailTemplate removes the database override so the default template is used
+func (h *EmailTemplateHandler) ResetEmailTemplate(c *gin.Context) {
+	if err := h.templateService.DeleteOverride(c.Param("name"), c.Param("locale")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email template reset to default"})
+}
+
+type PreviewEmailTemplateRequest struct {
+	Body           string     `json:"body"`             // Unsaved template body; the saved template is used if empty
+	LeaveRequestID *uuid.UUID `json:"leave_request_id"` // Render against a real request instead of sample data
+}
+
+func (h *EmailTemplateHandler) PreviewEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+	locale := c.Param("locale")
+
+	var req PreviewEmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data := services.SampleEmailTemplateData(h.templateService.AppURL())
+	if req.LeaveRequestID != nil {
+		request, err := h.leaveService.GetLeaveRequest(*req.LeaveRequestID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Leave request not found"})
+			return
+		}
+		data.SetLeaveRequest(request)
+		data.Recipient = data.Employee
+	}
+
+	body := req.Body
+	if body == "" {
+		var err error
+		body, _, err = h.templateService.GetTemplateSource(name, locale)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	rendered, err := h.templateService.RenderSource(name, locale, body, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rendered)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/realtime"
+	"leave-management-system/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Comment lines keep idle connections from being closed by proxies
+const streamHeartbeatInterval = 25 * time.Second
+
+type EventHandler struct {
+	hub                 *realtime.Hub
+	notificationService *services.NotificationService
+}
+
+func NewEventHandler(hub *realtime.Hub, notificationService *services.NotificationService) *EventHandler {
+	return &EventHandler{hub: hub, notificationService: notificationService}
+}
+
+// Stream is a Server-Sent Events endpoint pushing leave events and unread notification counts.
+// Reconnecting clients send Last-Event-ID to replay what they missed.
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	role := c.MustGet("user_role").(models.UserRole)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	// The server's write timeout would otherwise end the stream
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	client, replay, resync := h.hub.Subscribe(userID, role, lastID)
+	defer h.hub.Unsubscribe(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	io.WriteString(w, "retry: 5000\n\n")
+	if resync {
+		// Some missed events are no longer retained; the client should reload its data
+		io.WriteString(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, event := range replay {
+		writeStreamEvent(w, event)
+	}
+	if count, err := h.notificationService.GetUnreadCount(userID); err == nil {
+		fmt.Fprintf(w, "event: %s\ndata: {\"unread_count\":%d}\n\n", services.EventNotificationCount, count)
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-client.Events:
+			if !ok {
+				// Dropped by the hub for falling behind; the client reconnects and replays
+				return
+			}
+			writeStreamEvent(w, event)
+			w.Flush()
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+			w.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(w io.Writer, event realtime.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
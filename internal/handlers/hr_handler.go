@@ -8,18 +8,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type HRHandler struct {
-	userService  *services.UserService
-	leaveService *services.LeaveService
+	userService    *services.UserService
+	leaveService   *services.LeaveService
+	passwordResets *services.PasswordResetService
+	permissions    *services.PermissionService
 }
 
-func NewHRHandler(userService *services.UserService, leaveService *services.LeaveService) *HRHandler {
+func NewHRHandler(userService *services.UserService, leaveService *services.LeaveService,
+	passwordResets *services.PasswordResetService, permissions *services.PermissionService) *HRHandler {
 	return &HRHandler{
-		userService:  userService,
-		leaveService: leaveService,
+		userService:    userService,
+		leaveService:   leaveService,
+		passwordResets: passwordResets,
+		permissions:    permissions,
 	}
 }
 
@@ -51,12 +55,13 @@ func (h *HRHandler) GetUser(c *gin.Context) {
 
 type CreateUserRequest struct {
 	Email           string          `json:"email" binding:"required,email"`
-	Password        string          `json:"password" binding:"required,min=8"`
+	Password        string          `json:"password"` // Temporary password; omit to email the user a link to set their own
 	FirstName       string          `json:"first_name" binding:"required"`
 	LastName        string          `json:"last_name" binding:"required"`
 	Role            models.UserRole `json:"role" binding:"required"`
 	Department      string          `json:"department" binding:"required"`
 	Position        string          `json:"position" binding:"required"`
+	State           string          `json:"state"`
 	ManagerID       *uuid.UUID      `json:"manager_id"`
 	JoinedDate      string          `json:"joined_date" binding:"required"`
 	ProbationMonths int             `json:"probation_months" default:"3"`
@@ -69,41 +74,26 @@ func (h *HRHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	// Validate role permissions
+	// Admin and SysAdmin users need user.create.admin
 	userRole := c.MustGet("user_role").(models.UserRole)
-	if userRole != models.RoleSysAdmin && userRole != models.RoleAdmin &&
-		userRole != models.RoleHR {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions to create user"})
-		return
-	}
-
-	// HR cannot create SysAdmin or Admin users
-	if (userRole == models.RoleHR) &&
-		(req.Role == models.RoleSysAdmin || req.Role == models.RoleAdmin) {
+	if !h.permissions.CanAssignRole(userRole, req.Role) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot create admin users"})
 		return
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
-	}
-
 	newUser := models.User{
-		ID:           uuid.New(),
-		Email:        req.Email,
-		PasswordHash: string(hashedPassword),
-		FirstName:    req.FirstName,
-		LastName:     req.LastName,
-		Role:         req.Role,
-		Department:   req.Department,
-		Position:     req.Position,
-		ManagerID:    req.ManagerID,
-		IsActive:     true,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:         uuid.New(),
+		Email:      req.Email,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Role:       req.Role,
+		Department: req.Department,
+		Position:   req.Position,
+		State:      req.State,
+		ManagerID:  req.ManagerID,
+		IsActive:   true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
 	joinedDate, err := time.Parse("2006-01-02", req.JoinedDate)
@@ -111,11 +101,20 @@ func (h *HRHandler) CreateUser(c *gin.Context) {
 		newUser.JoinedDate = joinedDate
 	}
 
-	if err := h.userService.CreateUser(&newUser); err != nil {
+	if err := h.userService.CreateUser(&newUser, req.Password); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.Password == "" {
+		if err := h.passwordResets.SendResetLink(newUser.ID, c.MustGet("user_id").(uuid.UUID), c.ClientIP()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "User created, but the link to set a password could not be sent. Use Send Reset Link to try again: " + err.Error(),
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusCreated, newUser)
 }
 
@@ -216,6 +215,7 @@ type UpdateUserRequest struct {
 	Role       models.UserRole `json:"role"`
 	Department string          `json:"department"`
 	Position   string          `json:"position"`
+	State      string          `json:"state"`
 	ManagerID  *uuid.UUID      `json:"manager_id"`
 }
 
@@ -239,10 +239,10 @@ func (h *HRHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	// Validate role permissions
+	// Giving an admin role, or changing an admin, needs user.create.admin
 	currentRole := c.MustGet("user_role").(models.UserRole)
-	if currentRole == models.RoleHR &&
-		(req.Role == models.RoleSysAdmin || req.Role == models.RoleAdmin) {
+	if !h.permissions.CanAssignRole(currentRole, user.Role) ||
+		(req.Role != "" && !h.permissions.CanAssignRole(currentRole, req.Role)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot assign admin roles"})
 		return
 	}
@@ -263,6 +263,9 @@ func (h *HRHandler) UpdateUser(c *gin.Context) {
 	if req.Position != "" {
 		user.Position = req.Position
 	}
+	if req.State != "" {
+		user.State = req.State
+	}
 	if req.ManagerID != nil {
 		user.ManagerID = req.ManagerID
 	}
@@ -299,6 +302,12 @@ func (h *HRHandler) ToggleUserActive(c *gin.Context) {
 		return
 	}
 
+	// Deactivating an admin, which also ends their sessions, needs user.create.admin
+	if !h.permissions.CanAssignRole(c.MustGet("user_role").(models.UserRole), user.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot change admin users"})
+		return
+	}
+
 	user.IsActive = req.IsActive
 
 	if err := h.userService.UpdateUser(user); err != nil {
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"leave-management-system/pkg/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys access tokens are verified with
+type JWKSHandler struct {
+	jwtManager *auth.JWTManager
+}
+
+func NewJWKSHandler(jwtManager *auth.JWTManager) *JWKSHandler {
+	return &JWKSHandler{jwtManager: jwtManager}
+}
+
+// GetJWKS returns the JWK Set of every active and retired key. Verifiers may cache it briefly,
+// and should fetch it again when a token names a key they don't know.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	set, err := h.jwtManager.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish signing keys"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
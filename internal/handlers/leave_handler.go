@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"leave-management-system/internal/models"
 	"leave-management-system/internal/services"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type LeaveHandler struct {
@@ -20,12 +22,13 @@ func NewLeaveHandler(leaveService *services.LeaveService) *LeaveHandler {
 }
 
 type CreateLeaveRequest struct {
-	LeaveType              models.LeaveType `json:"leave_type" binding:"required"`
-	StartDate              time.Time        `json:"start_date" binding:"required"`
-	EndDate                time.Time        `json:"end_date" binding:"required"`
-	Reason                 string           `json:"reason" binding:"required"`
-	AttachmentURL          string           `json:"attachment_url"`
-	UnrecordedLeaveSubtype string           `json:"unrecorded_leave_subtype"`
+	LeaveType              models.LeaveType      `json:"leave_type" binding:"required"`
+	StartDate              time.Time             `json:"start_date" binding:"required"`
+	EndDate                time.Time             `json:"end_date" binding:"required"`
+	Reason                 string                `json:"reason" binding:"required"`
+	AttachmentURL          string                `json:"attachment_url"`
+	UnrecordedLeaveSubtype string                `json:"unrecorded_leave_subtype"`
+	HalfDaySession         models.HalfDaySession `json:"half_day_session"`
 }
 
 func (h *LeaveHandler) CreateLeaveRequest(c *gin.Context) {
@@ -57,6 +60,7 @@ func (h *LeaveHandler) CreateLeaveRequest(c *gin.Context) {
 		Reason:                 req.Reason,
 		AttachmentURL:          req.AttachmentURL,
 		UnrecordedLeaveSubtype: req.UnrecordedLeaveSubtype,
+		HalfDaySession:         req.HalfDaySession,
 	}
 
 	if err := h.leaveService.CreateLeaveRequest(userID, &leaveRequest); err != nil {
@@ -67,6 +71,52 @@ func (h *LeaveHandler) CreateLeaveRequest(c *gin.Context) {
 	c.JSON(http.StatusCreated, leaveRequest)
 }
 
+type AmendLeaveRequest struct {
+	CreateLeaveRequest
+	Comment string `json:"comment"` // Shown to the approver
+}
+
+func (h *LeaveHandler) AmendLeaveRequest(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var req AmendLeaveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.LeaveType == models.LeaveTypeUnrecorded && req.UnrecordedLeaveSubtype == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unrecorded leave type requires a specific type/reason"})
+		return
+	}
+
+	changes := models.LeaveRequest{
+		LeaveType:              req.LeaveType,
+		StartDate:              req.StartDate,
+		EndDate:                req.EndDate,
+		Reason:                 req.Reason,
+		AttachmentURL:          req.AttachmentURL,
+		UnrecordedLeaveSubtype: req.UnrecordedLeaveSubtype,
+		HalfDaySession:         req.HalfDaySession,
+	}
+
+	request, err := h.leaveService.AmendLeaveRequest(requestID, userID, &changes, req.Comment)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Leave request not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
 func (h *LeaveHandler) GetMyLeaveRequests(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 
@@ -133,7 +183,11 @@ func (h *LeaveHandler) ApproveLeaveRequest(c *gin.Context) {
 		return
 	}
 
-	if err := h.leaveService.ApproveLeave(requestID, approverID, req.Comment); err != nil {
+	err = h.leaveService.ApproveLeave(requestID, approverID, req.Comment, services.ChannelWeb)
+	if errors.Is(err, services.ErrNotAuthorizedToDecide) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -155,7 +209,11 @@ func (h *LeaveHandler) RejectLeaveRequest(c *gin.Context) {
 		return
 	}
 
-	if err := h.leaveService.RejectLeave(requestID, approverID, req.Comment); err != nil {
+	err = h.leaveService.RejectLeave(requestID, approverID, req.Comment, services.ChannelWeb)
+	if errors.Is(err, services.ErrNotAuthorizedToDecide) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
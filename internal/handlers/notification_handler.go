@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// maxPageSize caps the limit query parameter of paginated lists
+const maxPageSize = 200
+
+// parsePagination reads the page and limit query parameters, responding 400 when they are invalid
+func parsePagination(c *gin.Context, defaultLimit int) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		return 0, 0, false
+	}
+	return page, limit, true
+}
+
+// GetOutbox lists queued, sent and dead-lettered notification emails. Action links in the
+// bodies are redacted, since each is a live credential to decide as the approver.
+func (h *NotificationHandler) GetOutbox(c *gin.Context) {
+	page, limit, ok := parsePagination(c, 50)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	eventType := c.Query("event_type")
+	leaveRequestID := c.Query("leave_request_id")
+
+	messages, total, err := h.notificationService.GetOutbox(page, limit, status, eventType, leaveRequestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// GetNotifications lists the current user's in-app notifications, newest first
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	page, limit, ok := parsePagination(c, 20)
+	if !ok {
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := h.notificationService.GetNotifications(userID, unreadOnly, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	count, err := h.notificationService.GetUnreadCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	if err := h.notificationService.MarkRead(userID, id); errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	updated, err := h.notificationService.MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All notifications marked as read", "updated": updated})
+}
+
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	preferences, err := h.notificationService.GetPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []services.NotificationPreferenceInfo `json:"preferences" binding:"required"`
+}
+
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.notificationService.UpdatePreferences(userID, req.Preferences); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preferences, err := h.notificationService.GetPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+func (h *NotificationHandler) GetNotificationRules(c *gin.Context) {
+	rules, err := h.notificationService.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+type UpdateNotificationRuleRequest struct {
+	IsEnabled *bool `json:"is_enabled" binding:"required"`
+}
+
+// UpdateNotificationRule turns the email for one recipient of an event on or off
+func (h *NotificationHandler) UpdateNotificationRule(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	event := models.NotificationEvent(c.Param("event"))
+	recipient := models.NotificationRecipient(c.Param("recipient"))
+
+	var req UpdateNotificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.notificationService.UpdateRule(event, recipient, *req.IsEnabled, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification rule updated"})
+}
+
+// ResendOutboxMessage queues a message for immediate redelivery
+func (h *NotificationHandler) ResendOutboxMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	message, err := h.notificationService.Resend(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Outbox message not found"})
+		return
+	} else if errors.Is(err, services.ErrOutboxNotResendable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"leave-management-system/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetHandler serves the forgot-password flow. Both steps accept JSON from the web
+// app or a form post from the pages served here, which the emailed link opens.
+type PasswordResetHandler struct {
+	passwordResets *services.PasswordResetService
+	appURL         string
+}
+
+func NewPasswordResetHandler(passwordResets *services.PasswordResetService, appURL string) *PasswordResetHandler {
+	return &PasswordResetHandler{passwordResets: passwordResets, appURL: appURL}
+}
+
+const forgotPasswordMessage = "If an account exists for that email, a password reset link has been sent to it"
+
+var passwordResetPage = template.Must(template.New("password_reset").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Leave Management System</title>
+<style>
+body { font-family: sans-serif; max-width: 32rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+input { display: block; width: 100%; padding: 0.5rem; margin: 0.25rem 0 1rem; box-sizing: border-box; }
+button { padding: 0.5rem 1.25rem; font-size: 1rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+{{if .Done}}
+<h2>{{.Title}}</h2>
+<p>{{.Message}}</p>
+<p><a href="{{.AppURL}}">Go to the Leave Management System</a></p>
+{{else if .Token}}
+<h2>Choose a new password</h2>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+<form method="post" action="reset-password">
+  <input type="hidden" name="token" value="{{.Token}}">
+  <label for="new_password">New password</label>
+  <input id="new_password" name="new_password" type="password" minlength="8" required autocomplete="new-password">
+  <label for="confirm_password">Confirm new password</label>
+  <input id="confirm_password" name="confirm_password" type="password" minlength="8" required autocomplete="new-password">
+  <button type="submit">Set password</button>
+</form>
+{{else}}
+<h2>Forgot your password?</h2>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+<p>Enter your email address and we'll send you a link to choose a new password.</p>
+<form method="post" action="forgot-password">
+  <label for="email">Email</label>
+  <input id="email" name="email" type="email" required autocomplete="email">
+  <button type="submit">Send reset link</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+type passwordResetPageData struct {
+	Token   string
+	Done    bool
+	Title   string
+	Message string
+	Error   string
+	AppURL  string
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token" form:"token" binding:"required"`
+	NewPassword     string `json:"new_password" form:"new_password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" form:"confirm_password"`
+}
+
+// ShowForgotPassword renders the form asking for an email address
+func (h *PasswordResetHandler) ShowForgotPassword(c *gin.Context) {
+	h.render(c, http.StatusOK, passwordResetPageData{})
+}
+
+// ForgotPassword emails a reset link. The response is the same whether or not the address
+// belongs to an account.
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondError(c, http.StatusBadRequest, passwordResetPageData{}, err)
+		return
+	}
+
+	h.passwordResets.RequestReset(req.Email, c.ClientIP())
+
+	if wantsJSON(c) {
+		c.JSON(http.StatusAccepted, gin.H{"message": forgotPasswordMessage})
+		return
+	}
+	h.render(c, http.StatusAccepted, passwordResetPageData{Done: true, Title: "Check your email", Message: forgotPasswordMessage})
+}
+
+// ShowResetPassword renders the new password form the emailed link opens
+func (h *PasswordResetHandler) ShowResetPassword(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		h.render(c, http.StatusNotFound, passwordResetPageData{Error: services.ErrInvalidResetToken.Error()})
+		return
+	}
+	h.render(c, http.StatusOK, passwordResetPageData{Token: token})
+}
+
+// ResetPassword spends a reset token and sets the new password
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.respondError(c, http.StatusBadRequest, passwordResetPageData{Token: c.PostForm("token")}, err)
+		return
+	}
+	if req.ConfirmPassword != "" && req.ConfirmPassword != req.NewPassword {
+		h.respondError(c, http.StatusBadRequest, passwordResetPageData{Token: req.Token}, errors.New("passwords do not match"))
+		return
+	}
+
+	var policyErr *services.PasswordPolicyError
+	if err := h.passwordResets.ResetPassword(req.Token, req.NewPassword); errors.Is(err, services.ErrInvalidResetToken) {
+		h.respondError(c, http.StatusBadRequest, passwordResetPageData{Done: true, Title: "Unable to reset password", Message: err.Error()}, err)
+		return
+	} else if errors.As(err, &policyErr) {
+		h.respondError(c, http.StatusBadRequest, passwordResetPageData{Token: req.Token}, err)
+		return
+	} else if err != nil {
+		h.respondError(c, http.StatusInternalServerError, passwordResetPageData{Token: req.Token},
+			errors.New("failed to reset the password, please try again"))
+		return
+	}
+
+	const message = "Your password has been changed. Sign in with your new password."
+	if wantsJSON(c) {
+		c.JSON(http.StatusOK, gin.H{"message": message})
+		return
+	}
+	h.render(c, http.StatusOK, passwordResetPageData{Done: true, Title: "Password changed", Message: message})
+}
+
+// SendResetLink lets HR email a user a reset link from user management
+func (h *PasswordResetHandler) SendResetLink(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	requestedBy := c.MustGet("user_id").(uuid.UUID)
+
+	if err := h.passwordResets.SendResetLink(userID, requestedBy, c.ClientIP()); errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Password reset link sent"})
+}
+
+func (h *PasswordResetHandler) respondError(c *gin.Context, status int, data passwordResetPageData, err error) {
+	if wantsJSON(c) {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if data.Message == "" {
+		data.Error = err.Error()
+	}
+	h.render(c, status, data)
+}
+
+func (h *PasswordResetHandler) render(c *gin.Context, status int, data passwordResetPageData) {
+	data.AppURL = h.appURL
+	// The reset token is a credential, so keep it out of caches and referrers
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = passwordResetPage.Execute(c.Writer, data)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PermissionHandler lets system administrators change what each role may do
+type PermissionHandler struct {
+	permissions *services.PermissionService
+}
+
+func NewPermissionHandler(permissions *services.PermissionService) *PermissionHandler {
+	return &PermissionHandler{permissions: permissions}
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []models.Permission `json:"permissions" binding:"required"`
+}
+
+func (h *PermissionHandler) GetRolePermissions(c *gin.Context) {
+	roles, err := h.permissions.GetRolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles":          roles,
+		"permissions":    services.Permissions,
+		"editable_roles": services.PermissionRoles,
+	})
+}
+
+// UpdateRolePermissions replaces every permission of a role with the ones given
+func (h *PermissionHandler) UpdateRolePermissions(c *gin.Context) {
+	var req UpdateRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := models.UserRole(c.Param("role"))
+	err := h.permissions.SetRolePermissions(role, req.Permissions, c.MustGet("user_id").(uuid.UUID),
+		c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, services.ErrSysAdminNotEditable) || errors.Is(err, services.ErrUnknownPermissionRole) ||
+		errors.Is(err, services.ErrUnknownPermission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role permissions updated"})
+}
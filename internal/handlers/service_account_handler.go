@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"leave-management-system/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ServiceAccountHandler lets administrators manage service accounts and their API keys
+type ServiceAccountHandler struct {
+	apiKeys *services.APIKeyService
+}
+
+func NewServiceAccountHandler(apiKeys *services.APIKeyService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{apiKeys: apiKeys}
+}
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // Defaults to the configured key lifetime
+}
+
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.apiKeys.CreateServiceAccount(req.Name, req.Description, c.MustGet("user_id").(uuid.UUID),
+		c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+func (h *ServiceAccountHandler) GetServiceAccounts(c *gin.Context) {
+	accounts, err := h.apiKeys.GetServiceAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_accounts": accounts,
+		"scopes":           services.APIKeyScopes,
+	})
+}
+
+func (h *ServiceAccountHandler) GetServiceAccount(c *gin.Context) {
+	id, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.apiKeys.GetServiceAccount(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// UpdateServiceAccount changes the name, description or is_active of a service account
+func (h *ServiceAccountHandler) UpdateServiceAccount(c *gin.Context) {
+	id, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.apiKeys.UpdateServiceAccount(id, updates, c.MustGet("user_id").(uuid.UUID),
+		c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// CreateAPIKey issues a key for a service account. The key is only returned in this response.
+func (h *ServiceAccountHandler) CreateAPIKey(c *gin.Context) {
+	id, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.apiKeys.CreateKey(id, req.Name, req.Scopes, req.ExpiresAt, c.MustGet("user_id").(uuid.UUID),
+		c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *ServiceAccountHandler) RevokeAPIKey(c *gin.Context) {
+	id, ok := parseServiceAccountID(c)
+	if !ok {
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	err = h.apiKeys.RevokeKey(id, keyID, c.MustGet("user_id").(uuid.UUID), c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+func parseServiceAccountID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service account ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"leave-management-system/internal/services"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ssoStateCookie ties a sign in to the browser that started it
+const ssoStateCookie = "lms_sso_state"
+
+// SSOHandler runs the browser side of single sign-on. The callback never puts tokens in a
+// URL: it sends the browser back to the web app with a one-time handoff token, which the app
+// exchanges for a session at /sso/exchange.
+type SSOHandler struct {
+	sso    *services.SSOService
+	appURL string
+}
+
+func NewSSOHandler(sso *services.SSOService, appURL string) *SSOHandler {
+	return &SSOHandler{sso: sso, appURL: appURL}
+}
+
+// GetConfig tells the sign-in page whether to offer single sign-on
+func (h *SSOHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":       h.sso.Enabled(),
+		"provider_name": h.sso.ProviderName(),
+	})
+}
+
+// Login sends the browser to the identity provider
+func (h *SSOHandler) Login(c *gin.Context) {
+	authURL, state, err := h.sso.Begin(c.Request.Context(), c.ClientIP())
+	if errors.Is(err, services.ErrSSODisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		h.redirectToApp(c, "sso_error", "Single sign-on is unavailable, please try again later")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, state, 600, "/api/v1/sso", "", isHTTPS(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback receives the provider's response and hands the signed-in user to the web app
+func (h *SSOHandler) Callback(c *gin.Context) {
+	browserState, _ := c.Cookie(ssoStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, "", -1, "/api/v1/sso", "", isHTTPS(c), true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		message := "Single sign-on was cancelled or refused"
+		if description := c.Query("error_description"); description != "" {
+			message = description
+		}
+		h.redirectToApp(c, "sso_error", message)
+		return
+	}
+
+	attempt := services.LoginAttempt{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Endpoint:  c.Request.URL.Path,
+	}
+	handoff, err := h.sso.Callback(c.Request.Context(), c.Query("state"), browserState, c.Query("code"), attempt)
+	switch {
+	case err == nil:
+		h.redirectToApp(c, "sso", handoff)
+	case errors.Is(err, services.ErrInvalidSSOState),
+		errors.Is(err, services.ErrSSOUserNotFound),
+		errors.Is(err, services.ErrSSOEmailRequired),
+		errors.Is(err, services.ErrSSOUserInactive),
+		errors.Is(err, services.ErrSSOSubjectInUse),
+		errors.Is(err, services.ErrSSODisabled):
+		h.redirectToApp(c, "sso_error", err.Error())
+	default:
+		h.redirectToApp(c, "sso_error", "Single sign-on failed, please try again")
+	}
+}
+
+func (h *SSOHandler) redirectToApp(c *gin.Context, param, value string) {
+	c.Redirect(http.StatusFound, strings.TrimRight(h.appURL, "/")+"/login?"+url.Values{param: {value}}.Encode())
+}
+
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -6,6 +6,7 @@ import (
 	"leave-management-system/internal/models"
 	"leave-management-system/internal/services"
 	"leave-management-system/pkg/logger"
+	"net/http"
 	"strings"
 	"time"
 
@@ -13,6 +14,53 @@ import (
 	"github.com/google/uuid"
 )
 
+// credentialPaths carry passwords or tokens in their bodies, which are never written to the log
+var credentialPaths = []string{
+	"/login",
+	"/refresh",
+	"/logout",
+	"/change-password",
+	"/forgot-password",
+	"/reset-password",
+	"/2fa",
+	"/2fa/setup",
+	"/2fa/confirm",
+	"/2fa/recovery-codes",
+	"/2fa/disable",
+	"/sso/exchange",
+	"/keys",
+	"/webhooks",
+	"/rotate-secret",
+	"/calendar/feeds",
+}
+
+func isCredentialPath(path string) bool {
+	for _, p := range credentialPaths {
+		if strings.HasSuffix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenRoutes carry a secret token in the path. Their route template is logged instead of the
+// path, and their bodies are never written to the log.
+var tokenRoutes = map[string]bool{
+	"/api/v1/calendar/feeds/:token": true,
+	"/api/v1/actions/:token":        true,
+}
+
+// loggedPath is the request path, or the route template for routes with a token in the path
+func loggedPath(c *gin.Context) string {
+	if tokenRoutes[c.FullPath()] {
+		return c.FullPath()
+	}
+	return c.Request.URL.Path
+}
+
+// eventStreamRoute is the server-sent events route, which isn't audited
+const eventStreamRoute = "/api/v1/events"
+
 type AuditMiddleware struct {
 	auditLogger  *logger.AuditLogger
 	auditService *services.AuditService
@@ -34,6 +82,13 @@ func (m *AuditMiddleware) AuditLog() gin.HandlerFunc {
 			return
 		}
 
+		// The event stream never completes, so its response can't be captured. Match the route
+		// rather than a request header, which any client could send to dodge the audit log.
+		if c.Request.Method == http.MethodGet && c.FullPath() == eventStreamRoute {
+			c.Next()
+			return
+		}
+
 		// Capture request body
 		var requestBody []byte
 		if c.Request.Body != nil {
@@ -66,10 +121,16 @@ func (m *AuditMiddleware) AuditLog() gin.HandlerFunc {
 			role = val.(models.UserRole)
 		}
 
+		path := loggedPath(c)
+		requestLog, responseLog := string(requestBody), blw.body.String()
+		if isCredentialPath(c.Request.URL.Path) || tokenRoutes[c.FullPath()] {
+			requestLog, responseLog = "[redacted]", "[redacted]"
+		}
+
 		// Log audit trail to file
 		m.auditLogger.LogHTTP(
 			c.Request.Method,
-			c.Request.URL.Path,
+			path,
 			c.Writer.Status(),
 			duration,
 			c.ClientIP(),
@@ -77,8 +138,8 @@ func (m *AuditMiddleware) AuditLog() gin.HandlerFunc {
 			uid,
 			email,
 			role,
-			string(requestBody),
-			blw.body.String(),
+			requestLog,
+			responseLog,
 		)
 
 		// Save audit log to database (only for authenticated requests)
@@ -88,9 +149,9 @@ func (m *AuditMiddleware) AuditLog() gin.HandlerFunc {
 				ActorID:    uid,
 				ActorEmail: email,
 				ActorRole:  role,
-				Action:     c.Request.Method + " " + c.Request.URL.Path,
+				Action:     c.Request.Method + " " + path,
 				Method:     c.Request.Method,
-				Endpoint:   c.Request.URL.Path,
+				Endpoint:   path,
 				IPAddress:  c.ClientIP(),
 				UserAgent:  c.Request.UserAgent(),
 				CreatedAt:  time.Now(),
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"leave-management-system/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAuditLogPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	audit := NewAuditMiddleware(logger.NewAuditLogger(zap.New(core)), nil)
+
+	router := gin.New()
+	router.Use(audit.AuditLog())
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/api/v1/calendar/feeds/:token", ok)
+	router.POST("/api/v1/calendar/feeds", ok)
+	router.GET("/api/v1/actions/:token", ok)
+	router.POST("/api/v1/actions/:token", ok)
+	router.GET("/api/v1/leave-requests/:id", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"calendar feed token", http.MethodGet, "/api/v1/calendar/feeds/secret-feed-token", "/api/v1/calendar/feeds/:token"},
+		{"calendar feed creation", http.MethodPost, "/api/v1/calendar/feeds", "/api/v1/calendar/feeds"},
+		{"action link shown", http.MethodGet, "/api/v1/actions/v1.payload.signature", "/api/v1/actions/:token"},
+		{"action link used", http.MethodPost, "/api/v1/actions/v1.payload.signature", "/api/v1/actions/:token"},
+		{"ordinary route keeps its path", http.MethodGet, "/api/v1/leave-requests/42", "/api/v1/leave-requests/42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			entries := logs.TakeAll()
+			if len(entries) != 1 {
+				t.Fatalf("%d audit entries, want 1", len(entries))
+			}
+			if got := entries[0].ContextMap()["path"]; got != tt.want {
+				t.Errorf("logged path = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"leave-management-system/internal/services"
 	"leave-management-system/pkg/auth"
 	"net/http"
 	"strings"
@@ -10,12 +11,22 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// passwordChangeRoutes are all a user with a temporary or expired password can reach
+var passwordChangeRoutes = map[string]bool{
+	"GET /api/v1/profile":         true,
+	"PUT /api/v1/change-password": true,
+	"POST /api/v1/logout-all":     true,
+}
+
 type AuthMiddleware struct {
-	jwtManager *auth.JWTManager
+	jwtManager     *auth.JWTManager
+	sessionService *services.SessionService
+	permissions    *services.PermissionService
 }
 
-func NewAuthMiddleware(jwtManager *auth.JWTManager) *AuthMiddleware {
-	return &AuthMiddleware{jwtManager: jwtManager}
+func NewAuthMiddleware(jwtManager *auth.JWTManager, sessionService *services.SessionService,
+	permissions *services.PermissionService) *AuthMiddleware {
+	return &AuthMiddleware{jwtManager: jwtManager, sessionService: sessionService, permissions: permissions}
 }
 
 func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
@@ -41,33 +52,47 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
-		c.Next()
-	}
-}
-
-func (m *AuthMiddleware) RequireRole(requiredRole models.UserRole) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+		// A valid signature isn't enough: the session may have been logged out or revoked
+		state, err := m.sessionService.ValidateAccessToken(claims)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
 			c.Abort()
 			return
 		}
 
-		if role != requiredRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		if state.MustChangePassword && !passwordChangeRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "You must change your password before continuing",
+				"code":  "password_change_required",
+			})
 			c.Abort()
 			return
 		}
 
+		// Role comes from the user's current state, not the token, so a demotion applies at once
+		c.Set("user_id", claims.UserID)
+		c.Set("user_email", state.Email)
+		c.Set("user_role", state.Role)
 		c.Next()
 	}
 }
 
-func (m *AuthMiddleware) RequireAnyRole(requiredRoles []models.UserRole) gin.HandlerFunc {
+// AuthenticateStream is Authenticate for EventSource connections, which cannot set headers.
+// The token may be passed in the access_token query parameter instead.
+func (m *AuthMiddleware) AuthenticateStream() gin.HandlerFunc {
+	authenticate := m.Authenticate()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		authenticate(c)
+	}
+}
+
+// RequirePermission lets through users whose role holds the permission
+func (m *AuthMiddleware) RequirePermission(permission models.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("user_role")
 		if !exists {
@@ -76,15 +101,12 @@ func (m *AuthMiddleware) RequireAnyRole(requiredRoles []models.UserRole) gin.Han
 			return
 		}
 
-		userRole := role.(models.UserRole)
-		for _, requiredRole := range requiredRoles {
-			if userRole == requiredRole {
-				c.Next()
-				return
-			}
+		if !m.permissions.Has(role.(models.UserRole), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
 		}
 
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-		c.Abort()
+		c.Next()
 	}
 }
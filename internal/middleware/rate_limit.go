@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// RateLimiter allows a fixed number of requests per key in each window. Counts are kept in
+// memory, so each instance limits on its own.
+type RateLimiter struct {
+	limit     int
+	window    time.Duration
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	nextSweep time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow counts a request for key and reports whether it is within the limit. When it isn't,
+// retryAfter is how long until the window resets.
+func (rl *RateLimiter) Allow(key string) (allowed bool, retryAfter time.Duration) {
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.After(rl.nextSweep) {
+		for k, w := range rl.windows {
+			if now.After(w.resetAt) {
+				delete(rl.windows, k)
+			}
+		}
+		rl.nextSweep = now.Add(rl.window)
+	}
+
+	w, ok := rl.windows[key]
+	if !ok || now.After(w.resetAt) {
+		w = &rateWindow{resetAt: now.Add(rl.window)}
+		rl.windows[key] = w
+	}
+	if w.count >= rl.limit {
+		return false, w.resetAt.Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// LimitByIP rejects requests from a client IP over the limit with 429 Too Many Requests
+func (rl *RateLimiter) LimitByIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if allowed, retryAfter := rl.Allow(c.ClientIP()); !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
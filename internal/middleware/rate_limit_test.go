@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	const window = 100 * time.Millisecond
+	rl := NewRateLimiter(3, window)
+
+	tests := []struct {
+		name    string
+		key     string
+		wait    time.Duration
+		allowed bool
+	}{
+		{"first", "10.0.0.1", 0, true},
+		{"second", "10.0.0.1", 0, true},
+		{"third", "10.0.0.1", 0, true},
+		{"over the limit", "10.0.0.1", 0, false},
+		{"other key has its own window", "10.0.0.2", 0, true},
+		{"still over the limit", "10.0.0.1", 0, false},
+		{"window reset", "10.0.0.1", window + 20*time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Sleep(tt.wait)
+			allowed, retryAfter := rl.Allow(tt.key)
+			if allowed != tt.allowed {
+				t.Fatalf("Allow(%q) = %v, want %v", tt.key, allowed, tt.allowed)
+			}
+			if allowed && retryAfter != 0 {
+				t.Errorf("retryAfter = %v for an allowed request", retryAfter)
+			}
+			if !allowed && (retryAfter <= 0 || retryAfter > window) {
+				t.Errorf("retryAfter = %v, want within (0, %v]", retryAfter, window)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweepsExpiredWindows(t *testing.T) {
+	const window = 50 * time.Millisecond
+	rl := NewRateLimiter(1, window)
+	rl.Allow("10.0.0.1")
+	rl.Allow("10.0.0.2")
+
+	time.Sleep(window + 20*time.Millisecond)
+	rl.Allow("10.0.0.3")
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if len(rl.windows) != 1 {
+		t.Errorf("%d windows kept, want only the live one", len(rl.windows))
+	}
+}
+
+func TestLimitByIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := NewRateLimiter(2, time.Minute)
+	router := gin.New()
+	router.POST("/login", rl.LimitByIP(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		remoteAddr string
+		want       int
+	}{
+		{"192.0.2.1:1234", http.StatusOK},
+		{"192.0.2.1:1235", http.StatusOK},
+		{"192.0.2.1:1236", http.StatusTooManyRequests},
+		{"192.0.2.2:1234", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.remoteAddr, w.Code, tt.want)
+		}
+		if tt.want == http.StatusTooManyRequests {
+			seconds, err := strconv.Atoi(w.Header().Get("Retry-After"))
+			if err != nil || seconds < 1 || seconds > 60 {
+				t.Errorf("Retry-After = %q, want 1 to 60 seconds", w.Header().Get("Retry-After"))
+			}
+		}
+	}
+}
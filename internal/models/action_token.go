@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActionToken backs a signed approve/reject link sent to an approver. The link carries the
+// token ID; the row makes it single-use. Approve and reject links for the same message share
+// one token, so using either spends both.
+type ActionToken struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	LeaveRequestID uuid.UUID  `gorm:"type:uuid;not null;index" json:"leave_request_id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"` // Approver the link acts as
+	Channel        string     `gorm:"type:varchar(20);not null" json:"channel"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at"`
+	UsedAction     string     `gorm:"type:varchar(20)" json:"used_action"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CalendarFeedKind string
+
+const (
+	FeedKindMyLeave   CalendarFeedKind = "my_leave"
+	FeedKindTeamLeave CalendarFeedKind = "team_leave"
+	FeedKindHolidays  CalendarFeedKind = "holidays"
+)
+
+// CalendarFeedToken grants read access to a user's iCalendar subscription feed.
+// Only the SHA-256 hash of the token is stored.
+type CalendarFeedToken struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	UserID         uuid.UUID        `gorm:"not null;index" json:"user_id"`
+	Kind           CalendarFeedKind `gorm:"type:varchar(20);not null" json:"kind"`
+	TokenHash      string           `gorm:"uniqueIndex;not null" json:"-"`
+	LastAccessedAt *time.Time       `json:"last_accessed_at"`
+	RevokedAt      *time.Time       `json:"revoked_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
@@ -32,29 +32,50 @@ const (
 	StatusEscalated LeaveStatus = "escalated"
 )
 
+// HalfDaySession marks a single-day leave as covering only the morning or afternoon
+type HalfDaySession string
+
+const (
+	SessionMorning   HalfDaySession = "am"
+	SessionAfternoon HalfDaySession = "pm"
+)
+
+// EscalationLevel is how far up the escalation ladder a request has moved
+type EscalationLevel int
+
+const (
+	EscalationNone          EscalationLevel = 0 // With the employee's manager
+	EscalationSeniorManager EscalationLevel = 1 // With the manager's manager
+	EscalationHOD           EscalationLevel = 2 // With the head of the employee's department
+	EscalationHR            EscalationLevel = 3 // With HR, no individual approver
+)
+
 type LeaveRequest struct {
-	ID                     uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
-	UserID                 uuid.UUID    `gorm:"not null" json:"user_id"`
-	User                   User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	LeaveType              LeaveType    `gorm:"type:varchar(20);not null" json:"leave_type"`
-	StartDate              time.Time    `gorm:"not null" json:"start_date"`
-	EndDate                time.Time    `gorm:"not null" json:"end_date"`
-	DurationDays           float64      `gorm:"not null" json:"duration_days"` // Float for half-day leaves
-	Reason                 string       `json:"reason"`
-	Status                 LeaveStatus  `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	ApproverID             *uuid.UUID   `json:"approver_id"`
-	Approver               *User        `gorm:"foreignKey:ApproverID" json:"approver,omitempty"`
-	ApprovedAt             *time.Time   `json:"approved_at"`
-	RejectedAt             *time.Time   `json:"rejected_at"`
-	RejectionReason        string       `json:"rejection_reason"`
-	AttachmentURL          string       `json:"attachment_url"`
-	AttachmentFileName     string       `json:"attachment_file_name"`
-	IsEscalated            bool         `gorm:"default:false" json:"is_escalated"`
-	EscalatedAt            *time.Time   `json:"escalated_at"`
-	UnrecordedLeaveSubtype string       `json:"unrecorded_leave_subtype"` // For marriage, compassionate, hajj
-	ChronologyEntries      []Chronology `gorm:"foreignKey:LeaveRequestID" json:"chronology_entries,omitempty"`
-	CreatedAt              time.Time    `json:"created_at"`
-	UpdatedAt              time.Time    `json:"updated_at"`
+	ID                     uuid.UUID       `gorm:"type:uuid;primary_key" json:"id"`
+	UserID                 uuid.UUID       `gorm:"not null" json:"user_id"`
+	User                   User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	LeaveType              LeaveType       `gorm:"type:varchar(20);not null" json:"leave_type"`
+	StartDate              time.Time       `gorm:"not null" json:"start_date"`
+	EndDate                time.Time       `gorm:"not null" json:"end_date"`
+	DurationDays           float64         `gorm:"not null" json:"duration_days"`           // Float for half-day leaves
+	HalfDaySession         HalfDaySession  `gorm:"type:varchar(2)" json:"half_day_session"` // Empty for full-day leave
+	Reason                 string          `json:"reason"`
+	Status                 LeaveStatus     `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	ApproverID             *uuid.UUID      `json:"approver_id"`
+	Approver               *User           `gorm:"foreignKey:ApproverID" json:"approver,omitempty"`
+	ApprovedAt             *time.Time      `json:"approved_at"`
+	RejectedAt             *time.Time      `json:"rejected_at"`
+	RejectionReason        string          `json:"rejection_reason"`
+	AttachmentURL          string          `json:"attachment_url"`
+	AttachmentFileName     string          `json:"attachment_file_name"`
+	IsEscalated            bool            `gorm:"default:false" json:"is_escalated"`
+	EscalatedAt            *time.Time      `json:"escalated_at"` // Time of the latest escalation step
+	EscalationLevel        EscalationLevel `gorm:"default:0" json:"escalation_level"`
+	LastReminderAt         *time.Time      `json:"last_reminder_at"`
+	UnrecordedLeaveSubtype string          `json:"unrecorded_leave_subtype"` // For marriage, compassionate, hajj
+	ChronologyEntries      []Chronology    `gorm:"foreignKey:LeaveRequestID" json:"chronology_entries,omitempty"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UpdatedAt              time.Time       `json:"updated_at"`
 }
 
 type LeaveBalance struct {
@@ -67,19 +88,21 @@ type LeaveBalance struct {
 	CarriedForward   float64   `gorm:"not null;default:0" json:"carried_forward"`
 	Adjusted         float64   `gorm:"not null;default:0" json:"adjusted"` // Manual adjustments by HR
 	IsOverridden     bool      `gorm:"default:false" json:"is_overridden"` // HR override flag
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	// Set once the owner has been warned that unused carried-forward days expire soon
+	CarryForwardNotifiedAt *time.Time `json:"carry_forward_notified_at"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
 }
 
 type Chronology struct {
-	ID             uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	LeaveRequestID uuid.UUID `gorm:"not null;index" json:"leave_request_id"`
-	Action         string    `gorm:"not null" json:"action"` // submitted, modified, commented, approved, rejected
-	ActorID        uuid.UUID `gorm:"not null" json:"actor_id"`
-	Actor          User      `gorm:"foreignKey:ActorID" json:"actor"`
-	Comment        string    `json:"comment"`
-	Metadata       JSONMap   `gorm:"type:jsonb" json:"metadata"` // Store before/after states
-	CreatedAt      time.Time `json:"created_at"`
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	LeaveRequestID uuid.UUID  `gorm:"not null;index" json:"leave_request_id"`
+	Action         string     `gorm:"not null" json:"action"` // submitted, modified, commented, approved, rejected
+	ActorID        *uuid.UUID `json:"actor_id"`               // Nil for automatic decisions
+	Actor          *User      `gorm:"foreignKey:ActorID" json:"actor"`
+	Comment        string     `json:"comment"`
+	Metadata       JSONMap    `gorm:"type:jsonb" json:"metadata"` // Store before/after states
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 type PublicHoliday struct {
@@ -92,6 +115,24 @@ type PublicHoliday struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// BlackoutPeriod blocks leave applications for a date range.
+// Empty Department, Role or LeaveType means the period applies to all of them.
+type BlackoutPeriod struct {
+	ID                   uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name                 string     `gorm:"not null" json:"name"`
+	Description          string     `json:"description"`
+	StartDate            time.Time  `gorm:"not null;index" json:"start_date"`
+	EndDate              time.Time  `gorm:"not null;index" json:"end_date"`
+	Department           string     `json:"department"`
+	Role                 UserRole   `gorm:"type:varchar(20)" json:"role"`
+	LeaveType            LeaveType  `gorm:"type:varchar(20)" json:"leave_type"`
+	ExemptEmergencyLeave bool       `gorm:"default:false" json:"exempt_emergency_leave"` // Emergency and sick leave bypass the blackout
+	IsActive             bool       `gorm:"default:true" json:"is_active"`
+	CreatedByID          *uuid.UUID `json:"created_by_id"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
 // LeaveTypeConfig stores configurable settings for each leave type
 type LeaveTypeConfig struct {
 	ID                    uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
@@ -104,12 +145,40 @@ type LeaveTypeConfig struct {
 	MaxDaysPerApplication *int      `json:"max_days_per_application"`
 	RequiresAttachment    bool      `gorm:"default:false" json:"requires_attachment"`
 	MinAdvanceDays        int       `gorm:"default:0" json:"min_advance_days"`
+	ReminderDays          *int      `json:"reminder_days"`   // Working days pending before reminders; nil uses the system setting
+	EscalationDays        *int      `json:"escalation_days"` // Working days pending before escalation; nil uses the system setting
 	IsActive              bool      `gorm:"default:true" json:"is_active"`
 	DisplayOrder          int       `gorm:"default:0" json:"display_order"`
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 }
 
+// Actions of audit entries written for sign-in and account security events
+const (
+	AuditLoginFailed     = "auth.login_failed"
+	AuditAccountLocked   = "auth.account_locked"
+	AuditAccountUnlocked = "auth.account_unlocked"
+
+	AuditTwoFactorEnabled         = "auth.two_factor_enabled"
+	AuditTwoFactorDisabled        = "auth.two_factor_disabled"
+	AuditTwoFactorReset           = "auth.two_factor_reset"
+	AuditRecoveryCodeUsed         = "auth.recovery_code_used"
+	AuditRecoveryCodesRegenerated = "auth.recovery_codes_regenerated"
+
+	AuditSSOLogin           = "auth.sso_login"
+	AuditSSOUserLinked      = "auth.sso_user_linked"
+	AuditSSOUserProvisioned = "auth.sso_user_provisioned"
+	AuditSSOLoginRejected   = "auth.sso_login_rejected"
+
+	AuditServiceAccountCreated = "auth.service_account_created"
+	AuditServiceAccountUpdated = "auth.service_account_updated"
+	AuditAPIKeyCreated         = "auth.api_key_created"
+	AuditAPIKeyRevoked         = "auth.api_key_revoked"
+	AuditAPIKeyUsed            = "auth.api_key_used"
+
+	AuditRolePermissionsChanged = "auth.role_permissions_changed"
+)
+
 type AuditLog struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
 	ActorID     uuid.UUID `gorm:"not null" json:"actor_id"`
@@ -145,3 +214,34 @@ func (j *JSONMap) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(b, &j)
 }
+
+// StringList for storing a list of strings as a JSON array
+type StringList []string
+
+func (l StringList) GormDataType() string {
+	return "jsonb"
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
+
+func (l StringList) Contains(s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
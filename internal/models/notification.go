@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailTemplate overrides a built-in email template for one locale.
+// Body holds "subject", "text" and "html" template definitions.
+type EmailTemplate struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name        string     `gorm:"not null;uniqueIndex:idx_email_template_name_locale" json:"name"`
+	Locale      string     `gorm:"type:varchar(5);not null;uniqueIndex:idx_email_template_name_locale" json:"locale"`
+	Body        string     `gorm:"type:text;not null" json:"body"`
+	UpdatedByID *uuid.UUID `json:"updated_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type NotificationEvent string
+
+const (
+	EventLeaveSubmitted NotificationEvent = "leave.submitted"
+	EventLeaveApproved  NotificationEvent = "leave.approved"
+	EventLeaveRejected  NotificationEvent = "leave.rejected"
+	EventLeaveCancelled NotificationEvent = "leave.cancelled"
+	EventLeaveEscalated NotificationEvent = "leave.escalated"
+	EventLeaveReminder  NotificationEvent = "leave.reminder"
+	EventLeaveAmended   NotificationEvent = "leave.amended"
+
+	EventCarryForwardExpiring NotificationEvent = "carry_forward.expiring"
+
+	EventPasswordReset NotificationEvent = "account.password_reset" // Always emailed, not user configurable
+)
+
+type NotificationRecipient string
+
+const (
+	RecipientEmployee NotificationRecipient = "employee" // The user who applied for leave
+	RecipientManager  NotificationRecipient = "manager"  // The employee's line manager
+	RecipientApprover NotificationRecipient = "approver" // Whoever the request is currently assigned to
+	RecipientHR       NotificationRecipient = "hr"
+)
+
+// NotificationRule turns the email for one recipient of an event on or off
+type NotificationRule struct {
+	ID          uuid.UUID             `gorm:"type:uuid;primary_key" json:"id"`
+	Event       NotificationEvent     `gorm:"type:varchar(50);not null;uniqueIndex:idx_notification_rule" json:"event"`
+	Recipient   NotificationRecipient `gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_rule" json:"recipient"`
+	IsEnabled   bool                  `gorm:"not null;default:false" json:"is_enabled"`
+	UpdatedByID *uuid.UUID            `gorm:"type:uuid" json:"updated_by_id"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSending OutboxStatus = "sending" // Claimed by a dispatcher until next_attempt_at
+	OutboxStatusSent    OutboxStatus = "sent"
+	OutboxStatusDead    OutboxStatus = "dead" // Gave up after MaxAttempts
+)
+
+// NotificationOutbox holds a rendered email until the dispatcher delivers it.
+// Rows are written in the same transaction as the change that triggered them.
+type NotificationOutbox struct {
+	ID             uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	EventType      NotificationEvent `gorm:"type:varchar(50);not null;index" json:"event_type"`
+	LeaveRequestID *uuid.UUID        `gorm:"type:uuid;index" json:"leave_request_id"`
+	Recipient      string            `gorm:"not null" json:"recipient"`
+	Subject        string            `gorm:"not null" json:"subject"`
+	TextBody       string            `gorm:"type:text" json:"text_body"`
+	HTMLBody       string            `gorm:"type:text" json:"html_body"`
+	CalendarMethod string            `gorm:"type:varchar(20)" json:"calendar_method"` // Set when an invite is attached
+	CalendarBody   string            `gorm:"type:text" json:"-"`
+	Status         OutboxStatus      `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts       int               `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time         `gorm:"not null;index" json:"next_attempt_at"`
+	LastError      string            `json:"last_error"`
+	SentAt         *time.Time        `json:"sent_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Notification is an in-app alert shown in the user's inbox
+type Notification struct {
+	ID             uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	UserID         uuid.UUID         `gorm:"type:uuid;not null;index:idx_notifications_user_created" json:"user_id"`
+	EventType      NotificationEvent `gorm:"type:varchar(50);not null" json:"event_type"`
+	Title          string            `gorm:"not null" json:"title"`
+	Body           string            `json:"body"`
+	LeaveRequestID *uuid.UUID        `gorm:"type:uuid" json:"leave_request_id"`
+	ReadAt         *time.Time        `json:"read_at"`
+	CreatedAt      time.Time         `gorm:"index:idx_notifications_user_created" json:"created_at"`
+}
+
+// NotificationPreference chooses the channels a user receives an event on.
+// Events without a preference are delivered on every channel.
+type NotificationPreference struct {
+	ID        uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_notification_preference" json:"user_id"`
+	Event     NotificationEvent `gorm:"type:varchar(50);not null;uniqueIndex:idx_notification_preference" json:"event"`
+	InApp     bool              `gorm:"not null;default:false" json:"in_app"`
+	Email     bool              `gorm:"not null;default:false" json:"email"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordResetToken backs a one-time password reset link. Only the SHA-256 of the token is
+// stored; the token itself is only ever in the email.
+type PasswordResetToken struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash     string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	RequestedByID *uuid.UUID `gorm:"type:uuid" json:"requested_by_id"` // HR user who sent the link, nil when self-service
+	IPAddress     string     `gorm:"type:varchar(45)" json:"ip_address"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Permission names something a role may do. Routes and services check permissions rather
+// than roles, so what each role may do can be changed without a release.
+type Permission string
+
+const (
+	PermLeaveApprove          Permission = "leave.approve"           // Decide requests routed to them and see their team's
+	PermLeaveApproveEscalated Permission = "leave.approve.escalated" // Decide requests escalated past the manager
+	PermLeaveViewAll          Permission = "leave.view_all"          // See every request, the company calendar and live leave events
+	PermBalanceAdjust         Permission = "balance.adjust"
+	PermReportView            Permission = "report.view"
+	PermUserView              Permission = "user.view"
+	PermUserCreate            Permission = "user.create"
+	PermUserCreateAdmin       Permission = "user.create.admin" // Create users with, or give users, an admin role
+	PermUserUpdate            Permission = "user.update"       // Edit, deactivate and confirm probation
+	PermUserUnlock            Permission = "user.unlock"       // Unlock accounts and send password reset links
+	PermUserResetTwoFactor    Permission = "user.reset_2fa"
+	PermHolidayManage         Permission = "holiday.manage"
+	PermBlackoutManage        Permission = "blackout.manage"
+	PermConfigManage          Permission = "config.manage" // System settings and leave types
+	PermYearEndRun            Permission = "year_end.run"
+	PermAuditView             Permission = "audit.view"
+	PermNotificationManage    Permission = "notification.manage" // Email templates, notification rules and the outbox
+	PermWebhookManage         Permission = "webhook.manage"
+	PermServiceAccountManage  Permission = "service_account.manage"
+	PermPermissionManage      Permission = "permission.manage"
+	PermSystemMaintain        Permission = "system.maintain"
+)
+
+// RolePermission grants a permission to every user with the role
+type RolePermission struct {
+	Role       UserRole   `gorm:"type:varchar(20);primaryKey" json:"role"`
+	Permission Permission `gorm:"type:varchar(50);primaryKey" json:"permission"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is a server-side refresh token. Using one rotates it: the row is revoked and
+// points at its replacement. Every token descended from one login shares a FamilyID, which is
+// the session ID carried in access tokens, so revoking the family ends the session.
+type RefreshToken struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	FamilyID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"family_id"`
+	TokenHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	UserAgent    string     `json:"user_agent"`
+	IPAddress    string     `gorm:"type:varchar(45)" json:"ip_address"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uuid.UUID `gorm:"type:uuid" json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
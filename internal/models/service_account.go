@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAccount is a non-human identity an integration signs in as with API keys
+type ServiceAccount struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Name        string    `gorm:"not null;uniqueIndex" json:"name"`
+	Description string    `json:"description"`
+	IsActive    bool      `gorm:"not null;default:false" json:"is_active"`
+	CreatedByID uuid.UUID `gorm:"type:uuid;not null" json:"created_by_id"`
+	APIKeys     []APIKey  `gorm:"foreignKey:ServiceAccountID" json:"api_keys,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// APIKey authenticates a service account for the scopes it was given. Only a hash of the key
+// is stored; Prefix identifies it in lists and logs.
+type APIKey struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ServiceAccountID uuid.UUID  `gorm:"type:uuid;not null;index" json:"service_account_id"`
+	Name             string     `gorm:"not null" json:"name"`
+	Prefix           string     `gorm:"type:varchar(20);not null" json:"prefix"`
+	KeyHash          string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes           StringList `json:"scopes"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	LastUsedIP       string     `json:"last_used_ip"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	RevokedByID      *uuid.UUID `gorm:"type:uuid" json:"revoked_by_id"`
+	CreatedByID      uuid.UUID  `gorm:"type:uuid;not null" json:"created_by_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
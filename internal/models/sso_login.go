@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SSOLogin tracks one single sign-on attempt. It starts with the state, nonce and PKCE
+// verifier sent to the provider. After the callback it holds a short-lived handoff token the
+// web app exchanges for a session, so tokens never appear in a redirect URL. Only hashes of
+// the state and handoff token are stored.
+type SSOLogin struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	StateHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Nonce        string     `gorm:"not null" json:"-"`
+	CodeVerifier string     `gorm:"not null" json:"-"`
+	UserID       *uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	HandoffHash  *string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	ProviderMFA  bool       `gorm:"not null;default:false" json:"provider_mfa"` // The ID token showed MFA at a trusted provider
+	IPAddress    string     `gorm:"type:varchar(45)" json:"ip_address"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	CompletedAt  *time.Time `json:"completed_at"` // When the provider sent the user back
+	UsedAt       *time.Time `json:"used_at"`      // When the handoff token was exchanged
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactorSecret is a user's TOTP authenticator. It stays pending until the user proves
+// they added it by entering a code, and the secret is stored encrypted.
+type TwoFactorSecret struct {
+	UserID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"user_id"`
+	EncryptedSecret string     `gorm:"not null" json:"-"`
+	ConfirmedAt     *time.Time `json:"confirmed_at"`
+	LastUsedStep    int64      `gorm:"not null;default:0" json:"-"` // Time step of the last accepted code, so a code can't be replayed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// RecoveryCode is a single-use code that stands in for an authenticator code. Only its
+// SHA-256 is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginChallenge is the half-finished sign-in of a user who has given their password and
+// still owes a second factor. The client holds the token; only its SHA-256 is stored.
+type LoginChallenge struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	IPAddress string     `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent string     `json:"user_agent"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -19,26 +19,44 @@ const (
 )
 
 type User struct {
-	ID                uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	Email             string         `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash      string         `gorm:"not null" json:"-"`
-	FirstName         string         `gorm:"not null" json:"first_name"`
-	LastName          string         `gorm:"not null" json:"last_name"`
-	Role              UserRole       `gorm:"type:varchar(20);not null" json:"role"`
-	Department        string         `json:"department"`
-	Position          string         `json:"position"`
-	ManagerID         *uuid.UUID     `json:"manager_id"`
-	Manager           *User          `gorm:"foreignKey:ManagerID" json:"manager,omitempty"`
-	JoinedDate        time.Time      `gorm:"not null" json:"joined_date"`
-	ProbationEndDate  *time.Time     `json:"probation_end_date"`
-	IsConfirmed       bool           `gorm:"default:false" json:"is_confirmed"`
-	IsActive          bool           `gorm:"default:true" json:"is_active"`
-	LeaveEntitlements []LeaveBalance `gorm:"foreignKey:UserID" json:"leave_entitlements,omitempty"`
-	LeaveRequests     []LeaveRequest `gorm:"foreignKey:UserID" json:"leave_requests,omitempty"`
-	ManagedUsers      []User         `gorm:"foreignKey:ManagerID" json:"managed_users,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	LastLoginAt       *time.Time     `json:"last_login_at"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Email              string         `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash       string         `gorm:"not null" json:"-"`
+	FirstName          string         `gorm:"not null" json:"first_name"`
+	LastName           string         `gorm:"not null" json:"last_name"`
+	Role               UserRole       `gorm:"type:varchar(20);not null" json:"role"`
+	Department         string         `json:"department"`
+	Position           string         `json:"position"`
+	State              string         `json:"state"`                                      // Used to resolve state public holidays
+	Locale             string         `gorm:"type:varchar(5);default:'en'" json:"locale"` // Preferred language for notifications
+	ManagerID          *uuid.UUID     `json:"manager_id"`
+	Manager            *User          `gorm:"foreignKey:ManagerID" json:"manager,omitempty"`
+	JoinedDate         time.Time      `gorm:"not null" json:"joined_date"`
+	ProbationEndDate   *time.Time     `json:"probation_end_date"`
+	IsConfirmed        bool           `gorm:"default:false" json:"is_confirmed"`
+	IsActive           bool           `gorm:"default:true" json:"is_active"`
+	TokenVersion       int            `gorm:"not null;default:0" json:"-"`                        // Bumped to revoke every token issued to the user
+	MustChangePassword bool           `gorm:"not null;default:false" json:"must_change_password"` // Temporary or expired password; only a password change is allowed
+	PasswordChangedAt  *time.Time     `json:"password_changed_at"`
+	FailedLoginCount   int            `gorm:"not null;default:0" json:"failed_login_count"` // Failed sign-ins since the last success
+	LastFailedLoginAt  *time.Time     `json:"last_failed_login_at"`
+	LockedUntil        *time.Time     `json:"locked_until"`
+	TwoFactorEnabled   bool           `gorm:"not null;default:false" json:"two_factor_enabled"`
+	SSOSubject         *string        `gorm:"uniqueIndex" json:"-"` // Subject at the single sign-on provider, once linked
+	LeaveEntitlements  []LeaveBalance `gorm:"foreignKey:UserID" json:"leave_entitlements,omitempty"`
+	LeaveRequests      []LeaveRequest `gorm:"foreignKey:UserID" json:"leave_requests,omitempty"`
+	ManagedUsers       []User         `gorm:"foreignKey:ManagerID" json:"managed_users,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	LastLoginAt        *time.Time     `json:"last_login_at"`
+}
+
+// PasswordHistory keeps a user's previous password hashes so old passwords can't be reused
+type PasswordHistory struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription is an external endpoint that receives leave events
+type WebhookSubscription struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name           string     `gorm:"not null" json:"name"`
+	URL            string     `gorm:"not null" json:"url"`
+	Secret         string     `gorm:"not null" json:"-"`                             // HMAC key for the signature header
+	Events         StringList `json:"events"`                                        // Empty subscribes to every event
+	IncludeActions bool       `gorm:"not null;default:false" json:"include_actions"` // Add approve/reject links acting as the approver
+	IsActive       bool       `gorm:"not null;default:false" json:"is_active"`
+	CreatedByID    uuid.UUID  `gorm:"type:uuid;not null" json:"created_by_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySending   WebhookDeliveryStatus = "sending" // Claimed by a dispatcher until next_attempt_at
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed" // Gave up after the last retry
+)
+
+// WebhookDelivery is one event queued for one subscription
+type WebhookDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;primary_key" json:"id"`
+	SubscriptionID uuid.UUID             `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	EventID        uuid.UUID             `gorm:"type:uuid;not null;index" json:"event_id"` // Shared by redeliveries of the same event
+	EventType      string                `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        string                `gorm:"type:text;not null" json:"payload"`
+	ActionTokenID  *uuid.UUID            `gorm:"type:uuid" json:"-"` // Its links are added when posted, never stored
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time             `gorm:"not null;index" json:"next_attempt_at"`
+	LastStatusCode int                   `json:"last_status_code"`
+	LastError      string                `json:"last_error"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	AttemptLog     []WebhookAttempt      `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// WebhookAttempt records the outcome of a single HTTP request for a delivery
+type WebhookAttempt struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	DeliveryID   uuid.UUID `gorm:"type:uuid;not null;index" json:"delivery_id"`
+	Number       int       `gorm:"not null" json:"number"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody string    `gorm:"type:text" json:"response_body"` // Truncated
+	Error        string    `json:"error"`
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,176 @@
+package realtime
+
+import (
+	"encoding/json"
+	"leave-management-system/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Size of each client's send buffer; clients that fall this far behind are disconnected
+// and replay what they missed when they reconnect
+const clientBufferSize = 64
+
+// Event is a message pushed to connected clients
+type Event struct {
+	ID        uint64
+	Type      string
+	Data      []byte // JSON payload
+	Audience  Audience
+	CreatedAt time.Time
+}
+
+// Audience selects which users receive an event
+type Audience struct {
+	UserIDs []uuid.UUID
+	Roles   []models.UserRole
+}
+
+func (a Audience) Includes(userID uuid.UUID, role models.UserRole) bool {
+	for _, id := range a.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Client is one open event stream
+type Client struct {
+	UserID uuid.UUID
+	Role   models.UserRole
+	Events chan Event // Closed when the hub drops the client
+}
+
+// Hub fans events out to connected clients and keeps recent history so clients can
+// resume from Last-Event-ID after reconnecting
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	history []Event // Ring buffer ordered by ID
+	start   int     // Index of the oldest event in history
+	size    int
+	nextID  uint64
+}
+
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		clients: make(map[*Client]struct{}),
+		history: make([]Event, historySize),
+		// Start from the clock so IDs keep increasing across restarts
+		nextID: uint64(time.Now().UnixMicro()),
+	}
+}
+
+// Publish sends an event to every connected client in the audience
+func (h *Hub) Publish(eventType string, data interface{}, audience Audience) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	event := Event{
+		ID:        h.nextID,
+		Type:      eventType,
+		Data:      payload,
+		Audience:  audience,
+		CreatedAt: time.Now(),
+	}
+	h.remember(event)
+
+	for client := range h.clients {
+		if !audience.Includes(client.UserID, client.Role) {
+			continue
+		}
+		select {
+		case client.Events <- event:
+		default:
+			// Slow consumer: disconnect it rather than block other clients
+			h.drop(client)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a client. When lastEventID is non-zero the missed events the client
+// may see are returned for replay; resync is true when some of them are no longer retained
+// and the client should reload its state.
+func (h *Hub) Subscribe(userID uuid.UUID, role models.UserRole, lastEventID uint64) (client *Client, replay []Event, resync bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client = &Client{
+		UserID: userID,
+		Role:   role,
+		Events: make(chan Event, clientBufferSize),
+	}
+	h.clients[client] = struct{}{}
+
+	if lastEventID == 0 {
+		return client, nil, false
+	}
+
+	if lastEventID > h.nextID {
+		// ID from another hub instance
+		return client, nil, true
+	}
+
+	oldest := h.nextID + 1
+	if h.size > 0 {
+		oldest = h.history[h.start].ID
+	}
+	resync = lastEventID+1 < oldest
+
+	for i := 0; i < h.size; i++ {
+		event := h.history[(h.start+i)%len(h.history)]
+		if event.ID > lastEventID && event.Audience.Includes(userID, role) {
+			replay = append(replay, event)
+		}
+	}
+	return client, replay, resync
+}
+
+// Unsubscribe removes a client whose connection has closed
+func (h *Hub) Unsubscribe(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(client)
+}
+
+// ClientCount returns the number of open streams
+func (h *Hub) ClientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+func (h *Hub) drop(client *Client) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.Events)
+	}
+}
+
+func (h *Hub) remember(event Event) {
+	if len(h.history) == 0 {
+		return
+	}
+	if h.size < len(h.history) {
+		h.history[(h.start+h.size)%len(h.history)] = event
+		h.size++
+		return
+	}
+	h.history[h.start] = event
+	h.start = (h.start + 1) % len(h.history)
+}
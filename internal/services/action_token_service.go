@@ -0,0 +1,221 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Decisions an action link can make
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+// Channels a leave decision can be made from, recorded in the chronology
+const (
+	ChannelWeb    = "web"
+	ChannelEmail  = "email"
+	ChannelChat   = "chat"
+	ChannelSystem = "system" // Automatic decisions
+)
+
+const actionTokenVersion = "v1"
+
+var (
+	ErrInvalidActionToken = errors.New("invalid action link")
+	ErrActionTokenExpired = errors.New("this action link has expired")
+	ErrActionTokenUsed    = errors.New("this action link has already been used")
+)
+
+// ActionClaims is the signed part of an action link
+type ActionClaims struct {
+	TokenID   uuid.UUID `json:"jti"`
+	RequestID uuid.UUID `json:"rid"`
+	UserID    uuid.UUID `json:"uid"`
+	Action    string    `json:"act"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// ActionLinks are the approve and reject URLs issued together for one message
+type ActionLinks struct {
+	ApproveURL string    `json:"approve_url"`
+	RejectURL  string    `json:"reject_url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ActionTokenService issues and redeems signed, single-use approve/reject links.
+// A link is "v1.<payload>.<signature>" where the payload is base64url JSON claims and the
+// signature is an HMAC-SHA256 over the version and payload.
+type ActionTokenService struct {
+	db      *gorm.DB
+	secret  []byte
+	ttl     time.Duration
+	baseURL string
+}
+
+func NewActionTokenService(db *gorm.DB, secret string, ttl time.Duration, baseURL string) *ActionTokenService {
+	return &ActionTokenService{
+		db:      db,
+		secret:  []byte(secret),
+		ttl:     ttl,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Issue creates approve and reject links for a user to decide on a request. Pass the
+// transaction that sends the message so no token outlives a rolled-back notification.
+func (as *ActionTokenService) Issue(tx *gorm.DB, requestID, userID uuid.UUID, channel string) (*ActionLinks, error) {
+	token, err := as.IssueToken(tx, requestID, userID, channel)
+	if err != nil {
+		return nil, err
+	}
+	return as.Links(token)
+}
+
+// IssueToken creates a token without its links, for messages that build them when sent
+func (as *ActionTokenService) IssueToken(tx *gorm.DB, requestID, userID uuid.UUID, channel string) (*models.ActionToken, error) {
+	if tx == nil {
+		tx = as.db
+	}
+
+	now := time.Now()
+	token := models.ActionToken{
+		ID:             uuid.New(),
+		LeaveRequestID: requestID,
+		UserID:         userID,
+		Channel:        channel,
+		ExpiresAt:      now.Add(as.ttl),
+		CreatedAt:      now,
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Links builds a token's approve and reject URLs. They are derived from the token alone, so
+// they can be rebuilt each time a message is sent rather than stored.
+func (as *ActionTokenService) Links(token *models.ActionToken) (*ActionLinks, error) {
+	links := &ActionLinks{ExpiresAt: token.ExpiresAt}
+	for _, action := range []string{ActionApprove, ActionReject} {
+		signed, err := as.sign(ActionClaims{
+			TokenID:   token.ID,
+			RequestID: token.LeaveRequestID,
+			UserID:    token.UserID,
+			Action:    action,
+			ExpiresAt: token.ExpiresAt.Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		url := fmt.Sprintf("%s/api/v1/actions/%s", as.baseURL, signed)
+		if action == ActionApprove {
+			links.ApproveURL = url
+		} else {
+			links.RejectURL = url
+		}
+	}
+	return links, nil
+}
+
+// Verify checks a link's signature and expiry and that it hasn't been used, without spending it
+func (as *ActionTokenService) Verify(signed string) (*ActionClaims, *models.ActionToken, error) {
+	claims, err := as.parse(signed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var token models.ActionToken
+	if err := as.db.First(&token, "id = ?", claims.TokenID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidActionToken
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if token.LeaveRequestID != claims.RequestID || token.UserID != claims.UserID {
+		return nil, nil, ErrInvalidActionToken
+	}
+	if token.UsedAt != nil {
+		return nil, nil, ErrActionTokenUsed
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, ErrActionTokenExpired
+	}
+	return claims, &token, nil
+}
+
+// Consume marks a verified token used. Only one caller can win for a given token.
+func (as *ActionTokenService) Consume(token *models.ActionToken, action string) error {
+	result := as.db.Model(&models.ActionToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, time.Now()).
+		Updates(map[string]interface{}{"used_at": time.Now(), "used_action": action})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrActionTokenUsed
+	}
+	return nil
+}
+
+// Release makes a consumed token usable again after the decision it was spent on failed
+func (as *ActionTokenService) Release(token *models.ActionToken) error {
+	return as.db.Model(&models.ActionToken{}).
+		Where("id = ?", token.ID).
+		Updates(map[string]interface{}{"used_at": nil, "used_action": ""}).Error
+}
+
+func (as *ActionTokenService) sign(claims ActionClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := actionTokenVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(as.mac(unsigned)), nil
+}
+
+func (as *ActionTokenService) parse(signed string) (*ActionClaims, error) {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 3 || parts[0] != actionTokenVersion {
+		return nil, ErrInvalidActionToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, as.mac(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidActionToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	var claims ActionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	if claims.Action != ActionApprove && claims.Action != ActionReject {
+		return nil, ErrInvalidActionToken
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrActionTokenExpired
+	}
+	return &claims, nil
+}
+
+func (as *ActionTokenService) mac(message string) []byte {
+	mac := hmac.New(sha256.New, as.secret)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
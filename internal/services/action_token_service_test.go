@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"leave-management-system/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestActionToken(expiresAt time.Time) *models.ActionToken {
+	return &models.ActionToken{
+		ID:             uuid.New(),
+		LeaveRequestID: uuid.New(),
+		UserID:         uuid.New(),
+		ExpiresAt:      expiresAt,
+	}
+}
+
+// signedPart returns the token at the end of an action link
+func signedPart(t *testing.T, link string) string {
+	t.Helper()
+	const prefix = "https://lms.example.com/api/v1/actions/"
+	if !strings.HasPrefix(link, prefix) {
+		t.Fatalf("link %q does not start with %q", link, prefix)
+	}
+	return strings.TrimPrefix(link, prefix)
+}
+
+func TestActionLinksRoundTrip(t *testing.T) {
+	as := NewActionTokenService(nil, "action-secret", time.Hour, "https://lms.example.com/")
+	token := newTestActionToken(time.Now().Add(time.Hour))
+
+	links, err := as.Links(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := as.Links(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *links != *again {
+		t.Error("links for the same token differ, so they can't be rebuilt at send time")
+	}
+	if links.ApproveURL == links.RejectURL {
+		t.Error("approve and reject links are the same")
+	}
+
+	tests := []struct {
+		name   string
+		link   string
+		action string
+	}{
+		{"approve", links.ApproveURL, ActionApprove},
+		{"reject", links.RejectURL, ActionReject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := as.parse(signedPart(t, tt.link))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Action != tt.action || claims.TokenID != token.ID ||
+				claims.RequestID != token.LeaveRequestID || claims.UserID != token.UserID {
+				t.Errorf("claims = %+v, want action %s of token %+v", claims, tt.action, token)
+			}
+		})
+	}
+}
+
+func TestActionTokenParseRejects(t *testing.T) {
+	as := NewActionTokenService(nil, "action-secret", time.Hour, "https://lms.example.com")
+	valid, err := as.sign(ActionClaims{
+		TokenID:   uuid.New(),
+		RequestID: uuid.New(),
+		UserID:    uuid.New(),
+		Action:    ActionApprove,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, ".")
+
+	// Re-encode the payload with one change, keeping the original signature
+	tamper := func(change func(claims map[string]interface{})) string {
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims map[string]interface{}
+		json.Unmarshal(payload, &claims)
+		change(claims)
+		payload, _ = json.Marshal(claims)
+		return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	}
+	signWith := func(service *ActionTokenService, action string, expiresAt time.Time) string {
+		signed, err := service.sign(ActionClaims{TokenID: uuid.New(), Action: action, ExpiresAt: expiresAt.Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		signed  string
+		wantErr error
+	}{
+		{"valid", valid, nil},
+		{"action changed", tamper(func(c map[string]interface{}) { c["act"] = ActionReject }), ErrInvalidActionToken},
+		{"expiry extended", tamper(func(c map[string]interface{}) { c["exp"] = time.Now().Add(24 * time.Hour).Unix() }), ErrInvalidActionToken},
+		{"request changed", tamper(func(c map[string]interface{}) { c["rid"] = uuid.NewString() }), ErrInvalidActionToken},
+		{"signature dropped", parts[0] + "." + parts[1] + ".", ErrInvalidActionToken},
+		{"signature not base64", parts[0] + "." + parts[1] + ".!!!", ErrInvalidActionToken},
+		{"other version", "v2." + parts[1] + "." + parts[2], ErrInvalidActionToken},
+		{"extra part", valid + ".extra", ErrInvalidActionToken},
+		{"empty", "", ErrInvalidActionToken},
+		{"other secret", signWith(NewActionTokenService(nil, "other-secret", time.Hour, ""), ActionApprove, time.Now().Add(time.Hour)), ErrInvalidActionToken},
+		{"unknown action", signWith(as, "delete", time.Now().Add(time.Hour)), ErrInvalidActionToken},
+		{"expired", signWith(as, ActionApprove, time.Now().Add(-time.Minute)), ErrActionTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := as.parse(tt.signed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedactActionLinks(t *testing.T) {
+	as := NewActionTokenService(nil, "action-secret", time.Hour, "https://lms.example.com")
+	links, err := as.Links(newTestActionToken(time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"text", "Approve: " + links.ApproveURL + "\nReject: " + links.RejectURL + "\n"},
+		{"html", `<a href="` + links.ApproveURL + `">Approve</a> <a href="` + links.RejectURL + `">Reject</a>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted := redactActionLinks(tt.body)
+			for _, link := range []string{links.ApproveURL, links.RejectURL} {
+				if strings.Contains(redacted, signedPart(t, link)) {
+					t.Errorf("token of %s left in %q", link, redacted)
+				}
+			}
+		})
+	}
+
+	if body := "See https://lms.example.com/leave-requests/1"; redactActionLinks(body) != body {
+		t.Error("redactActionLinks() changed a body without action links")
+	}
+}
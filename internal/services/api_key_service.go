@@ -0,0 +1,322 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid, expired or revoked API key")
+	ErrUnknownAPIKeyScope  = errors.New("unknown API key scope")
+	ErrAPIKeyScopeRequired = errors.New("an API key needs at least one scope")
+	ErrAPIKeyExpiry        = errors.New("API key expiry is in the past or too far away")
+)
+
+// apiKeyPrefix starts every key, so leaked keys are easy to recognise and search for
+const apiKeyPrefix = "lms_"
+
+// APIKeyScope is a permission an API key can be given
+type APIKeyScope struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// APIKeyScopes lists every scope, each guarding a group of integration endpoints
+var APIKeyScopes = []APIKeyScope{
+	{Scope: "reports:read", Description: "Download the payroll report"},
+	{Scope: "leave:read", Description: "List leave requests"},
+	{Scope: "users:read", Description: "List users and their leave balances"},
+	{Scope: "holidays:read", Description: "List public holidays"},
+	{Scope: "holidays:write", Description: "Create, update and delete public holidays"},
+}
+
+// APIKeyResponse includes the key itself, which is only returned when it is created
+type APIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyService manages service accounts and the API keys integrations authenticate with
+type APIKeyService struct {
+	db         *gorm.DB
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+func NewAPIKeyService(db *gorm.DB, defaultTTL, maxTTL time.Duration) *APIKeyService {
+	return &APIKeyService{db: db, defaultTTL: defaultTTL, maxTTL: maxTTL}
+}
+
+func (s *APIKeyService) CreateServiceAccount(name, description string, actorID uuid.UUID, ipAddress, userAgent string) (*models.ServiceAccount, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("service account name is required")
+	}
+
+	account := models.ServiceAccount{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		IsActive:    true,
+		CreatedByID: actorID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		return s.audit(tx, models.AuditServiceAccountCreated, actorID, account.ID, "service_account",
+			models.JSONMap{"name": account.Name}, ipAddress, userAgent)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (s *APIKeyService) GetServiceAccounts() ([]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	err := s.db.Preload("APIKeys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).Order("name ASC").Find(&accounts).Error
+	return accounts, err
+}
+
+func (s *APIKeyService) GetServiceAccount(id uuid.UUID) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := s.db.Preload("APIKeys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).First(&account, "id = ?", id).Error
+	return &account, err
+}
+
+// UpdateServiceAccount changes a service account's name, description or status. A deactivated
+// account's keys stop working until it is activated again.
+func (s *APIKeyService) UpdateServiceAccount(id uuid.UUID, updates map[string]interface{}, actorID uuid.UUID, ipAddress, userAgent string) (*models.ServiceAccount, error) {
+	account, err := s.GetServiceAccount(id)
+	if err != nil {
+		return nil, err
+	}
+	before := models.JSONMap{"name": account.Name, "description": account.Description, "is_active": account.IsActive}
+
+	if v, ok := updates["name"].(string); ok {
+		if v = strings.TrimSpace(v); v == "" {
+			return nil, errors.New("service account name is required")
+		}
+		account.Name = v
+	}
+	if v, ok := updates["description"].(string); ok {
+		account.Description = v
+	}
+	if v, ok := updates["is_active"].(bool); ok {
+		account.IsActive = v
+	}
+	account.UpdatedAt = time.Now()
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("APIKeys").Save(account).Error; err != nil {
+			return err
+		}
+		entry, err := s.auditLog(tx, models.AuditServiceAccountUpdated, actorID, account.ID, "service_account", ipAddress, userAgent)
+		if err != nil {
+			return err
+		}
+		entry.BeforeState = before
+		entry.AfterState = models.JSONMap{"name": account.Name, "description": account.Description, "is_active": account.IsActive}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// CreateKey issues a key for a service account. Without an expiry the default lifetime is used.
+// The key is only returned in this response.
+func (s *APIKeyService) CreateKey(accountID uuid.UUID, name string, scopes []string, expiresAt *time.Time, actorID uuid.UUID, ipAddress, userAgent string) (*APIKeyResponse, error) {
+	if err := validateAPIKeyScopes(scopes); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiry := now.Add(s.defaultTTL)
+	if expiresAt != nil {
+		expiry = *expiresAt
+	}
+	if !expiry.After(now) || expiry.After(now.Add(s.maxTTL)) {
+		return nil, ErrAPIKeyExpiry
+	}
+
+	var account models.ServiceAccount
+	if err := s.db.First(&account, "id = ?", accountID).Error; err != nil {
+		return nil, err
+	}
+
+	prefix, key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := models.APIKey{
+		ID:               uuid.New(),
+		ServiceAccountID: account.ID,
+		Name:             strings.TrimSpace(name),
+		Prefix:           prefix,
+		KeyHash:          hashToken(key),
+		Scopes:           models.StringList(scopes),
+		ExpiresAt:        expiry,
+		CreatedByID:      actorID,
+		CreatedAt:        now,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&apiKey).Error; err != nil {
+			return err
+		}
+		return s.audit(tx, models.AuditAPIKeyCreated, actorID, apiKey.ID, "api_key", models.JSONMap{
+			"service_account": account.Name,
+			"prefix":          apiKey.Prefix,
+			"scopes":          scopes,
+			"expires_at":      expiry,
+		}, ipAddress, userAgent)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &APIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// RevokeKey stops a key working at once
+func (s *APIKeyService) RevokeKey(accountID, keyID, actorID uuid.UUID, ipAddress, userAgent string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var apiKey models.APIKey
+		if err := tx.First(&apiKey, "id = ? AND service_account_id = ?", keyID, accountID).Error; err != nil {
+			return err
+		}
+		if apiKey.RevokedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		if err := tx.Model(&apiKey).Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoked_by_id": actorID,
+		}).Error; err != nil {
+			return err
+		}
+		return s.audit(tx, models.AuditAPIKeyRevoked, actorID, apiKey.ID, "api_key",
+			models.JSONMap{"prefix": apiKey.Prefix}, ipAddress, userAgent)
+	})
+}
+
+// Authenticate returns the key and its service account for a presented key. Unknown, expired
+// and revoked keys, and keys of deactivated accounts, all fail the same way.
+func (s *APIKeyService) Authenticate(key string) (*models.APIKey, *models.ServiceAccount, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var apiKey models.APIKey
+	if err := s.db.First(&apiKey, "key_hash = ?", hashToken(key)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if apiKey.RevokedAt != nil || !time.Now().Before(apiKey.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var account models.ServiceAccount
+	if err := s.db.First(&account, "id = ?", apiKey.ServiceAccountID).Error; err != nil {
+		return nil, nil, err
+	}
+	if !account.IsActive {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	return &apiKey, &account, nil
+}
+
+// RecordUsage notes when a key was last used and audits the request it made
+func (s *APIKeyService) RecordUsage(apiKey *models.APIKey, account *models.ServiceAccount, method, endpoint string, status int, ipAddress, userAgent string) error {
+	now := time.Now()
+	if err := s.db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).UpdateColumns(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ipAddress,
+	}).Error; err != nil {
+		return err
+	}
+
+	return s.db.Create(&models.AuditLog{
+		ID:         uuid.New(),
+		ActorID:    account.ID,
+		ActorEmail: "service-account:" + account.Name,
+		Action:     models.AuditAPIKeyUsed,
+		TargetID:   apiKey.ID,
+		TargetType: "api_key",
+		AfterState: models.JSONMap{"prefix": apiKey.Prefix, "status": status},
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		Method:     method,
+		Endpoint:   endpoint,
+		CreatedAt:  now,
+	}).Error
+}
+
+// audit records a change an administrator made to a service account or key
+func (s *APIKeyService) audit(tx *gorm.DB, action string, actorID, targetID uuid.UUID, targetType string, state models.JSONMap, ipAddress, userAgent string) error {
+	entry, err := s.auditLog(tx, action, actorID, targetID, targetType, ipAddress, userAgent)
+	if err != nil {
+		return err
+	}
+	entry.AfterState = state
+	return tx.Create(entry).Error
+}
+
+func (s *APIKeyService) auditLog(tx *gorm.DB, action string, actorID, targetID uuid.UUID, targetType, ipAddress, userAgent string) (*models.AuditLog, error) {
+	var actor models.User
+	if err := tx.First(&actor, "id = ?", actorID).Error; err != nil {
+		return nil, err
+	}
+	entry := newAuditLog(&actor, action, targetID, ipAddress, userAgent)
+	entry.TargetType = targetType
+	return entry, nil
+}
+
+func validateAPIKeyScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrAPIKeyScopeRequired
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, s := range APIKeyScopes {
+			if s.Scope == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w %q", ErrUnknownAPIKeyScope, scope)
+		}
+	}
+	return nil
+}
+
+// generateAPIKey returns a new key and the prefix that identifies it, lms_<id>_<secret>
+func generateAPIKey() (string, string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix := apiKeyPrefix + hex.EncodeToString(id)
+	return prefix, prefix + "_" + secret, nil
+}
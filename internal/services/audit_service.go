@@ -2,7 +2,9 @@ package services
 
 import (
 	"leave-management-system/internal/models"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -54,3 +56,19 @@ func (s *AuditService) GetAuditLogs(page, limit int, actorID, targetID, startDat
 func (s *AuditService) CreateAuditLog(log *models.AuditLog) error {
 	return s.db.Create(log).Error
 }
+
+// newAuditLog builds the audit entry for a change actor made to the target user
+func newAuditLog(actor *models.User, action string, targetID uuid.UUID, ipAddress, userAgent string) *models.AuditLog {
+	return &models.AuditLog{
+		ID:         uuid.New(),
+		ActorID:    actor.ID,
+		ActorEmail: actor.Email,
+		ActorRole:  actor.Role,
+		Action:     action,
+		TargetID:   targetID,
+		TargetType: "user",
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		CreatedAt:  time.Now(),
+	}
+}
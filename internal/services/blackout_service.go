@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"leave-management-system/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BlackoutService manages periods during which leave cannot be applied for
+type BlackoutService struct {
+	db *gorm.DB
+}
+
+func NewBlackoutService(db *gorm.DB) *BlackoutService {
+	return &BlackoutService{db: db}
+}
+
+func (bs *BlackoutService) CreateBlackoutPeriod(period *models.BlackoutPeriod) error {
+	if period.EndDate.Before(period.StartDate) {
+		return fmt.Errorf("end date must not be before start date")
+	}
+
+	period.ID = uuid.New()
+	period.StartDate = startOfDay(period.StartDate)
+	period.EndDate = startOfDay(period.EndDate)
+	return bs.db.Create(period).Error
+}
+
+func (bs *BlackoutService) GetBlackoutPeriods(year int) ([]models.BlackoutPeriod, error) {
+	var periods []models.BlackoutPeriod
+	query := bs.db.Model(&models.BlackoutPeriod{})
+
+	if year > 0 {
+		yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		yearEnd := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
+		query = query.Where("start_date <= ? AND end_date >= ?", yearEnd, yearStart)
+	}
+
+	err := query.Order("start_date ASC").Find(&periods).Error
+	return periods, err
+}
+
+func (bs *BlackoutService) UpdateBlackoutPeriod(id uuid.UUID, updates map[string]interface{}) error {
+	var period models.BlackoutPeriod
+	if err := bs.db.First(&period, "id = ?", id).Error; err != nil {
+		return err
+	}
+
+	if v, ok := updates["name"].(string); ok {
+		period.Name = v
+	}
+	if v, ok := updates["description"].(string); ok {
+		period.Description = v
+	}
+	if v, ok := updates["start_date"].(string); ok {
+		date, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid start_date: %w", err)
+		}
+		period.StartDate = startOfDay(date)
+	}
+	if v, ok := updates["end_date"].(string); ok {
+		date, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid end_date: %w", err)
+		}
+		period.EndDate = startOfDay(date)
+	}
+	if v, ok := updates["department"].(string); ok {
+		period.Department = v
+	}
+	if v, ok := updates["role"].(string); ok {
+		period.Role = models.UserRole(v)
+	}
+	if v, ok := updates["leave_type"].(string); ok {
+		period.LeaveType = models.LeaveType(v)
+	}
+	if v, ok := updates["exempt_emergency_leave"].(bool); ok {
+		period.ExemptEmergencyLeave = v
+	}
+	if v, ok := updates["is_active"].(bool); ok {
+		period.IsActive = v
+	}
+
+	if period.EndDate.Before(period.StartDate) {
+		return fmt.Errorf("end date must not be before start date")
+	}
+
+	period.UpdatedAt = time.Now()
+	return bs.db.Save(&period).Error
+}
+
+func (bs *BlackoutService) DeleteBlackoutPeriod(id uuid.UUID) error {
+	return bs.db.Delete(&models.BlackoutPeriod{}, "id = ?", id).Error
+}
+
+// GetUpcomingForUser returns active blackout periods that have not yet ended and apply to the user
+func (bs *BlackoutService) GetUpcomingForUser(userID uuid.UUID) ([]models.BlackoutPeriod, error) {
+	var user models.User
+	if err := bs.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	var periods []models.BlackoutPeriod
+	err := bs.scopedToUser(&user).
+		Where("end_date >= ?", startOfDay(time.Now())).
+		Order("start_date ASC").
+		Find(&periods).Error
+
+	return periods, err
+}
+
+// FindConflicts returns active blackout periods that overlap the given leave for the user.
+// Periods flagged ExemptEmergencyLeave are skipped for emergency and sick leave.
+func (bs *BlackoutService) FindConflicts(user *models.User, leaveType models.LeaveType, startDate, endDate time.Time) ([]models.BlackoutPeriod, error) {
+	var periods []models.BlackoutPeriod
+
+	query := bs.scopedToUser(user).
+		Where("start_date <= ? AND end_date >= ?", startOfDay(endDate), startOfDay(startDate)).
+		Where("leave_type = '' OR leave_type IS NULL OR leave_type = ?", leaveType)
+
+	if leaveType == models.LeaveTypeEmergency || leaveType == models.LeaveTypeSick {
+		query = query.Where("exempt_emergency_leave = ?", false)
+	}
+
+	err := query.Order("start_date ASC").Find(&periods).Error
+	return periods, err
+}
+
+func (bs *BlackoutService) scopedToUser(user *models.User) *gorm.DB {
+	return bs.db.Model(&models.BlackoutPeriod{}).
+		Where("is_active = ?", true).
+		Where("department = '' OR department IS NULL OR department = ?", user.Department).
+		Where("role = '' OR role IS NULL OR role = ?", user.Role)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
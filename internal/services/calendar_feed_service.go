@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/ical"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidFeedToken = errors.New("invalid or revoked calendar feed token")
+
+// CalendarFeedService issues tokenized iCalendar subscription feeds
+type CalendarFeedService struct {
+	db      *gorm.DB
+	baseURL string
+}
+
+func NewCalendarFeedService(db *gorm.DB, baseURL string) *CalendarFeedService {
+	return &CalendarFeedService{db: db, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+type CalendarFeedResponse struct {
+	models.CalendarFeedToken
+	URL string `json:"url,omitempty"` // Only returned when the feed is created
+}
+
+// CreateFeed issues a new feed token. The raw token is only available in the returned URL.
+func (fs *CalendarFeedService) CreateFeed(userID uuid.UUID, kind models.CalendarFeedKind) (*CalendarFeedResponse, error) {
+	switch kind {
+	case models.FeedKindMyLeave, models.FeedKindTeamLeave, models.FeedKindHolidays:
+	default:
+		return nil, fmt.Errorf("invalid feed kind '%s'", kind)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := models.CalendarFeedToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Kind:      kind,
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now(),
+	}
+	if err := fs.db.Create(&feed).Error; err != nil {
+		return nil, err
+	}
+
+	return &CalendarFeedResponse{
+		CalendarFeedToken: feed,
+		URL:               fmt.Sprintf("%s/api/v1/calendar/feeds/%s.ics", fs.baseURL, token),
+	}, nil
+}
+
+func (fs *CalendarFeedService) GetFeeds(userID uuid.UUID) ([]models.CalendarFeedToken, error) {
+	var feeds []models.CalendarFeedToken
+	err := fs.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&feeds).Error
+	return feeds, err
+}
+
+func (fs *CalendarFeedService) RevokeFeed(userID, feedID uuid.UUID) error {
+	result := fs.db.Model(&models.CalendarFeedToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", feedID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RenderFeed resolves a raw feed token and renders its iCalendar document
+func (fs *CalendarFeedService) RenderFeed(token string) (string, error) {
+	var feed models.CalendarFeedToken
+	err := fs.db.Where("token_hash = ? AND revoked_at IS NULL", hashToken(token)).First(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrInvalidFeedToken
+	} else if err != nil {
+		return "", err
+	}
+
+	var user models.User
+	if err := fs.db.First(&user, "id = ?", feed.UserID).Error; err != nil {
+		return "", err
+	}
+	if !user.IsActive {
+		return "", ErrInvalidFeedToken
+	}
+
+	var calendar *ical.Calendar
+	switch feed.Kind {
+	case models.FeedKindMyLeave:
+		calendar, err = fs.myLeaveCalendar(&user)
+	case models.FeedKindTeamLeave:
+		calendar, err = fs.teamLeaveCalendar(&user)
+	case models.FeedKindHolidays:
+		calendar, err = fs.holidayCalendar(&user)
+	default:
+		return "", ErrInvalidFeedToken
+	}
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	fs.db.Model(&feed).Update("last_accessed_at", now)
+
+	return calendar.String(), nil
+}
+
+func (fs *CalendarFeedService) myLeaveCalendar(user *models.User) (*ical.Calendar, error) {
+	var requests []models.LeaveRequest
+	err := fs.db.Where("user_id = ? AND status = ? AND end_date >= ?",
+		user.ID, models.StatusApproved, time.Now().AddDate(-1, 0, 0)).
+		Order("start_date ASC").
+		Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &ical.Calendar{Name: "My Leave", Method: ical.MethodPublish}
+	for _, request := range requests {
+		calendar.Events = append(calendar.Events, leaveEvent(&request, leaveSummary(&request, user.Locale)))
+	}
+	return calendar, nil
+}
+
+func (fs *CalendarFeedService) teamLeaveCalendar(user *models.User) (*ical.Calendar, error) {
+	var requests []models.LeaveRequest
+	query := fs.db.Preload("User").
+		Joins("JOIN users ON users.id = leave_requests.user_id").
+		Where("leave_requests.status = ? AND leave_requests.end_date >= ?",
+			models.StatusApproved, time.Now().AddDate(0, -3, 0))
+
+	if err := scopeToTeam(query, user).Order("leave_requests.start_date ASC").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+
+	calendar := &ical.Calendar{Name: "Team Leave", Method: ical.MethodPublish}
+	for _, request := range requests {
+		summary := fmt.Sprintf("%s %s - %s", request.User.FirstName, request.User.LastName, leaveSummary(&request, user.Locale))
+		event := leaveEvent(&request, summary)
+		event.Description = "" // Leave reasons are private to the employee
+		event.Transparent = true
+		calendar.Events = append(calendar.Events, event)
+	}
+	return calendar, nil
+}
+
+func (fs *CalendarFeedService) holidayCalendar(user *models.User) (*ical.Calendar, error) {
+	var holidays []models.PublicHoliday
+	query := fs.db.Where("is_active = ? AND date BETWEEN ? AND ?",
+		true, time.Now().AddDate(-1, 0, 0), time.Now().AddDate(2, 0, 0))
+
+	if user.State != "" {
+		query = query.Where("state = '' OR state IS NULL OR state = ?", user.State)
+	} else {
+		query = query.Where("state = '' OR state IS NULL")
+	}
+
+	if err := query.Order("date ASC").Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+
+	calendar := &ical.Calendar{Name: "Public Holidays", Method: ical.MethodPublish}
+	for _, holiday := range holidays {
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         holiday.ID.String() + "@holiday.leave-management-system",
+			Summary:     holiday.Name,
+			Description: holiday.Description,
+			Start:       holiday.Date,
+			End:         holiday.Date,
+			Transparent: true,
+		})
+	}
+	return calendar, nil
+}
+
+// leaveEventUID is shared by feeds and email invites so clients update the same event
+func leaveEventUID(requestID uuid.UUID) string {
+	return requestID.String() + "@leave-management-system"
+}
+
+func leaveEvent(request *models.LeaveRequest, summary string) ical.Event {
+	return ical.Event{
+		UID:          leaveEventUID(request.ID),
+		Sequence:     leaveEventSequence(request),
+		Summary:      summary,
+		Description:  request.Reason,
+		Start:        request.StartDate,
+		End:          request.EndDate,
+		Cancelled:    request.Status == models.StatusCancelled,
+		LastModified: request.UpdatedAt,
+	}
+}
+
+// leaveEventSequence increases whenever the request is modified so clients replace older copies
+func leaveEventSequence(request *models.LeaveRequest) int {
+	return int(request.UpdatedAt.Unix())
+}
+
+func leaveSummary(request *models.LeaveRequest, locale string) string {
+	summary := localizedLeaveType(request.LeaveType, locale)
+	switch request.HalfDaySession {
+	case models.SessionMorning:
+		summary += " (AM)"
+	case models.SessionAfternoon:
+		summary += " (PM)"
+	}
+	return summary
+}
+
+func leaveTypeLabel(leaveType models.LeaveType) string {
+	label := strings.ReplaceAll(string(leaveType), "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:] + " Leave"
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CalendarScope string
+
+const (
+	CalendarScopeTeam       CalendarScope = "team"
+	CalendarScopeDepartment CalendarScope = "department"
+	CalendarScopeCompany    CalendarScope = "company"
+)
+
+// Maximum number of days a single calendar query may span
+const maxCalendarRangeDays = 93
+
+var ErrCalendarScopeForbidden = errors.New("not allowed to view this calendar scope")
+
+type CalendarAbsence struct {
+	RequestID      uuid.UUID             `json:"request_id"`
+	UserID         uuid.UUID             `json:"user_id"`
+	EmployeeName   string                `json:"employee_name"`
+	Department     string                `json:"department"`
+	LeaveType      models.LeaveType      `json:"leave_type"`
+	Status         models.LeaveStatus    `json:"status"`
+	HalfDaySession models.HalfDaySession `json:"half_day_session,omitempty"`
+}
+
+type CalendarHoliday struct {
+	Name  string `json:"name"`
+	State string `json:"state,omitempty"`
+}
+
+type CalendarDay struct {
+	Date      string            `json:"date"`
+	IsWeekend bool              `json:"is_weekend"`
+	Holidays  []CalendarHoliday `json:"holidays"`
+	Absences  []CalendarAbsence `json:"absences"`
+}
+
+type LeaveCalendar struct {
+	StartDate  string        `json:"start_date"`
+	EndDate    string        `json:"end_date"`
+	Scope      CalendarScope `json:"scope"`
+	Department string        `json:"department,omitempty"`
+	Days       []CalendarDay `json:"days"`
+}
+
+// calendarRow is the flattened leave request used to build the calendar in one query
+type calendarRow struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	LeaveType      models.LeaveType
+	Status         models.LeaveStatus
+	StartDate      time.Time
+	EndDate        time.Time
+	HalfDaySession models.HalfDaySession
+	FirstName      string
+	LastName       string
+	Department     string
+	State          string
+}
+
+type CalendarService struct {
+	db             *gorm.DB
+	holidayService *HolidayService
+	permissions    *PermissionService
+}
+
+func NewCalendarService(db *gorm.DB, holidayService *HolidayService, permissions *PermissionService) *CalendarService {
+	return &CalendarService{db: db, holidayService: holidayService, permissions: permissions}
+}
+
+// GetLeaveCalendar returns per-day absences and holidays for the scope visible to the viewer.
+// Department is only honoured for roles with leave.view_all; other users see their own department.
+func (cs *CalendarService) GetLeaveCalendar(viewerID uuid.UUID, scope CalendarScope, department string,
+	startDate, endDate time.Time) (*LeaveCalendar, error) {
+
+	startDate = calendarDate(startDate)
+	endDate = calendarDate(endDate)
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end date must not be before start date")
+	}
+	if endDate.Sub(startDate).Hours()/24 >= maxCalendarRangeDays {
+		return nil, fmt.Errorf("date range must not exceed %d days", maxCalendarRangeDays)
+	}
+
+	var viewer models.User
+	if err := cs.db.First(&viewer, "id = ?", viewerID).Error; err != nil {
+		return nil, err
+	}
+
+	rangeEnd := endDate.Add(24*time.Hour - time.Nanosecond)
+
+	query := cs.db.Table("leave_requests").
+		Select("leave_requests.id, leave_requests.user_id, leave_requests.leave_type, leave_requests.status, "+
+			"leave_requests.start_date, leave_requests.end_date, leave_requests.half_day_session, "+
+			"users.first_name, users.last_name, users.department, users.state").
+		Joins("JOIN users ON users.id = leave_requests.user_id").
+		Where("leave_requests.status IN ?", []models.LeaveStatus{
+			models.StatusPending, models.StatusEscalated, models.StatusApproved,
+		}).
+		Where("leave_requests.start_date <= ? AND leave_requests.end_date >= ?", rangeEnd, startDate)
+
+	switch scope {
+	case CalendarScopeTeam, "":
+		scope = CalendarScopeTeam
+		query = scopeToTeam(query, &viewer)
+		department = ""
+	case CalendarScopeDepartment:
+		if department == "" || !cs.permissions.Has(viewer.Role, models.PermLeaveViewAll) {
+			department = viewer.Department
+		}
+		query = query.Where("users.department = ?", department)
+	case CalendarScopeCompany:
+		if !cs.permissions.Has(viewer.Role, models.PermLeaveViewAll) {
+			return nil, ErrCalendarScopeForbidden
+		}
+		department = ""
+	default:
+		return nil, fmt.Errorf("invalid scope '%s'", scope)
+	}
+
+	var rows []calendarRow
+	if err := query.Order("users.first_name ASC, users.last_name ASC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	holidays, err := cs.holidayService.GetHolidaysBetween(startDate, rangeEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildLeaveCalendar(scope, department, startDate, endDate, rows, holidays), nil
+}
+
+// scopeToTeam limits a query joined on users to the viewer, their direct reports
+// and colleagues sharing their manager
+func scopeToTeam(query *gorm.DB, viewer *models.User) *gorm.DB {
+	if viewer.ManagerID != nil {
+		return query.Where("users.id = ? OR users.manager_id = ? OR users.manager_id = ?",
+			viewer.ID, viewer.ID, *viewer.ManagerID)
+	}
+	return query.Where("users.id = ? OR users.manager_id = ?", viewer.ID, viewer.ID)
+}
+
+func buildLeaveCalendar(scope CalendarScope, department string, startDate, endDate time.Time,
+	rows []calendarRow, holidays []models.PublicHoliday) *LeaveCalendar {
+
+	dayCount := int(endDate.Sub(startDate).Hours()/24) + 1
+	days := make([]CalendarDay, dayCount)
+	for i := range days {
+		date := startDate.AddDate(0, 0, i)
+		days[i] = CalendarDay{
+			Date:      date.Format("2006-01-02"),
+			IsWeekend: date.Weekday() == time.Saturday || date.Weekday() == time.Sunday,
+			Holidays:  []CalendarHoliday{},
+			Absences:  []CalendarAbsence{},
+		}
+	}
+
+	// A holiday only keeps someone off work if it is nationwide or in their state
+	holidayStates := make(map[int]map[string]bool)
+	for _, holiday := range holidays {
+		idx := int(calendarDate(holiday.Date).Sub(startDate).Hours() / 24)
+		if idx < 0 || idx >= dayCount {
+			continue
+		}
+		days[idx].Holidays = append(days[idx].Holidays, CalendarHoliday{Name: holiday.Name, State: holiday.State})
+		if holidayStates[idx] == nil {
+			holidayStates[idx] = make(map[string]bool)
+		}
+		holidayStates[idx][holiday.State] = true
+	}
+	isHoliday := func(idx int, state string) bool {
+		return holidayStates[idx][""] || (state != "" && holidayStates[idx][state])
+	}
+
+	for _, row := range rows {
+		from := calendarDate(row.StartDate)
+		if from.Before(startDate) {
+			from = startDate
+		}
+		to := calendarDate(row.EndDate)
+		if to.After(endDate) {
+			to = endDate
+		}
+
+		// Maternity and paternity count calendar days; other leave skips weekends and holidays
+		allDays := row.LeaveType == models.LeaveTypeMaternity || row.LeaveType == models.LeaveTypePaternity
+
+		absence := CalendarAbsence{
+			RequestID:      row.ID,
+			UserID:         row.UserID,
+			EmployeeName:   row.FirstName + " " + row.LastName,
+			Department:     row.Department,
+			LeaveType:      row.LeaveType,
+			Status:         row.Status,
+			HalfDaySession: row.HalfDaySession,
+		}
+
+		for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+			idx := int(date.Sub(startDate).Hours() / 24)
+			if !allDays && (days[idx].IsWeekend || isHoliday(idx, row.State)) {
+				continue
+			}
+			days[idx].Absences = append(days[idx].Absences, absence)
+		}
+	}
+
+	return &LeaveCalendar{
+		StartDate:  startDate.Format("2006-01-02"),
+		EndDate:    endDate.Format("2006-01-02"),
+		Scope:      scope,
+		Department: department,
+		Days:       days,
+	}
+}
+
+// calendarDate strips the time component so days can be indexed by offset from the range start
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
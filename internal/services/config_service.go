@@ -2,35 +2,97 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
+	"leave-management-system/internal/models"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// EscalationRecipientMode decides who is emailed when a request is escalated to HR
+type EscalationRecipientMode string
+
+const (
+	EscalateToHRRole            EscalationRecipientMode = "hr_role"            // All active users with the HR role
+	EscalateToMailbox           EscalationRecipientMode = "mailbox"            // The shared HR mailbox
+	EscalateToDepartmentPartner EscalationRecipientMode = "department_partner" // The HR partner for the employee's department
+)
+
+// LapsedRequestAction decides what happens to a request still undecided when its leave has started
+type LapsedRequestAction string
+
+const (
+	LapsedLeavePending LapsedRequestAction = "none"         // Leave it for HR to decide
+	LapsedAutoApprove  LapsedRequestAction = "auto_approve" // Approve it automatically
+	LapsedAutoReject   LapsedRequestAction = "auto_reject"  // Reject it automatically
+)
+
 // SystemConfig represents system-wide configuration stored in database
 type SystemConfig struct {
-	ID                  uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	MaxCarryForwardDays int       `gorm:"default:5" json:"max_carry_forward_days"`
-	WorkingDays         string    `gorm:"type:text" json:"-"` // JSON array stored as string
-	EscalationDays      int       `gorm:"default:7" json:"escalation_days"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	ID                      uuid.UUID               `gorm:"type:uuid;primary_key" json:"id"`
+	MaxCarryForwardDays     int                     `gorm:"default:5" json:"max_carry_forward_days"`
+	WorkingDays             string                  `gorm:"type:text" json:"-"`               // JSON array stored as string
+	EscalationDays          int                     `gorm:"default:7" json:"escalation_days"` // Working days
+	ReminderDays            int                     `gorm:"default:3" json:"reminder_days"`   // Working days
+	ReminderIntervalDays    int                     `gorm:"default:1" json:"reminder_interval_days"`
+	ImminentEscalationDays  int                     `gorm:"default:2" json:"imminent_escalation_days"` // Working days before the start date; 0 disables
+	LapsedRequestAction     LapsedRequestAction     `gorm:"type:varchar(20);default:'none'" json:"lapsed_request_action"`
+	EscalationRecipientMode EscalationRecipientMode `gorm:"type:varchar(20);default:'hr_role'" json:"escalation_recipient_mode"`
+	HRMailbox               string                  `json:"hr_mailbox"`
+	DepartmentHRPartners    string                  `gorm:"type:text" json:"-"` // JSON object of department to email
+	PasswordMinLength       int                     `gorm:"default:10" json:"password_min_length"`
+	PasswordMinClasses      int                     `gorm:"default:3" json:"password_min_classes"`   // Of upper case, lower case, digits and symbols
+	PasswordHistoryCount    int                     `gorm:"default:5" json:"password_history_count"` // Previous passwords that can't be reused; 0 disables
+	PasswordMaxAgeDays      int                     `gorm:"default:0" json:"password_max_age_days"`  // Days before a password must be changed; 0 never expires
+	TwoFactorRequiredRoles  string                  `gorm:"type:text" json:"-"`                      // JSON array of roles that must use two-factor sign-in
+	CreatedAt               time.Time               `json:"created_at"`
+	UpdatedAt               time.Time               `json:"updated_at"`
 }
 
 // SystemConfigResponse is the API response format
 type SystemConfigResponse struct {
-	MaxCarryForwardDays int      `json:"max_carry_forward_days"`
-	WorkingDays         []string `json:"working_days"`
-	EscalationDays      int      `json:"escalation_days"`
+	MaxCarryForwardDays     int                     `json:"max_carry_forward_days"`
+	WorkingDays             []string                `json:"working_days"`
+	EscalationDays          int                     `json:"escalation_days"`
+	ReminderDays            int                     `json:"reminder_days"`
+	ReminderIntervalDays    int                     `json:"reminder_interval_days"`
+	ImminentEscalationDays  int                     `json:"imminent_escalation_days"`
+	LapsedRequestAction     LapsedRequestAction     `json:"lapsed_request_action"`
+	EscalationRecipientMode EscalationRecipientMode `json:"escalation_recipient_mode"`
+	HRMailbox               string                  `json:"hr_mailbox"`
+	DepartmentHRPartners    map[string]string       `json:"department_hr_partners"`
+	PasswordPolicy          PasswordPolicy          `json:"password_policy"`
+	TwoFactorRequiredRoles  []models.UserRole       `json:"two_factor_required_roles"`
 }
 
+// SystemConfigRequest replaces the core settings. Optional fields left nil keep their current value.
 type SystemConfigRequest struct {
-	MaxCarryForwardDays int      `json:"max_carry_forward_days"`
-	WorkingDays         []string `json:"working_days"`
-	EscalationDays      int      `json:"escalation_days"`
+	MaxCarryForwardDays     int                      `json:"max_carry_forward_days"`
+	WorkingDays             []string                 `json:"working_days"`
+	EscalationDays          int                      `json:"escalation_days"`
+	ReminderDays            *int                     `json:"reminder_days"`
+	ReminderIntervalDays    *int                     `json:"reminder_interval_days"`
+	ImminentEscalationDays  *int                     `json:"imminent_escalation_days"`
+	LapsedRequestAction     *LapsedRequestAction     `json:"lapsed_request_action"`
+	EscalationRecipientMode *EscalationRecipientMode `json:"escalation_recipient_mode"`
+	HRMailbox               *string                  `json:"hr_mailbox"`
+	DepartmentHRPartners    map[string]string        `json:"department_hr_partners"`
+	PasswordMinLength       *int                     `json:"password_min_length"`
+	PasswordMinClasses      *int                     `json:"password_min_classes"`
+	PasswordHistoryCount    *int                     `json:"password_history_count"`
+	PasswordMaxAgeDays      *int                     `json:"password_max_age_days"`
+	TwoFactorRequiredRoles  []models.UserRole        `json:"two_factor_required_roles"`
 }
 
+const (
+	defaultEscalationDays       = 7
+	defaultReminderDays         = 3
+	defaultReminderIntervalDays = 1
+	defaultImminentDays         = 2
+)
+
 type ConfigService struct {
 	db *gorm.DB
 }
@@ -47,9 +109,17 @@ func (s *ConfigService) GetSystemConfig() (*SystemConfigResponse, error) {
 		if err == gorm.ErrRecordNotFound {
 			// Return default config if none exists
 			return &SystemConfigResponse{
-				MaxCarryForwardDays: 5,
-				WorkingDays:         []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
-				EscalationDays:      7,
+				MaxCarryForwardDays:     5,
+				WorkingDays:             []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+				EscalationDays:          defaultEscalationDays,
+				ReminderDays:            defaultReminderDays,
+				ReminderIntervalDays:    defaultReminderIntervalDays,
+				ImminentEscalationDays:  defaultImminentDays,
+				LapsedRequestAction:     LapsedLeavePending,
+				EscalationRecipientMode: EscalateToHRRole,
+				DepartmentHRPartners:    map[string]string{},
+				PasswordPolicy:          DefaultPasswordPolicy,
+				TwoFactorRequiredRoles:  []models.UserRole{},
 			}, nil
 		}
 		return nil, err
@@ -65,14 +135,66 @@ func (s *ConfigService) GetSystemConfig() (*SystemConfigResponse, error) {
 		workingDays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
 	}
 
+	partners := map[string]string{}
+	if config.DepartmentHRPartners != "" {
+		if err := json.Unmarshal([]byte(config.DepartmentHRPartners), &partners); err != nil {
+			partners = map[string]string{}
+		}
+	}
+
+	twoFactorRoles := []models.UserRole{}
+	if config.TwoFactorRequiredRoles != "" {
+		if err := json.Unmarshal([]byte(config.TwoFactorRequiredRoles), &twoFactorRoles); err != nil {
+			twoFactorRoles = []models.UserRole{}
+		}
+	}
+
+	// Rows created before reminders were configurable have zero values
+	reminderDays := config.ReminderDays
+	if reminderDays <= 0 {
+		reminderDays = defaultReminderDays
+	}
+	reminderInterval := config.ReminderIntervalDays
+	if reminderInterval <= 0 {
+		reminderInterval = defaultReminderIntervalDays
+	}
+
+	lapsedAction := config.LapsedRequestAction
+	if lapsedAction == "" {
+		lapsedAction = LapsedLeavePending
+	}
+
+	mode := config.EscalationRecipientMode
+	if mode == "" {
+		mode = EscalateToHRRole
+	}
+
 	return &SystemConfigResponse{
-		MaxCarryForwardDays: config.MaxCarryForwardDays,
-		WorkingDays:         workingDays,
-		EscalationDays:      config.EscalationDays,
+		MaxCarryForwardDays:     config.MaxCarryForwardDays,
+		WorkingDays:             workingDays,
+		EscalationDays:          config.EscalationDays,
+		ReminderDays:            reminderDays,
+		ReminderIntervalDays:    reminderInterval,
+		ImminentEscalationDays:  config.ImminentEscalationDays,
+		LapsedRequestAction:     lapsedAction,
+		EscalationRecipientMode: mode,
+		HRMailbox:               config.HRMailbox,
+		DepartmentHRPartners:    partners,
+		PasswordPolicy: PasswordPolicy{
+			MinLength:    config.PasswordMinLength,
+			MinClasses:   config.PasswordMinClasses,
+			HistoryCount: config.PasswordHistoryCount,
+			MaxAgeDays:   config.PasswordMaxAgeDays,
+		}.withDefaults(),
+		TwoFactorRequiredRoles: twoFactorRoles,
 	}, nil
 }
 
 func (s *ConfigService) UpdateSystemConfig(req SystemConfigRequest) error {
+	if err := validateEscalationSettings(req); err != nil {
+		return err
+	}
+
 	// Serialize working days to JSON
 	workingDaysJSON, err := json.Marshal(req.WorkingDays)
 	if err != nil {
@@ -85,27 +207,159 @@ func (s *ConfigService) UpdateSystemConfig(req SystemConfigRequest) error {
 	if err == gorm.ErrRecordNotFound {
 		// Create new config
 		config = SystemConfig{
-			ID:                  uuid.New(),
-			MaxCarryForwardDays: req.MaxCarryForwardDays,
-			WorkingDays:         string(workingDaysJSON),
-			EscalationDays:      req.EscalationDays,
-			CreatedAt:           time.Now(),
-			UpdatedAt:           time.Now(),
+			ID:                      uuid.New(),
+			ReminderDays:            defaultReminderDays,
+			ReminderIntervalDays:    defaultReminderIntervalDays,
+			ImminentEscalationDays:  defaultImminentDays,
+			LapsedRequestAction:     LapsedLeavePending,
+			EscalationRecipientMode: EscalateToHRRole,
+			PasswordMinLength:       DefaultPasswordPolicy.MinLength,
+			PasswordMinClasses:      DefaultPasswordPolicy.MinClasses,
+			PasswordHistoryCount:    DefaultPasswordPolicy.HistoryCount,
+			PasswordMaxAgeDays:      DefaultPasswordPolicy.MaxAgeDays,
+			CreatedAt:               time.Now(),
 		}
-		return s.db.Create(&config).Error
 	} else if err != nil {
 		return err
 	}
 
-	// Update existing config
 	config.MaxCarryForwardDays = req.MaxCarryForwardDays
 	config.WorkingDays = string(workingDaysJSON)
 	config.EscalationDays = req.EscalationDays
+	if req.ReminderDays != nil {
+		config.ReminderDays = *req.ReminderDays
+	}
+	if req.ReminderIntervalDays != nil {
+		config.ReminderIntervalDays = *req.ReminderIntervalDays
+	}
+	if req.ImminentEscalationDays != nil {
+		config.ImminentEscalationDays = *req.ImminentEscalationDays
+	}
+	if req.LapsedRequestAction != nil {
+		config.LapsedRequestAction = *req.LapsedRequestAction
+	}
+	if req.EscalationRecipientMode != nil {
+		config.EscalationRecipientMode = *req.EscalationRecipientMode
+	}
+	if req.HRMailbox != nil {
+		config.HRMailbox = strings.TrimSpace(*req.HRMailbox)
+	}
+	if req.DepartmentHRPartners != nil {
+		partnersJSON, err := json.Marshal(req.DepartmentHRPartners)
+		if err != nil {
+			return err
+		}
+		config.DepartmentHRPartners = string(partnersJSON)
+	}
+	if req.PasswordMinLength != nil {
+		config.PasswordMinLength = *req.PasswordMinLength
+	}
+	if req.PasswordMinClasses != nil {
+		config.PasswordMinClasses = *req.PasswordMinClasses
+	}
+	if req.PasswordHistoryCount != nil {
+		config.PasswordHistoryCount = *req.PasswordHistoryCount
+	}
+	if req.PasswordMaxAgeDays != nil {
+		config.PasswordMaxAgeDays = *req.PasswordMaxAgeDays
+	}
+	if req.TwoFactorRequiredRoles != nil {
+		rolesJSON, err := json.Marshal(req.TwoFactorRequiredRoles)
+		if err != nil {
+			return err
+		}
+		config.TwoFactorRequiredRoles = string(rolesJSON)
+	}
 	config.UpdatedAt = time.Now()
 
 	return s.db.Save(&config).Error
 }
 
+func validateEscalationSettings(req SystemConfigRequest) error {
+	if req.EscalationDays < 1 {
+		return fmt.Errorf("escalation_days must be at least 1")
+	}
+	if req.ReminderDays != nil && *req.ReminderDays < 1 {
+		return fmt.Errorf("reminder_days must be at least 1")
+	}
+	if req.ReminderIntervalDays != nil && *req.ReminderIntervalDays < 1 {
+		return fmt.Errorf("reminder_interval_days must be at least 1")
+	}
+	if req.ImminentEscalationDays != nil && *req.ImminentEscalationDays < 0 {
+		return fmt.Errorf("imminent_escalation_days cannot be negative")
+	}
+	if req.LapsedRequestAction != nil {
+		switch *req.LapsedRequestAction {
+		case LapsedLeavePending, LapsedAutoApprove, LapsedAutoReject:
+		default:
+			return fmt.Errorf("invalid lapsed request action '%s'", *req.LapsedRequestAction)
+		}
+	}
+	if req.EscalationRecipientMode != nil {
+		switch *req.EscalationRecipientMode {
+		case EscalateToHRRole, EscalateToMailbox, EscalateToDepartmentPartner:
+		default:
+			return fmt.Errorf("invalid escalation recipient mode '%s'", *req.EscalationRecipientMode)
+		}
+	}
+	if req.HRMailbox != nil && *req.HRMailbox != "" && !strings.Contains(*req.HRMailbox, "@") {
+		return fmt.Errorf("invalid HR mailbox '%s'", *req.HRMailbox)
+	}
+	for department, email := range req.DepartmentHRPartners {
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("invalid HR partner email for department '%s'", department)
+		}
+	}
+	for _, role := range req.TwoFactorRequiredRoles {
+		switch role {
+		case models.RoleSysAdmin, models.RoleAdmin, models.RoleHR, models.RoleManager, models.RoleHOD, models.RoleStaff:
+		default:
+			return fmt.Errorf("invalid role '%s' in two_factor_required_roles", role)
+		}
+	}
+	return validatePasswordPolicySettings(req)
+}
+
+func validatePasswordPolicySettings(req SystemConfigRequest) error {
+	if req.PasswordMinLength != nil && (*req.PasswordMinLength < 8 || *req.PasswordMinLength > 72) {
+		// bcrypt ignores everything past 72 bytes
+		return fmt.Errorf("password_min_length must be between 8 and 72")
+	}
+	if req.PasswordMinClasses != nil && (*req.PasswordMinClasses < 1 || *req.PasswordMinClasses > 4) {
+		return fmt.Errorf("password_min_classes must be between 1 and 4")
+	}
+	if req.PasswordHistoryCount != nil && (*req.PasswordHistoryCount < 0 || *req.PasswordHistoryCount > 24) {
+		return fmt.Errorf("password_history_count must be between 0 and 24")
+	}
+	if req.PasswordMaxAgeDays != nil && *req.PasswordMaxAgeDays < 0 {
+		return fmt.Errorf("password_max_age_days cannot be negative")
+	}
+	return nil
+}
+
+// GetPasswordPolicy returns the password rules every new password is checked against
+func (s *ConfigService) GetPasswordPolicy() PasswordPolicy {
+	config, err := s.GetSystemConfig()
+	if err != nil {
+		return DefaultPasswordPolicy
+	}
+	return config.PasswordPolicy
+}
+
+// RequiresTwoFactor reports whether users with the role must sign in with a second factor
+func (s *ConfigService) RequiresTwoFactor(role models.UserRole) bool {
+	config, err := s.GetSystemConfig()
+	if err != nil {
+		return false
+	}
+	for _, r := range config.TwoFactorRequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *ConfigService) GetMaxCarryForwardDays() int {
 	config, err := s.GetSystemConfig()
 	if err != nil {
@@ -116,8 +370,8 @@ func (s *ConfigService) GetMaxCarryForwardDays() int {
 
 func (s *ConfigService) GetEscalationDays() int {
 	config, err := s.GetSystemConfig()
-	if err != nil {
-		return 7 // Default
+	if err != nil || config.EscalationDays <= 0 {
+		return defaultEscalationDays
 	}
 	return config.EscalationDays
 }
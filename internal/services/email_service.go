@@ -2,167 +2,154 @@ package services
 
 import (
 	"fmt"
+	"io"
 	"leave-management-system/internal/models"
-	"strings"
+	"leave-management-system/pkg/ical"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
 
 type EmailService struct {
-	dialer *gomail.Dialer
-	from   string
+	dialer    *gomail.Dialer
+	from      string
+	templates *TemplateService
 }
 
-func NewEmailService(host string, port int, username, password, from string) *EmailService {
+func NewEmailService(host string, port int, username, password, from string, templates *TemplateService) *EmailService {
 	dialer := gomail.NewDialer(host, port, username, password)
 	return &EmailService{
-		dialer: dialer,
-		from:   from,
+		dialer:    dialer,
+		from:      from,
+		templates: templates,
 	}
 }
 
-func (es *EmailService) SendLeaveRequestNotification(request *models.LeaveRequest, manager *models.User) error {
-	subject := fmt.Sprintf("New Leave Request from %s %s",
-		request.User.FirstName, request.User.LastName)
-
-	body := fmt.Sprintf(`
-	Dear %s %s,
-	
-	A new leave request has been submitted for your approval:
-	
-	Employee: %s %s
-	Leave Type: %s
-	Dates: %s to %s
-	Duration: %.1f days
-	Reason: %s
-	
-	Please log in to the Leave Management System to review and take action.
-	
-	Regards,
-	Leave Management System
-	`,
-		manager.FirstName, manager.LastName,
-		request.User.FirstName, request.User.LastName,
-		strings.Title(string(request.LeaveType)),
-		request.StartDate.Format("January 2, 2006"),
-		request.EndDate.Format("January 2, 2006"),
-		request.DurationDays,
-		request.Reason)
-
-	return es.sendEmail(manager.Email, subject, body)
+// ComposeLeaveEmail renders a leave notification template for one recipient. When invite is set
+// an iCalendar invite for the leave is attached; anyone other than the employee gets a
+// non-blocking FYI copy. actions adds one-click approve/reject links for the recipient.
+// The request's User, User.Manager and Approver should be preloaded.
+func (es *EmailService) ComposeLeaveEmail(name string, recipient *models.User, request *models.LeaveRequest,
+	comment string, invite ical.Method, actions *ActionLinks) (*models.NotificationOutbox, error) {
+
+	data := es.templateData(request, recipient)
+	data.Comment = comment
+	data.Actions = actions
+
+	var calendar *ical.Calendar
+	if invite != "" {
+		calendar = es.leaveInvite(request, invite, recipient, recipient.ID != request.UserID)
+	}
+	return es.compose(recipient, name, data, calendar)
 }
 
-func (es *EmailService) SendApprovalNotification(request *models.LeaveRequest) error {
-	subject := "Your Leave Request has been Approved"
-
-	body := fmt.Sprintf(`
-	Dear %s %s,
-	
-	Your leave request has been approved:
-	
-	Leave Type: %s
-	Dates: %s to %s
-	Duration: %.1f days
-	Approved by: %s %s
-	Approval Date: %s
-	
-	Regards,
-	Leave Management System
-	`,
-		request.User.FirstName, request.User.LastName,
-		strings.Title(string(request.LeaveType)),
-		request.StartDate.Format("January 2, 2006"),
-		request.EndDate.Format("January 2, 2006"),
-		request.DurationDays,
-		request.Approver.FirstName, request.Approver.LastName,
-		request.ApprovedAt.Format("January 2, 2006"))
-
-	return es.sendEmail(request.User.Email, subject, body)
+// ComposeCarryForwardExpiry warns the owner of a balance that unused carried-forward days expire soon
+func (es *EmailService) ComposeCarryForwardExpiry(recipient *models.User, balance *models.LeaveBalance,
+	days float64, expiresOn time.Time) (*models.NotificationOutbox, error) {
+
+	data := &EmailTemplateData{
+		Recipient:    NewTemplateUser(recipient),
+		Employee:     NewTemplateUser(recipient),
+		Balance:      balance,
+		ExpiringDays: days,
+		ExpiresOn:    expiresOn,
+		AppURL:       es.templates.AppURL(),
+	}
+	return es.compose(recipient, "carry_forward_expiring", data, nil)
 }
 
-func (es *EmailService) SendEscalationNotification(request *models.LeaveRequest) error {
-	subject := "Leave Request Escalated - Action Required"
-
-	body := fmt.Sprintf(`
-	Attention HR Team,
-	
-	A leave request has been escalated due to no response from the manager:
-	
-	Employee: %s %s
-	Manager: %s %s
-	Leave Type: %s
-	Dates: %s to %s
-	Duration: %.1f days
-	Submitted: %s
-	
-	Please log in to the Leave Management System to review and take action.
-	
-	Regards,
-	Leave Management System
-	`,
-		request.User.FirstName, request.User.LastName,
-		request.User.Manager.FirstName, request.User.Manager.LastName,
-		strings.Title(string(request.LeaveType)),
-		request.StartDate.Format("January 2, 2006"),
-		request.EndDate.Format("January 2, 2006"),
-		request.DurationDays,
-		request.CreatedAt.Format("January 2, 2006"))
-
-	// Send to HR team (would need to fetch HR emails)
-	// For now, using a placeholder
-	return es.sendEmail("hr@company.com", subject, body)
+// ComposePasswordReset sends a user a one-time link to choose a new password
+func (es *EmailService) ComposePasswordReset(recipient *models.User, resetURL string, validFor time.Duration,
+	byHR bool) (*models.NotificationOutbox, error) {
+
+	data := &EmailTemplateData{
+		Recipient:         NewTemplateUser(recipient),
+		Employee:          NewTemplateUser(recipient),
+		AppURL:            es.templates.AppURL(),
+		ResetURL:          resetURL,
+		ResetValidMinutes: int(validFor.Minutes()),
+		ResetByHR:         byHR,
+	}
+	return es.compose(recipient, "password_reset", data, nil)
 }
 
-func (es *EmailService) SendReminderNotification(request *models.LeaveRequest) error {
-	subject := fmt.Sprintf("Reminder: Pending Leave Request for %s %s",
-		request.User.FirstName, request.User.LastName)
-
-	body := fmt.Sprintf(`
-	Dear Manager,
-	
-	This is a reminder that you have a pending leave request waiting for your approval:
-	
-	Employee: %s %s
-	Leave Type: %s
-	Dates: %s to %s
-	Duration: %.1f days
-	
-	Please log in to the Leave Management System to take action.
-	
-	Regards,
-	Leave Management System
-	`,
-		request.User.FirstName, request.User.LastName,
-		request.LeaveType, // strings.Title might be better but just using raw for now
-		request.StartDate.Format("January 2, 2006"),
-		request.EndDate.Format("January 2, 2006"),
-		request.DurationDays)
-
-	// Assuming manager email is available in request or needs fetching.
-	// The request struct in cron_jobs might not have Manager loaded unless Preload was used.
-	// In cron_jobs.go checkEscalatedRequests usage, Preload is used in GetPendingRequestsOlderThan?
-	// I added Preload in leave_service_methods.go: GetPendingRequestsOlderThan DOES Preload("User").Preload("User.Manager").
-	// But SendReminderNotification uses request.User.Manager.Email?
-	// Note: in previous cron_jobs Code, usage was just passing request.
-
-	// If User.Manager is nil, we should handle it.
-	targetEmail := "manager@company.com"
-	if request.User.Manager != nil {
-		targetEmail = request.User.Manager.Email
-	} else if request.User.ManagerID != nil {
-		// ManagerID exists but struct not loaded? Protocol was Preload("User.Manager").
-		// If Preload worked, Manager should be non-nil.
+func (es *EmailService) leaveInvite(request *models.LeaveRequest, method ical.Method,
+	attendee *models.User, fyi bool) *ical.Calendar {
+
+	summary := leaveSummary(request, attendee.Locale)
+	if fyi {
+		summary = fmt.Sprintf("%s %s - %s", request.User.FirstName, request.User.LastName, summary)
+	}
+
+	event := leaveEvent(request, summary)
+	event.Transparent = fyi
+	if fyi {
+		event.Description = ""
 	}
+	event.Organizer = &ical.Attendee{Name: "Leave Management System", Email: es.from}
+	event.Attendees = []ical.Attendee{{
+		Name:  attendee.FirstName + " " + attendee.LastName,
+		Email: attendee.Email,
+	}}
 
-	return es.sendEmail(targetEmail, subject, body)
+	return &ical.Calendar{Method: method, Events: []ical.Event{event}}
 }
 
-func (es *EmailService) sendEmail(to, subject, body string) error {
+// templateData collects the common template fields for a leave request
+func (es *EmailService) templateData(request *models.LeaveRequest, recipient *models.User) *EmailTemplateData {
+	data := &EmailTemplateData{
+		Recipient: NewTemplateUser(recipient),
+		AppURL:    es.templates.AppURL(),
+	}
+	data.SetLeaveRequest(request)
+	return data
+}
+
+// compose renders a template in the recipient's locale into an unsaved outbox message
+func (es *EmailService) compose(recipient *models.User, name string, data *EmailTemplateData,
+	invite *ical.Calendar) (*models.NotificationOutbox, error) {
+
+	rendered, err := es.templates.Render(name, recipient.Locale, data)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &models.NotificationOutbox{
+		Recipient: recipient.Email,
+		Subject:   rendered.Subject,
+		TextBody:  rendered.Text,
+		HTMLBody:  rendered.HTML,
+	}
+	if invite != nil {
+		message.CalendarMethod = string(invite.Method)
+		message.CalendarBody = invite.String()
+	}
+	return message, nil
+}
+
+// Deliver sends an outbox message over SMTP
+func (es *EmailService) Deliver(message *models.NotificationOutbox) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", es.from)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/plain", body)
+	m.SetHeader("To", message.Recipient)
+	m.SetHeader("Subject", message.Subject)
+	m.SetBody("text/plain", message.TextBody)
+	m.AddAlternative("text/html", message.HTMLBody)
+
+	if message.CalendarBody != "" {
+		ics := message.CalendarBody
+		method := message.CalendarMethod
+		// Inline part lets mail clients render the invite; the attachment is for clients that don't
+		m.AddAlternative(fmt.Sprintf("text/calendar; method=%s", method), ics)
+		m.Attach("invite.ics",
+			gomail.SetHeader(map[string][]string{
+				"Content-Type": {fmt.Sprintf("application/ics; name=invite.ics; method=%s", method)},
+			}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := io.WriteString(w, ics)
+				return err
+			}))
+	}
 
 	return es.dialer.DialAndSend(m)
 }
@@ -44,6 +44,18 @@ func (hs *HolidayService) GetHolidays(year int) ([]models.PublicHoliday, error)
 	return holidays, err
 }
 
+// GetHolidaysBetween returns active holidays falling within the inclusive date range
+func (hs *HolidayService) GetHolidaysBetween(startDate, endDate time.Time) ([]models.PublicHoliday, error) {
+	var holidays []models.PublicHoliday
+
+	err := hs.db.Where("date BETWEEN ? AND ? AND is_active = ?",
+		startDate, endDate, true).
+		Order("date ASC").
+		Find(&holidays).Error
+
+	return holidays, err
+}
+
 func (hs *HolidayService) AddHoliday(holiday *models.PublicHoliday) error {
 	holiday.ID = uuid.New()
 	return hs.db.Create(holiday).Error
@@ -3,18 +3,22 @@ package services
 import (
 	"fmt"
 	"leave-management-system/internal/models"
+	"strings"
 	"time"
 )
 
 type LeaveCalculator struct {
 	holidayService     *HolidayService
 	leaveTypeConfigSvc *LeaveTypeConfigService
+	blackoutService    *BlackoutService
 }
 
-func NewLeaveCalculator(holidayService *HolidayService, leaveTypeConfigSvc *LeaveTypeConfigService) *LeaveCalculator {
+func NewLeaveCalculator(holidayService *HolidayService, leaveTypeConfigSvc *LeaveTypeConfigService,
+	blackoutService *BlackoutService) *LeaveCalculator {
 	return &LeaveCalculator{
 		holidayService:     holidayService,
 		leaveTypeConfigSvc: leaveTypeConfigSvc,
+		blackoutService:    blackoutService,
 	}
 }
 
@@ -199,6 +203,42 @@ func (lc *LeaveCalculator) CalculateWorkingDays(startDate, endDate time.Time, le
 	return totalDays, nil
 }
 
+// CountWorkingDays counts the days from start to end inclusive that fall on one of the given
+// weekdays and are not public holidays
+func (lc *LeaveCalculator) CountWorkingDays(startDate, endDate time.Time, workingDays []string) (int, error) {
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, endDate.Location())
+	if endDate.Before(startDate) {
+		return 0, nil
+	}
+
+	weekdays := make(map[time.Weekday]bool, len(workingDays))
+	for _, name := range workingDays {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(d.String(), name) {
+				weekdays[d] = true
+			}
+		}
+	}
+
+	holidays, err := lc.holidayService.GetHolidaysBetween(startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+	isHoliday := make(map[string]bool, len(holidays))
+	for _, holiday := range holidays {
+		isHoliday[holiday.Date.Format("2006-01-02")] = true
+	}
+
+	count := 0
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		if weekdays[date.Weekday()] && !isHoliday[date.Format("2006-01-02")] {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // Check if leave request is valid
 func (lc *LeaveCalculator) ValidateLeaveRequest(user *models.User, request *models.LeaveRequest) error {
 	// Check probation status
@@ -211,6 +251,19 @@ func (lc *LeaveCalculator) ValidateLeaveRequest(user *models.User, request *mode
 		return fmt.Errorf("start date must be before end date")
 	}
 
+	// Half-day leave must be a single working day
+	if request.HalfDaySession != "" {
+		if request.HalfDaySession != models.SessionMorning && request.HalfDaySession != models.SessionAfternoon {
+			return fmt.Errorf("half-day session must be 'am' or 'pm'")
+		}
+		if !startOfDay(request.StartDate).Equal(startOfDay(request.EndDate)) {
+			return fmt.Errorf("half-day leave must start and end on the same day")
+		}
+		if request.LeaveType == models.LeaveTypeMaternity || request.LeaveType == models.LeaveTypePaternity {
+			return fmt.Errorf("half-day is not available for %s leave", request.LeaveType)
+		}
+	}
+
 	// Get leave type config
 	config, err := lc.leaveTypeConfigSvc.GetConfig(request.LeaveType)
 	if err != nil {
@@ -237,5 +290,17 @@ func (lc *LeaveCalculator) ValidateLeaveRequest(user *models.User, request *mode
 		return fmt.Errorf("cannot apply for leave in the past")
 	}
 
+	// Check blackout periods for the user's department, role and leave type
+	blackouts, err := lc.blackoutService.FindConflicts(user, request.LeaveType, request.StartDate, request.EndDate)
+	if err != nil {
+		return err
+	}
+	if len(blackouts) > 0 {
+		return fmt.Errorf("leave is not allowed during blackout period '%s' (%s to %s)",
+			blackouts[0].Name,
+			blackouts[0].StartDate.Format("2006-01-02"),
+			blackouts[0].EndDate.Format("2006-01-02"))
+	}
+
 	return nil
 }
@@ -12,27 +12,42 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrNotAuthorizedToDecide means the approver may not approve or reject the request
+var ErrNotAuthorizedToDecide = errors.New("not authorized to decide this request")
+
 type LeaveService struct {
 	db                 *gorm.DB
 	calculator         *LeaveCalculator
 	auditLogger        *logger.AuditLogger
 	holidayService     *HolidayService
 	leaveTypeConfigSvc *LeaveTypeConfigService
+	configService      *ConfigService
+	notifier           *NotificationService
+	webhooks           *WebhookService
+	permissions        *PermissionService
 }
 
 func NewLeaveService(db *gorm.DB, calculator *LeaveCalculator,
-	auditLogger *logger.AuditLogger, holidayService *HolidayService, leaveTypeConfigSvc *LeaveTypeConfigService) *LeaveService {
+	auditLogger *logger.AuditLogger, holidayService *HolidayService, leaveTypeConfigSvc *LeaveTypeConfigService,
+	configService *ConfigService, notifier *NotificationService, webhooks *WebhookService,
+	permissions *PermissionService) *LeaveService {
 	return &LeaveService{
 		db:                 db,
 		calculator:         calculator,
 		auditLogger:        auditLogger,
 		holidayService:     holidayService,
 		leaveTypeConfigSvc: leaveTypeConfigSvc,
+		configService:      configService,
+		notifier:           notifier,
+		webhooks:           webhooks,
+		permissions:        permissions,
 	}
 }
 
 func (ls *LeaveService) CreateLeaveRequest(userID uuid.UUID, request *models.LeaveRequest) error {
-	return ls.db.Transaction(func(tx *gorm.DB) error {
+	var event LeaveEvent
+	var notified []uuid.UUID
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
 		// Get user with manager
 		var user models.User
 		if err := tx.Preload("Manager").First(&user, "id = ?", userID).Error; err != nil {
@@ -44,32 +59,10 @@ func (ls *LeaveService) CreateLeaveRequest(userID uuid.UUID, request *models.Lea
 			return err
 		}
 
-		// Calculate working days
-		workingDays, err := ls.calculator.CalculateWorkingDays(
-			request.StartDate, request.EndDate, request.LeaveType)
-		if err != nil {
+		// Calculate working days and check the balance
+		if err := ls.applyDuration(userID, request); err != nil {
 			return err
 		}
-		request.DurationDays = workingDays
-
-		// Check balance for leave types that deduct from balance
-		if request.LeaveType == models.LeaveTypeAnnual ||
-			request.LeaveType == models.LeaveTypeEmergency ||
-			request.LeaveType == models.LeaveTypeSick {
-
-			balance, err := ls.GetLeaveBalance(userID, int(time.Now().Year()), request.LeaveType)
-			if err != nil {
-				return err
-			}
-
-			available := balance.TotalEntitlement + balance.CarriedForward +
-				balance.Adjusted - balance.Used
-
-			if available < request.DurationDays {
-				return fmt.Errorf("insufficient balance. Available: %.1f, Requested: %.1f",
-					available, request.DurationDays)
-			}
-		}
 
 		// Set request details
 		request.ID = uuid.New()
@@ -82,6 +75,7 @@ func (ls *LeaveService) CreateLeaveRequest(userID uuid.UUID, request *models.Lea
 			// If no manager, escalate to HR
 			request.Status = models.StatusEscalated
 			request.IsEscalated = true
+			request.EscalationLevel = models.EscalationHR
 			now := time.Now()
 			request.EscalatedAt = &now
 		}
@@ -91,7 +85,7 @@ func (ls *LeaveService) CreateLeaveRequest(userID uuid.UUID, request *models.Lea
 			ID:             uuid.New(),
 			LeaveRequestID: request.ID,
 			Action:         "submitted",
-			ActorID:        userID,
+			ActorID:        &userID,
 			Comment:        "Leave application submitted",
 			Metadata: models.JSONMap{
 				"leave_type": request.LeaveType,
@@ -111,12 +105,143 @@ func (ls *LeaveService) CreateLeaveRequest(userID uuid.UUID, request *models.Lea
 			return err
 		}
 
-		return nil
+		event = LeaveEvent{
+			Type:      models.EventLeaveSubmitted,
+			RequestID: request.ID,
+			ActorID:   userID,
+		}
+		return ls.recordEvent(tx, event, &notified)
 	})
+	if err != nil {
+		return err
+	}
+
+	ls.notifier.PublishLeaveEvent(event, notified...)
+	return nil
 }
 
-func (ls *LeaveService) ApproveLeave(requestID, approverID uuid.UUID, comment string) error {
-	return ls.db.Transaction(func(tx *gorm.DB) error {
+// applyDuration sets the request's working-day duration and checks the user has enough balance
+func (ls *LeaveService) applyDuration(userID uuid.UUID, request *models.LeaveRequest) error {
+	workingDays, err := ls.calculator.CalculateWorkingDays(
+		request.StartDate, request.EndDate, request.LeaveType)
+	if err != nil {
+		return err
+	}
+	request.DurationDays = workingDays
+	if request.HalfDaySession != "" && workingDays > 0 {
+		request.DurationDays = 0.5
+	}
+
+	// Check balance for leave types that deduct from balance
+	if request.LeaveType == models.LeaveTypeAnnual ||
+		request.LeaveType == models.LeaveTypeEmergency ||
+		request.LeaveType == models.LeaveTypeSick {
+
+		balance, err := ls.GetLeaveBalance(userID, int(time.Now().Year()), request.LeaveType)
+		if err != nil {
+			return err
+		}
+
+		available := balance.TotalEntitlement + balance.CarriedForward +
+			balance.Adjusted - balance.Used
+
+		if available < request.DurationDays {
+			return fmt.Errorf("insufficient balance. Available: %.1f, Requested: %.1f",
+				available, request.DurationDays)
+		}
+	}
+
+	return nil
+}
+
+// AmendLeaveRequest lets the employee change a request that is still awaiting a decision.
+// The approver is notified with the optional comment.
+func (ls *LeaveService) AmendLeaveRequest(requestID, userID uuid.UUID, changes *models.LeaveRequest, comment string) (*models.LeaveRequest, error) {
+	var request models.LeaveRequest
+	var notified []uuid.UUID
+
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&request, "id = ? AND user_id = ?", requestID, userID).Error; err != nil {
+			return err
+		}
+
+		if request.Status != models.StatusPending && request.Status != models.StatusEscalated {
+			return errors.New("only pending requests can be amended")
+		}
+
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		previous := models.JSONMap{
+			"leave_type":       request.LeaveType,
+			"start_date":       request.StartDate.Format(time.RFC3339),
+			"end_date":         request.EndDate.Format(time.RFC3339),
+			"half_day_session": request.HalfDaySession,
+			"duration":         request.DurationDays,
+		}
+
+		request.LeaveType = changes.LeaveType
+		request.StartDate = changes.StartDate
+		request.EndDate = changes.EndDate
+		request.HalfDaySession = changes.HalfDaySession
+		request.Reason = changes.Reason
+		request.AttachmentURL = changes.AttachmentURL
+		request.UnrecordedLeaveSubtype = changes.UnrecordedLeaveSubtype
+
+		if err := ls.calculator.ValidateLeaveRequest(&user, &request); err != nil {
+			return err
+		}
+		if err := ls.applyDuration(userID, &request); err != nil {
+			return err
+		}
+		request.UpdatedAt = time.Now()
+
+		chronology := models.Chronology{
+			ID:             uuid.New(),
+			LeaveRequestID: request.ID,
+			Action:         "amended",
+			ActorID:        &userID,
+			Comment:        comment,
+			Metadata: models.JSONMap{
+				"previous":   previous,
+				"leave_type": request.LeaveType,
+				"start_date": request.StartDate.Format(time.RFC3339),
+				"end_date":   request.EndDate.Format(time.RFC3339),
+				"duration":   request.DurationDays,
+			},
+			CreatedAt: time.Now(),
+		}
+
+		if err := tx.Save(&request).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&chronology).Error; err != nil {
+			return err
+		}
+
+		return ls.recordEvent(tx, LeaveEvent{
+			Type:      models.EventLeaveAmended,
+			RequestID: request.ID,
+			ActorID:   userID,
+			Comment:   comment,
+		}, &notified)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ls.notifier.PublishLeaveEvent(LeaveEvent{Type: models.EventLeaveAmended, RequestID: request.ID, ActorID: userID}, notified...)
+	return &request, nil
+}
+
+// ApproveLeave approves a pending or escalated request. channel records where the decision was made.
+func (ls *LeaveService) ApproveLeave(requestID, approverID uuid.UUID, comment, channel string) error {
+	var event LeaveEvent
+	var notified []uuid.UUID
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
 		var request models.LeaveRequest
 		if err := tx.Preload("User").First(&request, "id = ?", requestID).Error; err != nil {
 			return err
@@ -126,10 +251,11 @@ func (ls *LeaveService) ApproveLeave(requestID, approverID uuid.UUID, comment st
 			return errors.New("leave request is not pending")
 		}
 
-		// Check if approver is the manager
-		if request.ApproverID != nil && *request.ApproverID != approverID {
-			// Allow HR to approve escalated requests?
-			// For now, strict check, but assuming logic allows manager
+		// System decisions on lapsed requests skip the check
+		if channel != ChannelSystem {
+			if err := ls.authorizeDecision(tx, &request, approverID); err != nil {
+				return err
+			}
 		}
 
 		// Update request
@@ -182,8 +308,9 @@ func (ls *LeaveService) ApproveLeave(requestID, approverID uuid.UUID, comment st
 			ID:             uuid.New(),
 			LeaveRequestID: request.ID,
 			Action:         "approved",
-			ActorID:        approverID,
+			ActorID:        decisionActor(approverID, channel),
 			Comment:        comment,
+			Metadata:       models.JSONMap{"channel": channel},
 			CreatedAt:      time.Now(),
 		}
 
@@ -195,8 +322,20 @@ func (ls *LeaveService) ApproveLeave(requestID, approverID uuid.UUID, comment st
 			return err
 		}
 
-		return nil
+		event = LeaveEvent{
+			Type:      models.EventLeaveApproved,
+			RequestID: request.ID,
+			ActorID:   approverID,
+			Comment:   comment,
+		}
+		return ls.recordEvent(tx, event, &notified)
 	})
+	if err != nil {
+		return err
+	}
+
+	ls.notifier.PublishLeaveEvent(event, notified...)
+	return nil
 }
 
 func (ls *LeaveService) GetLeaveBalance(userID uuid.UUID, year int, leaveType models.LeaveType) (*models.LeaveBalance, error) {
@@ -307,6 +446,48 @@ func (ls *LeaveService) ProcessYearEndCarryForward() error {
 	})
 }
 
+// How long before carried-forward leave expires owners are warned
+const carryForwardExpiryNoticeDays = 30
+
+// ProcessCarryForwardExpiry warns users whose carried-forward days are still unused near the
+// end of the year. Year-end carry forward only carries this year's entitlement, so days
+// carried into this year that aren't taken by then are lost. Leave taken is counted against
+// carried-forward days first.
+func (ls *LeaveService) ProcessCarryForwardExpiry() error {
+	now := time.Now()
+	year := now.Year()
+
+	expiresOn := time.Date(year+1, time.January, 1, 0, 0, 0, 0, now.Location())
+	if expiresOn.Sub(now) > carryForwardExpiryNoticeDays*24*time.Hour {
+		return nil
+	}
+
+	var balances []models.LeaveBalance
+	err := ls.db.Where("year = ? AND carried_forward > used AND carry_forward_notified_at IS NULL", year).
+		Find(&balances).Error
+	if err != nil {
+		return err
+	}
+
+	// Display the last day the leave can still be used
+	lastDay := expiresOn.AddDate(0, 0, -1)
+	for i := range balances {
+		balance := &balances[i]
+		err := ls.db.Transaction(func(tx *gorm.DB) error {
+			if err := ls.notifier.NotifyCarryForwardExpiring(tx, balance, balance.CarriedForward-balance.Used, lastDay); err != nil {
+				return err
+			}
+			return tx.Model(balance).Update("carry_forward_notified_at", now).Error
+		})
+		if err != nil {
+			return err
+		}
+		ls.notifier.PublishUnreadCount(balance.UserID)
+	}
+
+	return nil
+}
+
 // === New Methods Added by User ===
 
 func (ls *LeaveService) GetUserLeaveRequests(userID uuid.UUID, status, year, leaveType string) ([]models.LeaveRequest, error) {
@@ -341,7 +522,9 @@ func (ls *LeaveService) GetLeaveRequest(requestID uuid.UUID) (*models.LeaveReque
 }
 
 func (ls *LeaveService) CancelLeaveRequest(requestID, userID uuid.UUID) error {
-	return ls.db.Transaction(func(tx *gorm.DB) error {
+	var event LeaveEvent
+	var notified []uuid.UUID
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
 		var request models.LeaveRequest
 		if err := tx.First(&request, "id = ? AND user_id = ?", requestID, userID).Error; err != nil {
 			return err
@@ -352,6 +535,7 @@ func (ls *LeaveService) CancelLeaveRequest(requestID, userID uuid.UUID) error {
 			return errors.New("only pending requests can be cancelled")
 		}
 
+		previousStatus := request.Status
 		request.Status = models.StatusCancelled
 		request.UpdatedAt = time.Now()
 
@@ -360,7 +544,7 @@ func (ls *LeaveService) CancelLeaveRequest(requestID, userID uuid.UUID) error {
 			ID:             uuid.New(),
 			LeaveRequestID: request.ID,
 			Action:         "cancelled",
-			ActorID:        userID,
+			ActorID:        &userID,
 			Comment:        "Request cancelled by user",
 			CreatedAt:      time.Now(),
 		}
@@ -369,12 +553,31 @@ func (ls *LeaveService) CancelLeaveRequest(requestID, userID uuid.UUID) error {
 			return err
 		}
 
-		return tx.Create(&chronology).Error
+		if err := tx.Create(&chronology).Error; err != nil {
+			return err
+		}
+
+		event = LeaveEvent{
+			Type:           models.EventLeaveCancelled,
+			RequestID:      request.ID,
+			ActorID:        userID,
+			PreviousStatus: previousStatus,
+		}
+		return ls.recordEvent(tx, event, &notified)
 	})
+	if err != nil {
+		return err
+	}
+
+	ls.notifier.PublishLeaveEvent(event, notified...)
+	return nil
 }
 
-func (ls *LeaveService) RejectLeave(requestID, approverID uuid.UUID, comment string) error {
-	return ls.db.Transaction(func(tx *gorm.DB) error {
+// RejectLeave rejects a pending or escalated request. channel records where the decision was made.
+func (ls *LeaveService) RejectLeave(requestID, approverID uuid.UUID, comment, channel string) error {
+	var event LeaveEvent
+	var notified []uuid.UUID
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
 		var request models.LeaveRequest
 		if err := tx.Preload("User").First(&request, "id = ?", requestID).Error; err != nil {
 			return err
@@ -385,11 +588,11 @@ func (ls *LeaveService) RejectLeave(requestID, approverID uuid.UUID, comment str
 			return errors.New("leave request is not pending")
 		}
 
-		// Authorization check:
-		// - If ApproverID is set, only that approver can reject
-		// - If ApproverID is nil (escalated), any HR/Admin can reject (handled at route level)
-		if request.ApproverID != nil && *request.ApproverID != approverID {
-			return errors.New("not authorized to reject this request")
+		// System decisions on lapsed requests skip the check
+		if channel != ChannelSystem {
+			if err := ls.authorizeDecision(tx, &request, approverID); err != nil {
+				return err
+			}
 		}
 
 		// Update request
@@ -404,8 +607,9 @@ func (ls *LeaveService) RejectLeave(requestID, approverID uuid.UUID, comment str
 			ID:             uuid.New(),
 			LeaveRequestID: request.ID,
 			Action:         "rejected",
-			ActorID:        approverID,
+			ActorID:        decisionActor(approverID, channel),
 			Comment:        comment,
+			Metadata:       models.JSONMap{"channel": channel},
 			CreatedAt:      time.Now(),
 		}
 
@@ -414,8 +618,65 @@ func (ls *LeaveService) RejectLeave(requestID, approverID uuid.UUID, comment str
 			return err
 		}
 
-		return tx.Create(&chronology).Error
+		if err := tx.Create(&chronology).Error; err != nil {
+			return err
+		}
+
+		event = LeaveEvent{
+			Type:      models.EventLeaveRejected,
+			RequestID: request.ID,
+			ActorID:   approverID,
+			Comment:   comment,
+		}
+		return ls.recordEvent(tx, event, &notified)
 	})
+	if err != nil {
+		return err
+	}
+
+	ls.notifier.PublishLeaveEvent(event, notified...)
+	return nil
+}
+
+// ApplyAction makes the decision an action link was issued for, acting as the link's approver.
+// ApproveLeave and RejectLeave check the approver is still allowed to decide the request.
+func (ls *LeaveService) ApplyAction(token *models.ActionToken, action, comment string) error {
+	switch action {
+	case ActionApprove:
+		return ls.ApproveLeave(token.LeaveRequestID, token.UserID, comment, token.Channel)
+	case ActionReject:
+		return ls.RejectLeave(token.LeaveRequestID, token.UserID, comment, token.Channel)
+	default:
+		return fmt.Errorf("unknown action '%s'", action)
+	}
+}
+
+// decisionActor is who a decision is recorded against: nobody for automatic decisions
+func decisionActor(approverID uuid.UUID, channel string) *uuid.UUID {
+	if channel == ChannelSystem {
+		return nil
+	}
+	return &approverID
+}
+
+// authorizeDecision checks the approver is active and responsible for the request: its
+// assigned approver, or a role with leave.approve.escalated once it has been escalated
+func (ls *LeaveService) authorizeDecision(tx *gorm.DB, request *models.LeaveRequest, approverID uuid.UUID) error {
+	var approver models.User
+	if err := tx.First(&approver, "id = ?", approverID).Error; err != nil {
+		return err
+	}
+	if !approver.IsActive {
+		return errors.New("approver account is inactive")
+	}
+
+	if request.ApproverID != nil && *request.ApproverID == approver.ID {
+		return nil
+	}
+	if request.Status == models.StatusEscalated && ls.permissions.Has(approver.Role, models.PermLeaveApproveEscalated) {
+		return nil
+	}
+	return ErrNotAuthorizedToDecide
 }
 
 func (ls *LeaveService) GetTeamLeaveRequests(managerID uuid.UUID, status, year string) ([]models.LeaveRequest, error) {
@@ -424,7 +685,7 @@ func (ls *LeaveService) GetTeamLeaveRequests(managerID uuid.UUID, status, year s
 	query := ls.db.Preload("User").
 		Preload("Approver").
 		Joins("JOIN users ON users.id = leave_requests.user_id").
-		Where("users.manager_id = ?", managerID)
+		Where("users.manager_id = ? OR leave_requests.approver_id = ?", managerID, managerID)
 
 	if status != "" {
 		query = query.Where("leave_requests.status = ?", status)
@@ -585,28 +846,308 @@ func (ls *LeaveService) GeneratePayrollReport(month, year string) ([]byte, error
 	return []byte(csv), nil
 }
 
-func (ls *LeaveService) GetPendingRequestsOlderThan(date time.Time) ([]models.LeaveRequest, error) {
+// pendingSchedule holds how many working days a request may stay pending, per leave type,
+// before its approver is reminded and before it is escalated to HR
+type pendingSchedule struct {
+	workingDays       []string
+	reminderInterval  int
+	imminentDays      int
+	lapsedAction      LapsedRequestAction
+	defaultReminder   int
+	defaultEscalation int
+	reminder          map[models.LeaveType]int
+	escalation        map[models.LeaveType]int
+}
+
+func (s *pendingSchedule) reminderDays(leaveType models.LeaveType) int {
+	if days, ok := s.reminder[leaveType]; ok {
+		return days
+	}
+	return s.defaultReminder
+}
+
+func (s *pendingSchedule) escalationDays(leaveType models.LeaveType) int {
+	if days, ok := s.escalation[leaveType]; ok {
+		return days
+	}
+	return s.defaultEscalation
+}
+
+// loadPendingSchedule reads the thresholds from the system and leave type configuration
+func (ls *LeaveService) loadPendingSchedule() (*pendingSchedule, error) {
+	config, err := ls.configService.GetSystemConfig()
+	if err != nil {
+		return nil, err
+	}
+	typeConfigs, err := ls.leaveTypeConfigSvc.GetAllConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &pendingSchedule{
+		workingDays:       config.WorkingDays,
+		reminderInterval:  config.ReminderIntervalDays,
+		imminentDays:      config.ImminentEscalationDays,
+		lapsedAction:      config.LapsedRequestAction,
+		defaultReminder:   config.ReminderDays,
+		defaultEscalation: config.EscalationDays,
+		reminder:          map[models.LeaveType]int{},
+		escalation:        map[models.LeaveType]int{},
+	}
+	if schedule.defaultEscalation <= 0 {
+		schedule.defaultEscalation = defaultEscalationDays
+	}
+	for _, typeConfig := range typeConfigs {
+		if typeConfig.ReminderDays != nil {
+			schedule.reminder[typeConfig.LeaveType] = *typeConfig.ReminderDays
+		}
+		if typeConfig.EscalationDays != nil {
+			schedule.escalation[typeConfig.LeaveType] = *typeConfig.EscalationDays
+		}
+	}
+	return schedule, nil
+}
+
+// workingDaysSince counts the whole working days that have passed since a moment: the days
+// after it up to and including yesterday
+func (ls *LeaveService) workingDaysSince(since, now time.Time, workingDays []string) (int, error) {
+	return ls.calculator.CountWorkingDays(since.AddDate(0, 0, 1), now.AddDate(0, 0, -1), workingDays)
+}
+
+// GetRequestsDueForEscalation returns requests that have waited their escalation threshold with
+// their current approver
+func (ls *LeaveService) GetRequestsDueForEscalation(now time.Time) ([]models.LeaveRequest, error) {
+	schedule, err := ls.loadPendingSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := ls.escalatableRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	var due []models.LeaveRequest
+	for _, request := range pending {
+		since := request.CreatedAt
+		if request.EscalatedAt != nil {
+			since = *request.EscalatedAt
+		}
+
+		waited, err := ls.workingDaysSince(since, now, schedule.workingDays)
+		if err != nil {
+			return nil, err
+		}
+		if waited >= schedule.escalationDays(request.LeaveType) {
+			due = append(due, request)
+		}
+	}
+	return due, nil
+}
+
+// escalatableRequests returns undecided requests that can still move up the ladder: pending
+// requests, and escalated requests not yet with HR. Requests escalated before the ladder
+// existed have level 0 and are already with HR.
+func (ls *LeaveService) escalatableRequests() ([]models.LeaveRequest, error) {
 	var requests []models.LeaveRequest
+	err := ls.db.Where("status = ? OR (status = ? AND escalation_level IN ?)",
+		models.StatusPending, models.StatusEscalated,
+		[]models.EscalationLevel{models.EscalationSeniorManager, models.EscalationHOD}).
+		Find(&requests).Error
+	return requests, err
+}
 
-	err := ls.db.Preload("User").
-		Preload("User.Manager").
-		Where("status = ? AND created_at < ?", models.StatusPending, date).
+// GetImminentRequests returns undecided requests, not yet with HR, whose leave starts within the
+// configured number of working days
+func (ls *LeaveService) GetImminentRequests(now time.Time) ([]models.LeaveRequest, error) {
+	schedule, err := ls.loadPendingSchedule()
+	if err != nil || schedule.imminentDays <= 0 {
+		return nil, err
+	}
+
+	requests, err := ls.escalatableRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var due []models.LeaveRequest
+	for _, request := range requests {
+		if request.StartDate.Before(today) {
+			continue // Lapsed, handled by ProcessLapsedRequests
+		}
+
+		// Working days left to decide, from today up to the day before the leave starts
+		remaining, err := ls.calculator.CountWorkingDays(today, request.StartDate.AddDate(0, 0, -1), schedule.workingDays)
+		if err != nil {
+			return nil, err
+		}
+		if remaining <= schedule.imminentDays {
+			due = append(due, request)
+		}
+	}
+	return due, nil
+}
+
+// ProcessLapsedRequests applies the lapsed request policy to requests still undecided after their
+// leave has started. It returns how many requests were approved or rejected.
+func (ls *LeaveService) ProcessLapsedRequests(now time.Time) (int, error) {
+	schedule, err := ls.loadPendingSchedule()
+	if err != nil {
+		return 0, err
+	}
+	if schedule.lapsedAction != LapsedAutoApprove && schedule.lapsedAction != LapsedAutoReject {
+		return 0, nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var requests []models.LeaveRequest
+	err = ls.db.Where("status IN ? AND start_date < ?",
+		[]models.LeaveStatus{models.StatusPending, models.StatusEscalated}, today).
 		Find(&requests).Error
+	if err != nil {
+		return 0, err
+	}
 
-	return requests, err
+	// Automatic decisions have no approver, so they aren't recorded against anyone
+	processed := 0
+	var errs []error
+	for _, request := range requests {
+		if schedule.lapsedAction == LapsedAutoApprove {
+			err = ls.ApproveLeave(request.ID, uuid.Nil,
+				"Automatically approved because the leave started without a decision", ChannelSystem)
+		} else {
+			err = ls.RejectLeave(request.ID, uuid.Nil,
+				"Automatically rejected because the leave started without a decision", ChannelSystem)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("request %s: %w", request.ID, err))
+			continue
+		}
+		processed++
+	}
+	return processed, errors.Join(errs...)
+}
+
+// GetRequestsDueForReminder returns pending requests past their reminder threshold whose
+// approver hasn't already been reminded within the reminder interval
+func (ls *LeaveService) GetRequestsDueForReminder(now time.Time) ([]models.LeaveRequest, error) {
+	schedule, err := ls.loadPendingSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []models.LeaveRequest
+	if err := ls.db.Where("status = ?", models.StatusPending).Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	var due []models.LeaveRequest
+	for _, request := range pending {
+		waited, err := ls.workingDaysSince(request.CreatedAt, now, schedule.workingDays)
+		if err != nil {
+			return nil, err
+		}
+		if waited < schedule.reminderDays(request.LeaveType) {
+			continue
+		}
+
+		if request.LastReminderAt != nil {
+			// Working days since the day of the last reminder, counting today
+			sinceReminder, err := ls.calculator.CountWorkingDays(request.LastReminderAt.AddDate(0, 0, 1), now, schedule.workingDays)
+			if err != nil {
+				return nil, err
+			}
+			if sinceReminder < schedule.reminderInterval {
+				continue
+			}
+		}
+		due = append(due, request)
+	}
+	return due, nil
 }
 
+// EscalateRequest moves a request one step up the escalation ladder: to the manager's manager,
+// then the head of department, then HR. Steps without an active candidate are skipped.
 func (ls *LeaveService) EscalateRequest(requestID uuid.UUID) error {
-	return ls.db.Transaction(func(tx *gorm.DB) error {
+	return ls.escalate(requestID, false)
+}
+
+// EscalateImminentRequest sends a request whose leave is about to start straight to HR,
+// as there is no time left to climb the ladder
+func (ls *LeaveService) EscalateImminentRequest(requestID uuid.UUID) error {
+	return ls.escalate(requestID, true)
+}
+
+func (ls *LeaveService) escalate(requestID uuid.UUID, imminent bool) error {
+	var event LeaveEvent
+	var notified []uuid.UUID
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
 		var request models.LeaveRequest
-		if err := tx.Preload("User").First(&request, "id = ?", requestID).Error; err != nil {
+		if err := tx.Preload("User").Preload("User.Manager").First(&request, "id = ?", requestID).Error; err != nil {
 			return err
 		}
 
+		if request.Status != models.StatusPending && request.Status != models.StatusEscalated {
+			return errors.New("leave request is not pending")
+		}
+		if request.Status == models.StatusEscalated &&
+			(request.EscalationLevel == models.EscalationNone || request.EscalationLevel >= models.EscalationHR) {
+			return errors.New("leave request is already escalated to HR")
+		}
+
+		schedule, err := ls.loadPendingSchedule()
+		if err != nil {
+			return err
+		}
+		escalationDays := schedule.escalationDays(request.LeaveType)
+
+		level, approver := models.EscalationHR, (*models.User)(nil)
+		if !imminent {
+			level, approver, err = ls.nextEscalationStep(tx, &request)
+			if err != nil {
+				return err
+			}
+		}
+
+		metadata := models.JSONMap{
+			"reason":          fmt.Sprintf("No decision within %d working days", escalationDays),
+			"escalation_days": escalationDays,
+			"from_level":      request.EscalationLevel,
+			"to_level":        level,
+		}
+		if imminent {
+			metadata["reason"] = fmt.Sprintf("Leave starts within %d working days without a decision", schedule.imminentDays)
+			metadata["imminent_escalation_days"] = schedule.imminentDays
+			metadata["start_date"] = request.StartDate.Format("2006-01-02")
+		}
+		if request.ApproverID != nil {
+			metadata["from_approver_id"] = request.ApproverID.String()
+		}
+
+		comment := "Request escalated to HR due to no response from the approver"
+		if imminent {
+			comment = "Request escalated to HR because the leave is about to start without a decision"
+		}
+		if approver != nil {
+			metadata["to_approver_id"] = approver.ID.String()
+			role := "the manager's manager"
+			if level == models.EscalationHOD {
+				role = "the head of department"
+			}
+			comment = fmt.Sprintf("Request escalated to %s, %s %s, due to no response from the approver",
+				role, approver.FirstName, approver.LastName)
+		}
+
 		now := time.Now()
 		request.Status = models.StatusEscalated
 		request.IsEscalated = true
 		request.EscalatedAt = &now
+		request.EscalationLevel = level
+		request.ApproverID = nil
+		if approver != nil {
+			request.ApproverID = &approver.ID
+		}
 		request.UpdatedAt = now
 
 		// Create chronology entry
@@ -614,20 +1155,104 @@ func (ls *LeaveService) EscalateRequest(requestID uuid.UUID) error {
 			ID:             uuid.New(),
 			LeaveRequestID: request.ID,
 			Action:         "escalated",
-			ActorID:        request.UserID, // System action
-			Comment:        "Request escalated due to no response from manager",
-			Metadata: models.JSONMap{
-				"reason": "7-day escalation rule",
-			},
-			CreatedAt: time.Now(),
+			ActorID:        &request.UserID, // System action
+			Comment:        comment,
+			Metadata:       metadata,
+			CreatedAt:      time.Now(),
 		}
 
 		if err := tx.Save(&request).Error; err != nil {
 			return err
 		}
 
-		return tx.Create(&chronology).Error
+		if err := tx.Create(&chronology).Error; err != nil {
+			return err
+		}
+
+		event = LeaveEvent{
+			Type:      models.EventLeaveEscalated,
+			RequestID: request.ID,
+			ActorID:   request.UserID,
+		}
+		return ls.recordEvent(tx, event, &notified)
 	})
+	if err != nil {
+		return err
+	}
+
+	ls.notifier.PublishLeaveEvent(event, notified...)
+	return nil
+}
+
+// nextEscalationStep finds the next rung of the ladder above the request's current level that
+// has an active approver other than the employee and the current approver. HR is the last rung
+// and has no individual approver.
+func (ls *LeaveService) nextEscalationStep(tx *gorm.DB, request *models.LeaveRequest) (models.EscalationLevel, *models.User, error) {
+	for level := request.EscalationLevel + 1; level < models.EscalationHR; level++ {
+		var candidate models.User
+		var err error
+		switch level {
+		case models.EscalationSeniorManager:
+			if request.User.Manager == nil || request.User.Manager.ManagerID == nil {
+				continue
+			}
+			err = tx.First(&candidate, "id = ? AND is_active = ?", *request.User.Manager.ManagerID, true).Error
+		case models.EscalationHOD:
+			if request.User.Department == "" {
+				continue
+			}
+			err = tx.Where("role = ? AND department = ? AND is_active = ? AND id <> ?",
+				models.RoleHOD, request.User.Department, true, request.UserID).
+				Order("created_at ASC").
+				First(&candidate).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		} else if err != nil {
+			return 0, nil, err
+		}
+
+		if candidate.ID == request.UserID || (request.ApproverID != nil && candidate.ID == *request.ApproverID) {
+			continue
+		}
+		return level, &candidate, nil
+	}
+	return models.EscalationHR, nil, nil
+}
+
+// SendReminder queues a reminder to the approver of a pending request
+func (ls *LeaveService) SendReminder(requestID uuid.UUID) error {
+	event := LeaveEvent{
+		Type:      models.EventLeaveReminder,
+		RequestID: requestID,
+	}
+	var notified []uuid.UUID
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.LeaveRequest{}).
+			Where("id = ?", requestID).
+			Update("last_reminder_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return ls.recordEvent(tx, event, &notified)
+	})
+	if err != nil {
+		return err
+	}
+
+	ls.notifier.PublishLeaveEvent(event, notified...)
+	return nil
+}
+
+// recordEvent queues notifications and webhook deliveries for a leave event in the caller's
+// transaction. The users given an in-app notification are stored in notified, for PublishLeaveEvent.
+func (ls *LeaveService) recordEvent(tx *gorm.DB, event LeaveEvent, notified *[]uuid.UUID) error {
+	recipients, err := ls.notifier.EnqueueLeaveEvent(tx, event)
+	if err != nil {
+		return err
+	}
+	*notified = recipients
+	return ls.webhooks.EnqueueLeaveEvent(tx, event)
 }
 
 func (ls *LeaveService) ArchiveOldRecords(beforeDate time.Time) error {
@@ -78,6 +78,20 @@ func (s *LeaveTypeConfigService) UpdateConfig(leaveType models.LeaveType, update
 			config.IsActive = b
 		}
 	}
+	if v, ok := updates["reminder_days"]; ok {
+		days, err := optionalDays("reminder_days", v)
+		if err != nil {
+			return err
+		}
+		config.ReminderDays = days
+	}
+	if v, ok := updates["escalation_days"]; ok {
+		days, err := optionalDays("escalation_days", v)
+		if err != nil {
+			return err
+		}
+		config.EscalationDays = days
+	}
 
 	// Handle JSONB field
 	if v, ok := updates["years_of_service_tiers"]; ok {
@@ -95,6 +109,19 @@ func (s *LeaveTypeConfigService) UpdateConfig(leaveType models.LeaveType, update
 	return s.db.Save(&config).Error
 }
 
+// optionalDays reads a per-type day override from a JSON update; null clears it
+func optionalDays(field string, v interface{}) (*int, error) {
+	if v == nil {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 1 {
+		return nil, fmt.Errorf("%s must be a positive number of days or null", field)
+	}
+	days := int(f)
+	return &days, nil
+}
+
 // SeedDefaultConfigs creates default configurations if none exist
 func (s *LeaveTypeConfigService) SeedDefaultConfigs() error {
 	// Migration: Rename 'special' to 'unrecorded'
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"leave-management-system/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginThrottledError is returned for a sign-in attempt made while the account is locked, or
+// before the delay that follows a failed attempt has passed
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked after too many failed sign-in attempts"
+	}
+	return "too many failed sign-in attempts, please wait before trying again"
+}
+
+// LoginAttempt describes a sign-in attempt for the audit log
+type LoginAttempt struct {
+	Email     string
+	IPAddress string
+	UserAgent string
+	Endpoint  string
+}
+
+// maxFailureDelay caps the wait between attempts before the account locks
+const maxFailureDelay = 30 * time.Second
+
+// failureDelay is how long to wait after the nth failed attempt in a row. The first mistake
+// costs nothing; each one after doubles the wait.
+func failureDelay(failures int) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+	delay := time.Second << (failures - 2)
+	if delay > maxFailureDelay || delay <= 0 {
+		return maxFailureDelay
+	}
+	return delay
+}
+
+// LockoutService tracks failed sign-ins per account. Failures in a row slow down further
+// attempts and enough of them lock the account for a while. Failures older than the lockout
+// duration are forgotten, so an account that sat out its lockout starts over.
+type LockoutService struct {
+	db              *gorm.DB
+	maxAttempts     int
+	lockoutDuration time.Duration
+}
+
+func NewLockoutService(db *gorm.DB, maxAttempts int, lockoutDuration time.Duration) *LockoutService {
+	return &LockoutService{db: db, maxAttempts: maxAttempts, lockoutDuration: lockoutDuration}
+}
+
+// Check returns a *LoginThrottledError if the user can't attempt to sign in yet
+func (ls *LockoutService) Check(user *models.User, now time.Time) error {
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return &LoginThrottledError{RetryAfter: user.LockedUntil.Sub(now), Locked: true}
+	}
+	if user.LastFailedLoginAt != nil && now.Sub(*user.LastFailedLoginAt) <= ls.lockoutDuration {
+		if wait := user.LastFailedLoginAt.Add(failureDelay(user.FailedLoginCount)).Sub(now); wait > 0 {
+			return &LoginThrottledError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a wrong password or second factor for the user and audits it. When the
+// failure locks the account it returns a *LoginThrottledError saying for how long.
+func (ls *LockoutService) RecordFailure(user *models.User, attempt LoginAttempt, reason string) error {
+	var locked *LoginThrottledError
+	err := ls.db.Transaction(func(tx *gorm.DB) error {
+		var current models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "failed_login_count", "last_failed_login_at").
+			First(&current, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		failures := current.FailedLoginCount
+		if current.LastFailedLoginAt != nil && now.Sub(*current.LastFailedLoginAt) > ls.lockoutDuration {
+			failures = 0
+		}
+		failures++
+
+		updates := map[string]interface{}{
+			"failed_login_count":   failures,
+			"last_failed_login_at": now,
+		}
+		if failures >= ls.maxAttempts {
+			until := now.Add(ls.lockoutDuration)
+			updates["locked_until"] = until
+			locked = &LoginThrottledError{RetryAfter: ls.lockoutDuration, Locked: true}
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+
+		state := models.JSONMap{"reason": reason, "failed_attempts": failures}
+		if err := tx.Create(loginAuditLog(models.AuditLoginFailed, user, attempt, state)).Error; err != nil {
+			return err
+		}
+		if locked != nil {
+			state := models.JSONMap{"failed_attempts": failures, "locked_until": updates["locked_until"]}
+			return tx.Create(loginAuditLog(models.AuditAccountLocked, user, attempt, state)).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if locked != nil {
+		return locked
+	}
+	return nil
+}
+
+// RecordRejected audits a sign-in refused for a reason other than a wrong password, such as
+// an unknown address or a locked account. user is nil when the address has no account.
+func (ls *LockoutService) RecordRejected(user *models.User, attempt LoginAttempt, reason string) error {
+	return ls.db.Create(loginAuditLog(models.AuditLoginFailed, user, attempt, models.JSONMap{"reason": reason})).Error
+}
+
+// RecordSuccess clears the user's failed attempts after they sign in
+func (ls *LockoutService) RecordSuccess(user *models.User) error {
+	if user.FailedLoginCount == 0 && user.LastFailedLoginAt == nil && user.LockedUntil == nil {
+		return nil
+	}
+	if err := clearLockout(ls.db, user.ID); err != nil {
+		return err
+	}
+	user.FailedLoginCount = 0
+	user.LastFailedLoginAt = nil
+	user.LockedUntil = nil
+	return nil
+}
+
+// Unlock lifts a lockout and forgets the user's failed attempts on behalf of HR
+func (ls *LockoutService) Unlock(userID, actorID uuid.UUID, ipAddress, userAgent string) error {
+	return ls.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		var actor models.User
+		if err := tx.Select("id", "email", "role").First(&actor, "id = ?", actorID).Error; err != nil {
+			return err
+		}
+
+		if err := clearLockout(tx, user.ID); err != nil {
+			return err
+		}
+
+		entry := newAuditLog(&actor, models.AuditAccountUnlocked, user.ID, ipAddress, userAgent)
+		entry.BeforeState = models.JSONMap{"failed_attempts": user.FailedLoginCount, "locked_until": user.LockedUntil}
+		return tx.Create(entry).Error
+	})
+}
+
+func clearLockout(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+		"failed_login_count":   0,
+		"last_failed_login_at": nil,
+		"locked_until":         nil,
+	}).Error
+}
+
+// loginAuditLog builds the audit entry for a sign-in event. The actor is whoever the attempt
+// claimed to be, which is no one when the address has no account.
+func loginAuditLog(action string, user *models.User, attempt LoginAttempt, state models.JSONMap) *models.AuditLog {
+	entry := &models.AuditLog{
+		ID:         uuid.New(),
+		ActorEmail: attempt.Email,
+		Action:     action,
+		TargetType: "user",
+		AfterState: state,
+		IPAddress:  attempt.IPAddress,
+		UserAgent:  attempt.UserAgent,
+		Method:     "POST",
+		Endpoint:   attempt.Endpoint,
+		CreatedAt:  time.Now(),
+	}
+	if user != nil {
+		entry.ActorID = user.ID
+		entry.ActorRole = user.Role
+		entry.TargetID = user.ID
+	}
+	return entry
+}
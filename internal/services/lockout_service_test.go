@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"leave-management-system/internal/models"
+	"testing"
+	"time"
+)
+
+func TestFailureDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{4, 4 * time.Second},
+		{6, 16 * time.Second},
+		{7, maxFailureDelay},
+		{40, maxFailureDelay},
+		{100, maxFailureDelay},
+	}
+	for _, tt := range tests {
+		if got := failureDelay(tt.failures); got != tt.want {
+			t.Errorf("failureDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutCheck(t *testing.T) {
+	ls := NewLockoutService(nil, 5, 15*time.Minute)
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		when := now.Add(d)
+		return &when
+	}
+
+	tests := []struct {
+		name       string
+		user       models.User
+		wantLocked bool
+		wantWait   time.Duration // Zero when the attempt is allowed
+	}{
+		{"no failures", models.User{}, false, 0},
+		{"one failure is free", models.User{FailedLoginCount: 1, LastFailedLoginAt: at(0)}, false, 0},
+		{"second failure waits a second", models.User{FailedLoginCount: 2, LastFailedLoginAt: at(0)}, false, time.Second},
+		{"delay partly served", models.User{FailedLoginCount: 4, LastFailedLoginAt: at(-time.Second)}, false, 3 * time.Second},
+		{"delay served", models.User{FailedLoginCount: 3, LastFailedLoginAt: at(-3 * time.Second)}, false, 0},
+		{"old failures forgotten", models.User{FailedLoginCount: 9, LastFailedLoginAt: at(-16 * time.Minute)}, false, 0},
+		{"locked", models.User{FailedLoginCount: 5, LastFailedLoginAt: at(0), LockedUntil: at(15 * time.Minute)}, true, 15 * time.Minute},
+		{"lock expired", models.User{FailedLoginCount: 5, LastFailedLoginAt: at(-16 * time.Minute), LockedUntil: at(-time.Minute)}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ls.Check(&tt.user, now)
+			if tt.wantWait == 0 {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+
+			var throttled *LoginThrottledError
+			if !errors.As(err, &throttled) {
+				t.Fatalf("Check() = %v, want a *LoginThrottledError", err)
+			}
+			if throttled.Locked != tt.wantLocked || throttled.RetryAfter != tt.wantWait {
+				t.Errorf("Check() = %+v, want locked %v retry after %v", throttled, tt.wantLocked, tt.wantWait)
+			}
+		})
+	}
+}
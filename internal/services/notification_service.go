@@ -0,0 +1,770 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/realtime"
+	"leave-management-system/pkg/ical"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxBatchSize      = 50
+	outboxMaxAttempts    = 8
+	outboxBaseRetryDelay = time.Minute
+	outboxMaxRetryDelay  = 6 * time.Hour
+	// How long a claimed message is left to its dispatcher before another may send it
+	outboxSendLease = 5 * time.Minute
+)
+
+// LeaveEvent describes a change to a leave request that users should be told about
+type LeaveEvent struct {
+	Type           models.NotificationEvent
+	RequestID      uuid.UUID
+	ActorID        uuid.UUID // uuid.Nil for automatic decisions
+	Comment        string
+	PreviousStatus models.LeaveStatus
+}
+
+// actor returns the event's actor for payloads, nil for automatic decisions
+func (e LeaveEvent) actor() *uuid.UUID {
+	if e.ActorID == uuid.Nil {
+		return nil
+	}
+	id := e.ActorID
+	return &id
+}
+
+// notificationRoute is an email an event can send to one kind of recipient
+type notificationRoute struct {
+	Event     models.NotificationEvent
+	Recipient models.NotificationRecipient
+	Template  string
+	Invite    bool // Attach a calendar invite; skipped when the event doesn't touch the calendar
+	Actions   bool // Include approve/reject links while the request awaits a decision
+	Enabled   bool // Default when the rule is first seeded
+}
+
+var notificationRoutes = []notificationRoute{
+	{Event: models.EventLeaveSubmitted, Recipient: models.RecipientApprover, Template: "leave_request", Actions: true, Enabled: true},
+	{Event: models.EventLeaveSubmitted, Recipient: models.RecipientEmployee, Template: "leave_submitted"},
+	{Event: models.EventLeaveAmended, Recipient: models.RecipientApprover, Template: "leave_amended", Actions: true, Enabled: true},
+	{Event: models.EventLeaveApproved, Recipient: models.RecipientEmployee, Template: "approval", Invite: true, Enabled: true},
+	{Event: models.EventLeaveApproved, Recipient: models.RecipientManager, Template: "manager_fyi", Invite: true},
+	{Event: models.EventLeaveRejected, Recipient: models.RecipientEmployee, Template: "rejection", Enabled: true},
+	{Event: models.EventLeaveCancelled, Recipient: models.RecipientEmployee, Template: "cancellation", Enabled: true},
+	{Event: models.EventLeaveCancelled, Recipient: models.RecipientApprover, Template: "request_cancelled", Enabled: true},
+	{Event: models.EventLeaveEscalated, Recipient: models.RecipientApprover, Template: "escalation", Actions: true, Enabled: true},
+	{Event: models.EventLeaveEscalated, Recipient: models.RecipientHR, Template: "escalation", Actions: true, Enabled: true},
+	{Event: models.EventLeaveReminder, Recipient: models.RecipientApprover, Template: "reminder", Actions: true, Enabled: true},
+}
+
+// UserNotificationEvents lists the events users can choose delivery channels for
+var UserNotificationEvents = []models.NotificationEvent{
+	models.EventLeaveSubmitted,
+	models.EventLeaveAmended,
+	models.EventLeaveApproved,
+	models.EventLeaveRejected,
+	models.EventLeaveCancelled,
+	models.EventLeaveEscalated,
+	models.EventLeaveReminder,
+	models.EventCarryForwardExpiring,
+}
+
+// NotificationRuleInfo is a notification rule together with the template it sends
+type NotificationRuleInfo struct {
+	Event     models.NotificationEvent     `json:"event"`
+	Recipient models.NotificationRecipient `json:"recipient"`
+	Template  string                       `json:"template"`
+	IsEnabled bool                         `json:"is_enabled"`
+}
+
+// NotificationService writes notifications to the outbox and delivers them in the background
+type NotificationService struct {
+	db            *gorm.DB
+	emailService  *EmailService
+	configService *ConfigService
+	actionTokens  *ActionTokenService
+	hub           *realtime.Hub
+	permissions   *PermissionService
+}
+
+func NewNotificationService(db *gorm.DB, emailService *EmailService, configService *ConfigService,
+	actionTokens *ActionTokenService, hub *realtime.Hub, permissions *PermissionService) *NotificationService {
+	return &NotificationService{db: db, emailService: emailService, configService: configService,
+		actionTokens: actionTokens, hub: hub, permissions: permissions}
+}
+
+// SeedDefaultRules creates a rule for every route that doesn't have one yet. managerInvites
+// sets the default for the FYI calendar invites sent to managers.
+func (ns *NotificationService) SeedDefaultRules(managerInvites bool) error {
+	for _, route := range notificationRoutes {
+		enabled := route.Enabled
+		if route.Recipient == models.RecipientManager && route.Template == "manager_fyi" {
+			enabled = managerInvites
+		}
+
+		rule := models.NotificationRule{
+			ID:        uuid.New(),
+			Event:     route.Event,
+			Recipient: route.Recipient,
+			IsEnabled: enabled,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		err := ns.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rule).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns *NotificationService) GetRules() ([]NotificationRuleInfo, error) {
+	enabled, err := ns.enabledRules(ns.db)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]NotificationRuleInfo, 0, len(notificationRoutes))
+	for _, route := range notificationRoutes {
+		rules = append(rules, NotificationRuleInfo{
+			Event:     route.Event,
+			Recipient: route.Recipient,
+			Template:  route.Template,
+			IsEnabled: enabled[ruleKey(route.Event, route.Recipient)],
+		})
+	}
+	return rules, nil
+}
+
+func (ns *NotificationService) UpdateRule(event models.NotificationEvent, recipient models.NotificationRecipient,
+	isEnabled bool, updatedByID uuid.UUID) error {
+
+	if findRoute(event, recipient) == nil {
+		return fmt.Errorf("'%s' notifications cannot be sent to '%s'", event, recipient)
+	}
+
+	rule := models.NotificationRule{
+		ID:          uuid.New(),
+		Event:       event,
+		Recipient:   recipient,
+		IsEnabled:   isEnabled,
+		UpdatedByID: &updatedByID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	return ns.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event"}, {Name: "recipient"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_enabled", "updated_by_id", "updated_at"}),
+	}).Create(&rule).Error
+}
+
+func (ns *NotificationService) enabledRules(tx *gorm.DB) (map[string]bool, error) {
+	var rules []models.NotificationRule
+	if err := tx.Where("is_enabled = ?", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		enabled[ruleKey(rule.Event, rule.Recipient)] = true
+	}
+	return enabled, nil
+}
+
+func ruleKey(event models.NotificationEvent, recipient models.NotificationRecipient) string {
+	return string(event) + "/" + string(recipient)
+}
+
+func findRoute(event models.NotificationEvent, recipient models.NotificationRecipient) *notificationRoute {
+	for i := range notificationRoutes {
+		if notificationRoutes[i].Event == event && notificationRoutes[i].Recipient == recipient {
+			return &notificationRoutes[i]
+		}
+	}
+	return nil
+}
+
+// EnqueueLeaveEvent renders the emails enabled for a leave event and stores them in the outbox.
+// Pass the transaction that made the change so notifications are only sent if it commits.
+// It returns the users given an in-app notification, whose unread counts PublishLeaveEvent
+// refreshes once the transaction has committed.
+func (ns *NotificationService) EnqueueLeaveEvent(tx *gorm.DB, event LeaveEvent) ([]uuid.UUID, error) {
+	if tx == nil {
+		tx = ns.db
+	}
+
+	var request models.LeaveRequest
+	err := tx.Preload("User").
+		Preload("User.Manager").
+		Preload("Approver").
+		First(&request, "id = ?", event.RequestID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := ns.enabledRules(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests from users without a manager are escalated to HR as soon as they are submitted
+	escalatedOnSubmit := event.Type == models.EventLeaveSubmitted && request.Status == models.StatusEscalated
+
+	var messages []*models.NotificationOutbox
+	var notified []uuid.UUID
+	for _, route := range notificationRoutes {
+		if route.Event != event.Type && !(escalatedOnSubmit && route.Event == models.EventLeaveEscalated) {
+			continue
+		}
+		if !enabled[ruleKey(route.Event, route.Recipient)] {
+			continue
+		}
+		// HR only hears about escalations once the request reaches the top of the ladder
+		if route.Event == models.EventLeaveEscalated && route.Recipient == models.RecipientHR && request.ApproverID != nil {
+			continue
+		}
+
+		var invite ical.Method
+		if route.Invite {
+			invite = leaveEventInvite(event)
+			// FYI copies only make sense when there is a calendar entry to add
+			if invite == "" && route.Recipient != models.RecipientEmployee {
+				continue
+			}
+		}
+
+		recipients, err := ns.resolveRecipients(tx, route.Recipient, &request)
+		if err != nil {
+			return nil, err
+		}
+		for _, recipient := range recipients {
+			inApp, email, err := ns.channels(tx, recipient, event.Type)
+			if err != nil {
+				return nil, err
+			}
+			if !inApp && !email {
+				continue
+			}
+
+			var actions *ActionLinks
+			if email && route.Actions && recipient.ID != uuid.Nil && awaitingDecision(&request) {
+				actions, err = ns.actionTokens.Issue(tx, request.ID, recipient.ID, ChannelEmail)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			message, err := ns.emailService.ComposeLeaveEmail(route.Template, recipient, &request, event.Comment, invite, actions)
+			if err != nil {
+				return nil, err
+			}
+
+			if inApp {
+				err := ns.createInApp(tx, recipient.ID, event.Type, message.Subject, leaveNotificationBody(&request, recipient.Locale), &request.ID)
+				if err != nil {
+					return nil, err
+				}
+				notified = append(notified, recipient.ID)
+			}
+			if email {
+				message.EventType = event.Type
+				message.LeaveRequestID = &request.ID
+				messages = append(messages, message)
+			}
+		}
+	}
+
+	return notified, ns.Enqueue(tx, messages...)
+}
+
+// NotifyCarryForwardExpiring tells the owner of a balance that unused carried-forward days expire soon
+func (ns *NotificationService) NotifyCarryForwardExpiring(tx *gorm.DB, balance *models.LeaveBalance,
+	days float64, expiresOn time.Time) error {
+
+	if tx == nil {
+		tx = ns.db
+	}
+
+	var user models.User
+	if err := tx.First(&user, "id = ?", balance.UserID).Error; err != nil {
+		return err
+	}
+
+	event := models.EventCarryForwardExpiring
+	inApp, email, err := ns.channels(tx, &user, event)
+	if err != nil || (!inApp && !email) {
+		return err
+	}
+
+	message, err := ns.emailService.ComposeCarryForwardExpiry(&user, balance, days, expiresOn)
+	if err != nil {
+		return err
+	}
+
+	if inApp {
+		body := localizedLeaveType(balance.LeaveType, user.Locale)
+		if err := ns.createInApp(tx, user.ID, event, message.Subject, body, nil); err != nil {
+			return err
+		}
+	}
+	if !email {
+		return nil
+	}
+	message.EventType = event
+	return ns.Enqueue(tx, message)
+}
+
+// channels returns which channels a recipient wants an event on. Addresses that aren't users,
+// such as a shared mailbox, only receive email.
+func (ns *NotificationService) channels(tx *gorm.DB, recipient *models.User,
+	event models.NotificationEvent) (inApp, email bool, err error) {
+
+	if recipient.ID == uuid.Nil {
+		return false, true, nil
+	}
+
+	var preference models.NotificationPreference
+	err = tx.Where("user_id = ? AND event = ?", recipient.ID, event).First(&preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, true, nil
+	} else if err != nil {
+		return false, false, err
+	}
+	return preference.InApp, preference.Email, nil
+}
+
+func (ns *NotificationService) createInApp(tx *gorm.DB, userID uuid.UUID, event models.NotificationEvent,
+	title, body string, leaveRequestID *uuid.UUID) error {
+
+	notification := models.Notification{
+		ID:             uuid.New(),
+		UserID:         userID,
+		EventType:      event,
+		Title:          title,
+		Body:           body,
+		LeaveRequestID: leaveRequestID,
+		CreatedAt:      time.Now(),
+	}
+	return tx.Create(&notification).Error
+}
+
+// leaveNotificationBody summarises a request as "Annual Leave: March 2, 2026 - March 4, 2026"
+func leaveNotificationBody(request *models.LeaveRequest, locale string) string {
+	formatDate := templateFuncs(NormalizeLocale(locale))["date"].(func(time.Time) string)
+	return fmt.Sprintf("%s: %s - %s", leaveSummary(request, locale),
+		formatDate(request.StartDate), formatDate(request.EndDate))
+}
+
+func (ns *NotificationService) GetNotifications(userID uuid.UUID, unreadOnly bool, page, limit int) ([]models.Notification, int64, error) {
+	var notifications []models.Notification
+	var total int64
+
+	query := ns.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func (ns *NotificationService) GetUnreadCount(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := ns.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (ns *NotificationService) MarkRead(userID, notificationID uuid.UUID) error {
+	result := ns.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Where("read_at IS NULL").
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Already read is fine; a missing or foreign notification is not
+		var count int64
+		ns.db.Model(&models.Notification{}).Where("id = ? AND user_id = ?", notificationID, userID).Count(&count)
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+
+	ns.PublishUnreadCount(userID)
+	return nil
+}
+
+func (ns *NotificationService) MarkAllRead(userID uuid.UUID) (int64, error) {
+	result := ns.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	ns.PublishUnreadCount(userID)
+	return result.RowsAffected, nil
+}
+
+// NotificationPreferenceInfo is the effective channel choice for one event
+type NotificationPreferenceInfo struct {
+	Event models.NotificationEvent `json:"event"`
+	InApp bool                     `json:"in_app"`
+	Email bool                     `json:"email"`
+}
+
+func (ns *NotificationService) GetPreferences(userID uuid.UUID) ([]NotificationPreferenceInfo, error) {
+	var stored []models.NotificationPreference
+	if err := ns.db.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	byEvent := make(map[models.NotificationEvent]models.NotificationPreference, len(stored))
+	for _, preference := range stored {
+		byEvent[preference.Event] = preference
+	}
+
+	preferences := make([]NotificationPreferenceInfo, 0, len(UserNotificationEvents))
+	for _, event := range UserNotificationEvents {
+		info := NotificationPreferenceInfo{Event: event, InApp: true, Email: true}
+		if preference, ok := byEvent[event]; ok {
+			info.InApp = preference.InApp
+			info.Email = preference.Email
+		}
+		preferences = append(preferences, info)
+	}
+	return preferences, nil
+}
+
+func (ns *NotificationService) UpdatePreferences(userID uuid.UUID, preferences []NotificationPreferenceInfo) error {
+	for _, preference := range preferences {
+		if !isUserNotificationEvent(preference.Event) {
+			return fmt.Errorf("unknown notification event '%s'", preference.Event)
+		}
+	}
+
+	return ns.db.Transaction(func(tx *gorm.DB) error {
+		for _, preference := range preferences {
+			row := models.NotificationPreference{
+				ID:        uuid.New(),
+				UserID:    userID,
+				Event:     preference.Event,
+				InApp:     preference.InApp,
+				Email:     preference.Email,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "updated_at"}),
+			}).Create(&row).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func isUserNotificationEvent(event models.NotificationEvent) bool {
+	for _, e := range UserNotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitingDecision reports whether a request still needs an approver to act on it
+func awaitingDecision(request *models.LeaveRequest) bool {
+	return request.Status == models.StatusPending || request.Status == models.StatusEscalated
+}
+
+// leaveEventInvite returns the calendar method for events that add approved leave
+func leaveEventInvite(event LeaveEvent) ical.Method {
+	if event.Type == models.EventLeaveApproved {
+		return ical.MethodRequest
+	}
+	return ""
+}
+
+func (ns *NotificationService) resolveRecipients(tx *gorm.DB, recipient models.NotificationRecipient,
+	request *models.LeaveRequest) ([]*models.User, error) {
+
+	switch recipient {
+	case models.RecipientEmployee:
+		return []*models.User{&request.User}, nil
+	case models.RecipientManager:
+		if request.User.Manager != nil {
+			return []*models.User{request.User.Manager}, nil
+		}
+	case models.RecipientApprover:
+		if request.Approver != nil {
+			return []*models.User{request.Approver}, nil
+		}
+	case models.RecipientHR:
+		return ns.hrRecipients(tx, request.User.Department)
+	}
+	return nil, nil
+}
+
+// hrRecipients resolves who handles escalations for a department. The department's HR partner
+// falls back to the HR mailbox, and the mailbox falls back to every active HR user.
+func (ns *NotificationService) hrRecipients(tx *gorm.DB, department string) ([]*models.User, error) {
+	config, err := ns.configService.GetSystemConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.EscalationRecipientMode {
+	case EscalateToDepartmentPartner:
+		if email := config.DepartmentHRPartners[department]; email != "" {
+			return mailboxRecipient(tx, email)
+		}
+		if config.HRMailbox != "" {
+			return mailboxRecipient(tx, config.HRMailbox)
+		}
+	case EscalateToMailbox:
+		if config.HRMailbox != "" {
+			return mailboxRecipient(tx, config.HRMailbox)
+		}
+	}
+
+	var hrUsers []models.User
+	if err := tx.Where("role = ? AND is_active = ?", models.RoleHR, true).Find(&hrUsers).Error; err != nil {
+		return nil, err
+	}
+
+	recipients := make([]*models.User, len(hrUsers))
+	for i := range hrUsers {
+		recipients[i] = &hrUsers[i]
+	}
+	return recipients, nil
+}
+
+// mailboxRecipient uses the matching user's name and locale when the address belongs to one
+func mailboxRecipient(tx *gorm.DB, email string) ([]*models.User, error) {
+	var user models.User
+	err := tx.Where("LOWER(email) = LOWER(?) AND is_active = ?", email, true).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []*models.User{{FirstName: "HR", LastName: "Team", Email: email, Locale: DefaultLocale}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return []*models.User{&user}, nil
+}
+
+// Enqueue stores rendered messages as pending outbox rows
+func (ns *NotificationService) Enqueue(tx *gorm.DB, messages ...*models.NotificationOutbox) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if tx == nil {
+		tx = ns.db
+	}
+
+	now := time.Now()
+	for _, message := range messages {
+		message.ID = uuid.New()
+		message.Status = models.OutboxStatusPending
+		message.Attempts = 0
+		message.NextAttemptAt = now
+		message.CreatedAt = now
+		message.UpdatedAt = now
+	}
+	return tx.Create(&messages).Error
+}
+
+// DispatchPending delivers due outbox messages. Messages are claimed in a short transaction
+// and sent after it commits, so a slow mail server holds no locks and recording one result
+// can't undo another. A message whose dispatcher died mid-send is retried once its lease ends.
+func (ns *NotificationService) DispatchPending() (sent, failed int, err error) {
+	messages, err := ns.claimPending()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var errs []error
+	for i := range messages {
+		deliverErr := ns.emailService.Deliver(&messages[i])
+		if deliverErr != nil {
+			failed++
+		} else {
+			sent++
+		}
+		if err := ns.recordDelivery(&messages[i], deliverErr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return sent, failed, errors.Join(errs...)
+}
+
+// claimPending marks a batch of due messages as sending under a lease. Rows are locked with
+// SKIP LOCKED so several instances can run the dispatcher without claiming the same message.
+func (ns *NotificationService) claimPending() ([]models.NotificationOutbox, error) {
+	var messages []models.NotificationOutbox
+	err := ns.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?",
+				[]models.OutboxStatus{models.OutboxStatusPending, models.OutboxStatusSending}, now).
+			Order("next_attempt_at ASC").
+			Limit(outboxBatchSize).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+			messages[i].Attempts++
+		}
+		return tx.Model(&models.NotificationOutbox{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":          models.OutboxStatusSending,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(outboxSendLease),
+			"updated_at":      now,
+		}).Error
+	})
+	return messages, err
+}
+
+// recordDelivery stores the outcome of sending a claimed message, scheduling a retry on failure
+func (ns *NotificationService) recordDelivery(message *models.NotificationOutbox, deliverErr error) error {
+	return ns.db.Model(&models.NotificationOutbox{}).
+		Where("id = ? AND status = ?", message.ID, models.OutboxStatusSending).
+		Updates(deliveryUpdates(message.Attempts, deliverErr, time.Now())).Error
+}
+
+// deliveryUpdates are the changes to a message after its attempts-th send ended with deliverErr.
+// A failure is retried with backoff until the last attempt, after which the message is dead.
+func deliveryUpdates(attempts int, deliverErr error, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{"updated_at": now}
+	switch {
+	case deliverErr == nil:
+		updates["status"] = models.OutboxStatusSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case attempts >= outboxMaxAttempts:
+		updates["status"] = models.OutboxStatusDead
+		updates["last_error"] = deliverErr.Error()
+	default:
+		updates["status"] = models.OutboxStatusPending
+		updates["last_error"] = deliverErr.Error()
+		updates["next_attempt_at"] = now.Add(backoffDelay(attempts, outboxBaseRetryDelay, outboxMaxRetryDelay))
+	}
+	return updates
+}
+
+// backoffDelay doubles the wait after each failed attempt, starting at base and capped at max
+func backoffDelay(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+func (ns *NotificationService) GetOutbox(page, limit int, status, eventType, leaveRequestID string) ([]models.NotificationOutbox, int64, error) {
+	var messages []models.NotificationOutbox
+	var total int64
+
+	query := ns.db.Model(&models.NotificationOutbox{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	if leaveRequestID != "" {
+		query = query.Where("leave_request_id = ?", leaveRequestID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range messages {
+		messages[i].TextBody = redactActionLinks(messages[i].TextBody)
+		messages[i].HTMLBody = redactActionLinks(messages[i].HTMLBody)
+	}
+	return messages, total, nil
+}
+
+// actionLinkPattern matches the signed token of an action link
+var actionLinkPattern = regexp.MustCompile(`/api/v1/actions/[A-Za-z0-9_.\-]+`)
+
+// redactActionLinks removes the tokens from action links, which would let anyone reading the
+// message decide the request as its approver
+func redactActionLinks(body string) string {
+	return actionLinkPattern.ReplaceAllString(body, "/api/v1/actions/[redacted]")
+}
+
+// ErrOutboxNotResendable is returned when resending a message that was sent or is being sent
+var ErrOutboxNotResendable = errors.New("only messages waiting for a retry or dead-lettered can be resent")
+
+// Resend queues an outbox message waiting for a retry, or a dead-lettered one, for immediate
+// delivery. Messages already sent, or claimed by a dispatcher, are left alone.
+func (ns *NotificationService) Resend(id uuid.UUID) (*models.NotificationOutbox, error) {
+	var message models.NotificationOutbox
+	if err := ns.db.First(&message, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := ns.db.Model(&models.NotificationOutbox{}).
+		Where("id = ? AND status IN ?", id, []models.OutboxStatus{models.OutboxStatusPending, models.OutboxStatusDead}).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"last_error":      "",
+			"updated_at":      now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOutboxNotResendable
+	}
+
+	message.Status = models.OutboxStatusPending
+	message.Attempts = 0
+	message.NextAttemptAt = now
+	message.LastError = ""
+	message.UpdatedAt = now
+	message.TextBody = redactActionLinks(message.TextBody)
+	message.HTMLBody = redactActionLinks(message.HTMLBody)
+	return &message, nil
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"leave-management-system/internal/models"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{9, 4*time.Hour + 16*time.Minute},
+		{10, outboxMaxRetryDelay},
+		{60, outboxMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.attempts, outboxBaseRetryDelay, outboxMaxRetryDelay); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveryUpdates(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	sendErr := errors.New("550 mailbox unavailable")
+
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		status   models.OutboxStatus
+		retryIn  time.Duration // Zero when no retry is scheduled
+	}{
+		{"sent first time", 1, nil, models.OutboxStatusSent, 0},
+		{"sent on the last attempt", outboxMaxAttempts, nil, models.OutboxStatusSent, 0},
+		{"first failure retries in a minute", 1, sendErr, models.OutboxStatusPending, time.Minute},
+		{"later failure backs off", 4, sendErr, models.OutboxStatusPending, 8 * time.Minute},
+		{"failure before the last attempt retries", outboxMaxAttempts - 1, sendErr, models.OutboxStatusPending,
+			backoffDelay(outboxMaxAttempts-1, outboxBaseRetryDelay, outboxMaxRetryDelay)},
+		{"failure on the last attempt is dead", outboxMaxAttempts, sendErr, models.OutboxStatusDead, 0},
+		{"failure after a resend past the limit is dead", outboxMaxAttempts + 1, sendErr, models.OutboxStatusDead, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := deliveryUpdates(tt.attempts, tt.err, now)
+			if updates["status"] != tt.status {
+				t.Errorf("status = %v, want %v", updates["status"], tt.status)
+			}
+
+			next, scheduled := updates["next_attempt_at"]
+			if (tt.retryIn != 0) != scheduled {
+				t.Fatalf("next_attempt_at set = %v, want %v", scheduled, tt.retryIn != 0)
+			}
+			if scheduled && next != now.Add(tt.retryIn) {
+				t.Errorf("next_attempt_at = %v, want %v", next, now.Add(tt.retryIn))
+			}
+
+			wantError := ""
+			if tt.err != nil {
+				wantError = tt.err.Error()
+			}
+			if updates["last_error"] != wantError {
+				t.Errorf("last_error = %q, want %q", updates["last_error"], wantError)
+			}
+			if _, sent := updates["sent_at"]; sent != (tt.err == nil) {
+				t.Errorf("sent_at set = %v, want %v", sent, tt.err == nil)
+			}
+		})
+	}
+}
+
+// fakeSMTP is an in-process SMTP server that accepts mail for every recipient except those
+// with "reject" in their address, and keeps what it accepted
+type fakeSMTP struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []fakeMail
+}
+
+type fakeMail struct {
+	to   string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) received() []fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMail(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake.smtp ready")
+	var to string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 fake.smtp")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			if strings.Contains(command, "REJECT") {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			to = strings.Trim(strings.TrimSpace(line[len("RCPT TO:"):]), "<>")
+			reply("250 ok")
+		case command == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, fakeMail{to: to, data: data.String()})
+			s.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// TestOutboxDeliveryRoundTrip sends outbox messages to the fake SMTP server and records the
+// outcome as the dispatcher does
+func TestOutboxDeliveryRoundTrip(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	es := NewEmailService("127.0.0.1", smtp.port(), "", "", "noreply@example.com", nil)
+
+	tests := []struct {
+		name     string
+		message  models.NotificationOutbox
+		attempts int
+		status   models.OutboxStatus
+		contains []string
+	}{
+		{
+			name: "plain message",
+			message: models.NotificationOutbox{
+				Recipient: "jane@example.com",
+				Subject:   "Your leave request has been approved",
+				TextBody:  "Enjoy your leave",
+				HTMLBody:  "<p>Enjoy your leave</p>",
+			},
+			attempts: 1,
+			status:   models.OutboxStatusSent,
+			contains: []string{"Subject: Your leave request has been approved", "Enjoy your leave", "text/html"},
+		},
+		{
+			name: "message with an invite",
+			message: models.NotificationOutbox{
+				Recipient:      "jane@example.com",
+				Subject:        "Leave approved",
+				TextBody:       "Approved",
+				HTMLBody:       "<p>Approved</p>",
+				CalendarMethod: "REQUEST",
+				CalendarBody:   "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR\r\n",
+			},
+			attempts: 1,
+			status:   models.OutboxStatusSent,
+			contains: []string{"text/calendar; method=REQUEST", "invite.ics"},
+		},
+		{
+			name:     "rejected recipient is retried",
+			message:  models.NotificationOutbox{Recipient: "reject@example.com", Subject: "Hello", TextBody: "Hello"},
+			attempts: 1,
+			status:   models.OutboxStatusPending,
+		},
+		{
+			name:     "rejected recipient on the last attempt is dead",
+			message:  models.NotificationOutbox{Recipient: "reject@example.com", Subject: "Hello", TextBody: "Hello"},
+			attempts: outboxMaxAttempts,
+			status:   models.OutboxStatusDead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(smtp.received())
+			err := es.Deliver(&tt.message)
+
+			updates := deliveryUpdates(tt.attempts, err, time.Now())
+			if updates["status"] != tt.status {
+				t.Fatalf("status = %v, want %v (send error: %v)", updates["status"], tt.status, err)
+			}
+			if tt.status != models.OutboxStatusSent {
+				if len(smtp.received()) != before {
+					t.Error("rejected message was accepted")
+				}
+				return
+			}
+
+			received := smtp.received()
+			if len(received) != before+1 {
+				t.Fatalf("%d messages received, want %d", len(received), before+1)
+			}
+			mail := received[len(received)-1]
+			if mail.to != tt.message.Recipient {
+				t.Errorf("recipient = %q, want %q", mail.to, tt.message.Recipient)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(mail.data, want) {
+					t.Errorf("message does not contain %q:\n%s", want, mail.data)
+				}
+			}
+		})
+	}
+}
+
+// TestDispatchPending runs the dispatcher against a real database and the fake SMTP server.
+// Set LMS_TEST_DATABASE_URL to a disposable PostgreSQL database to run it; its outbox is emptied.
+func TestDispatchPending(t *testing.T) {
+	dsn := os.Getenv("LMS_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("LMS_TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.NotificationOutbox{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Where("1 = 1").Delete(&models.NotificationOutbox{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	smtp := newFakeSMTP(t)
+	ns := &NotificationService{
+		db:           db,
+		emailService: NewEmailService("127.0.0.1", smtp.port(), "", "", "noreply@example.com", nil),
+	}
+
+	now := time.Now()
+	message := func(recipient string, status models.OutboxStatus, attempts int, nextAttemptAt time.Time) models.NotificationOutbox {
+		return models.NotificationOutbox{
+			ID:            uuid.New(),
+			EventType:     models.EventLeaveApproved,
+			Recipient:     recipient,
+			Subject:       "Leave approved " + strconv.Itoa(attempts),
+			TextBody:      "Approved",
+			Status:        status,
+			Attempts:      attempts,
+			NextAttemptAt: nextAttemptAt,
+		}
+	}
+	tests := []struct {
+		name     string
+		message  models.NotificationOutbox
+		status   models.OutboxStatus
+		attempts int
+	}{
+		{"due", message("due@example.com", models.OutboxStatusPending, 0, now.Add(-time.Minute)),
+			models.OutboxStatusSent, 1},
+		{"rejected is retried", message("reject-retry@example.com", models.OutboxStatusPending, 0, now.Add(-time.Minute)),
+			models.OutboxStatusPending, 1},
+		{"rejected on the last attempt is dead", message("reject-dead@example.com", models.OutboxStatusPending, outboxMaxAttempts-1, now.Add(-time.Minute)),
+			models.OutboxStatusDead, outboxMaxAttempts},
+		{"not due yet", message("later@example.com", models.OutboxStatusPending, 0, now.Add(time.Hour)),
+			models.OutboxStatusPending, 0},
+		{"held by another dispatcher", message("leased@example.com", models.OutboxStatusSending, 1, now.Add(outboxSendLease)),
+			models.OutboxStatusSending, 1},
+		{"lease expired", message("expired-lease@example.com", models.OutboxStatusSending, 1, now.Add(-time.Second)),
+			models.OutboxStatusSent, 2},
+		{"already sent", message("sent@example.com", models.OutboxStatusSent, 1, now.Add(-time.Hour)),
+			models.OutboxStatusSent, 1},
+	}
+	for _, tt := range tests {
+		if err := db.Create(&tt.message).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent, failed, err := ns.DispatchPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 2 || failed != 2 {
+		t.Errorf("DispatchPending() = %d sent, %d failed, want 2 and 2", sent, failed)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.NotificationOutbox
+			if err := db.First(&got, "id = ?", tt.message.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.status || got.Attempts != tt.attempts {
+				t.Errorf("status %s after %d attempts, want %s after %d", got.Status, got.Attempts, tt.status, tt.attempts)
+			}
+			if tt.status == models.OutboxStatusPending && tt.attempts > 0 && !got.NextAttemptAt.After(now) {
+				t.Errorf("retry scheduled at %v, want after %v", got.NextAttemptAt, now)
+			}
+		})
+	}
+
+	delivered := map[string]bool{}
+	for _, mail := range smtp.received() {
+		delivered[mail.to] = true
+	}
+	if len(delivered) != 2 || !delivered["due@example.com"] || !delivered["expired-lease@example.com"] {
+		t.Errorf("delivered to %v, want due@ and expired-lease@example.com", delivered)
+	}
+}
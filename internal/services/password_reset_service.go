@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidResetToken = errors.New("this password reset link is invalid or has expired")
+
+// resetsPerUserPerHour caps self-service reset emails to one address, whoever asks for them
+const resetsPerUserPerHour = 3
+
+// PasswordResetService issues and redeems one-time password reset links. Links are emailed
+// through the notification outbox and only their hash is stored.
+type PasswordResetService struct {
+	db           *gorm.DB
+	emailService *EmailService
+	notifier     *NotificationService
+	userStates   *UserStateCache
+	passwords    *PasswordService
+	ttl          time.Duration
+	baseURL      string
+	logger       *zap.Logger
+}
+
+func NewPasswordResetService(db *gorm.DB, emailService *EmailService, notifier *NotificationService,
+	userStates *UserStateCache, passwords *PasswordService, ttl time.Duration, baseURL string,
+	logger *zap.Logger) *PasswordResetService {
+	return &PasswordResetService{
+		db:           db,
+		emailService: emailService,
+		notifier:     notifier,
+		userStates:   userStates,
+		passwords:    passwords,
+		ttl:          ttl,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		logger:       logger,
+	}
+}
+
+// RequestReset emails a reset link to an active user with the given address. The lookup and
+// send happen in the background and unknown or inactive addresses and rate-limited requests
+// are dropped silently, so callers can't tell which addresses have accounts, even by timing.
+func (ps *PasswordResetService) RequestReset(email, ipAddress string) {
+	go func() {
+		if err := ps.requestReset(email, ipAddress); err != nil {
+			ps.logger.Error("Failed to send a password reset link", zap.Error(err))
+		}
+	}()
+}
+
+func (ps *PasswordResetService) requestReset(email, ipAddress string) error {
+	var user models.User
+	err := ps.db.Where("LOWER(email) = LOWER(?) AND is_active = ?", strings.TrimSpace(email), true).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var recent int64
+	if err := ps.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND requested_by_id IS NULL AND created_at > ?", user.ID, time.Now().Add(-time.Hour)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent >= resetsPerUserPerHour {
+		return nil
+	}
+
+	return ps.send(&user, nil, ipAddress)
+}
+
+// SendResetLink emails a reset link to a user on behalf of HR
+func (ps *PasswordResetService) SendResetLink(userID, requestedBy uuid.UUID, ipAddress string) error {
+	var user models.User
+	if err := ps.db.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return errors.New("cannot send a reset link to a deactivated user")
+	}
+	return ps.send(&user, &requestedBy, ipAddress)
+}
+
+// ResetPassword spends a reset token and sets the user's new password. Every session the user
+// had is ended.
+func (ps *PasswordResetService) ResetPassword(token, newPassword string) error {
+	var userID uuid.UUID
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		var reset models.PasswordResetToken
+		if err := tx.First(&reset, "token_hash = ?", hashToken(token)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		} else if err != nil {
+			return err
+		}
+
+		// Only one use of the token can win
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", reset.ID, time.Now()).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		var user models.User
+		if err := tx.First(&user, "id = ?", reset.UserID).Error; err != nil {
+			return err
+		}
+		if !user.IsActive {
+			return ErrInvalidResetToken
+		}
+		userID = user.ID
+
+		if err := ps.passwords.SetPassword(tx, &user, newPassword, false); err != nil {
+			return err
+		}
+
+		// Any other outstanding links stop working once the password has been chosen
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		// Proving control of the mailbox is as good as HR unlocking the account
+		if err := clearLockout(tx, user.ID); err != nil {
+			return err
+		}
+
+		return RevokeUserSessions(tx, user.ID)
+	})
+	if userID != uuid.Nil {
+		ps.userStates.Invalidate(userID)
+	}
+	return err
+}
+
+// CleanupExpired deletes reset tokens that expired more than a day ago. Recent ones are kept
+// because they count towards the per-user limit.
+func (ps *PasswordResetService) CleanupExpired() (int64, error) {
+	result := ps.db.Where("expires_at < ?", time.Now().Add(-24*time.Hour)).Delete(&models.PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
+
+func (ps *PasswordResetService) send(user *models.User, requestedBy *uuid.UUID, ipAddress string) error {
+	raw, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		reset := models.PasswordResetToken{
+			ID:            uuid.New(),
+			UserID:        user.ID,
+			TokenHash:     hashToken(raw),
+			RequestedByID: requestedBy,
+			IPAddress:     ipAddress,
+			ExpiresAt:     time.Now().Add(ps.ttl),
+		}
+		if err := tx.Create(&reset).Error; err != nil {
+			return err
+		}
+
+		resetURL := fmt.Sprintf("%s/api/v1/reset-password?token=%s", ps.baseURL, url.QueryEscape(raw))
+		message, err := ps.emailService.ComposePasswordReset(user, resetURL, ps.ttl, requestedBy != nil)
+		if err != nil {
+			return err
+		}
+		message.EventType = models.EventPasswordReset
+		return ps.notifier.Enqueue(tx, message)
+	})
+}
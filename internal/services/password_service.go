@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/validator"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// PasswordPolicyError is returned when a new password is rejected by the policy
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Reason
+}
+
+var ErrPasswordReused error = &PasswordPolicyError{Reason: "password was used recently, choose a different one"}
+
+// PasswordPolicy is the set of rules a new password must meet
+type PasswordPolicy struct {
+	MinLength    int `json:"min_length"`
+	MinClasses   int `json:"min_classes"`   // Of upper case, lower case, digits and symbols
+	HistoryCount int `json:"history_count"` // Previous passwords that can't be reused
+	MaxAgeDays   int `json:"max_age_days"`  // 0 never expires
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    10,
+	MinClasses:   3,
+	HistoryCount: 5,
+	MaxAgeDays:   0,
+}
+
+// withDefaults fills in settings from rows saved before the policy was configurable
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength <= 0 {
+		p.MinLength = DefaultPasswordPolicy.MinLength
+	}
+	if p.MinClasses <= 0 {
+		p.MinClasses = DefaultPasswordPolicy.MinClasses
+	}
+	return p
+}
+
+// Validate checks a password's length and character classes
+func (p PasswordPolicy) Validate(password string) error {
+	if len(password) < p.MinLength {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("password must be at least %d characters", p.MinLength)}
+	}
+	if len(password) > 72 {
+		return &PasswordPolicyError{Reason: "password must be at most 72 characters"}
+	}
+	if validator.CharacterClasses(password) < p.MinClasses {
+		return &PasswordPolicyError{Reason: fmt.Sprintf(
+			"password must use at least %d of upper case letters, lower case letters, digits and symbols", p.MinClasses)}
+	}
+	return nil
+}
+
+// Expired reports whether a user's password is past the maximum age. Passwords set before
+// changes were tracked count from when the account was created.
+func (p PasswordPolicy) Expired(user *models.User, now time.Time) bool {
+	if p.MaxAgeDays <= 0 {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return now.After(changedAt.AddDate(0, 0, p.MaxAgeDays))
+}
+
+// PasswordService is the single place passwords are written, so every write is checked
+// against the configured policy and the user's recent passwords.
+type PasswordService struct {
+	db            *gorm.DB
+	configService *ConfigService
+}
+
+func NewPasswordService(db *gorm.DB, configService *ConfigService) *PasswordService {
+	return &PasswordService{db: db, configService: configService}
+}
+
+// Policy returns the current password policy
+func (ps *PasswordService) Policy() PasswordPolicy {
+	return ps.configService.GetPasswordPolicy()
+}
+
+// SetInitialPassword checks the password for a user who hasn't been created yet and sets its
+// hash. mustChange marks it temporary, so the user has to replace it when they first sign in.
+func (ps *PasswordService) SetInitialPassword(user *models.User, password string, mustChange bool) error {
+	if err := ps.Policy().Validate(password); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.PasswordHash = string(hashed)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = mustChange
+	return nil
+}
+
+// SetPassword checks a new password for an existing user against the policy and their recent
+// passwords, then saves it in tx. mustChange marks it temporary.
+func (ps *PasswordService) SetPassword(tx *gorm.DB, user *models.User, password string, mustChange bool) error {
+	if tx == nil {
+		tx = ps.db
+	}
+
+	policy := ps.Policy()
+	if err := policy.Validate(password); err != nil {
+		return err
+	}
+	if err := ps.checkHistory(tx, user, password, policy.HistoryCount); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	previous := user.PasswordHash
+	now := time.Now()
+	user.PasswordHash = string(hashed)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = mustChange
+
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"password_hash":        user.PasswordHash,
+		"password_changed_at":  now,
+		"must_change_password": mustChange,
+		"updated_at":           now,
+	}).Error; err != nil {
+		return err
+	}
+
+	if previous == "" {
+		return nil
+	}
+	if err := tx.Create(&models.PasswordHistory{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		PasswordHash: previous,
+		CreatedAt:    now,
+	}).Error; err != nil {
+		return err
+	}
+	return ps.pruneHistory(tx, user.ID, policy.HistoryCount)
+}
+
+// SetUnusablePassword gives a new user a random password nobody knows, for accounts whose
+// owner will choose a password through a reset link
+func (ps *PasswordService) SetUnusablePassword(user *models.User) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(base64.RawURLEncoding.EncodeToString(b)), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hashed)
+	user.MustChangePassword = true
+	return nil
+}
+
+// checkHistory rejects the current password and the last historyCount previous ones
+func (ps *PasswordService) checkHistory(tx *gorm.DB, user *models.User, password string, historyCount int) error {
+	if historyCount <= 0 {
+		return nil
+	}
+	if user.PasswordHash != "" && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil {
+		return ErrPasswordReused
+	}
+
+	// The current password counts as one of the remembered ones
+	if historyCount == 1 {
+		return nil
+	}
+	var history []models.PasswordHistory
+	if err := tx.Where("user_id = ?", user.ID).Order("created_at DESC").
+		Limit(historyCount - 1).Find(&history).Error; err != nil {
+		return err
+	}
+	for _, h := range history {
+		if bcrypt.CompareHashAndPassword([]byte(h.PasswordHash), []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// pruneHistory keeps only the hashes the policy can still check against
+func (ps *PasswordService) pruneHistory(tx *gorm.DB, userID uuid.UUID, historyCount int) error {
+	keep := historyCount - 1
+	if keep <= 0 {
+		return tx.Where("user_id = ?", userID).Delete(&models.PasswordHistory{}).Error
+	}
+	return tx.Where("user_id = ? AND id NOT IN (?)", userID,
+		tx.Model(&models.PasswordHistory{}).Select("id").
+			Where("user_id = ?", userID).Order("created_at DESC").Limit(keep)).
+		Delete(&models.PasswordHistory{}).Error
+}
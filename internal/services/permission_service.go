@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPermissionDenied      = errors.New("insufficient permissions")
+	ErrUnknownPermission     = errors.New("unknown permission")
+	ErrSysAdminNotEditable   = errors.New("the sysadmin role always has every permission")
+	ErrUnknownPermissionRole = errors.New("unknown role")
+)
+
+// permissionCacheTTL bounds how long another instance keeps using a changed mapping
+const permissionCacheTTL = 30 * time.Second
+
+// PermissionInfo describes a permission for the editor
+type PermissionInfo struct {
+	Permission  models.Permission `json:"permission"`
+	Description string            `json:"description"`
+}
+
+// Permissions lists every permission the application checks
+var Permissions = []PermissionInfo{
+	{models.PermLeaveApprove, "Approve and reject leave routed to them, and see their team's requests"},
+	{models.PermLeaveApproveEscalated, "Approve and reject leave escalated past the manager"},
+	{models.PermLeaveViewAll, "See every leave request, the company calendar and live leave events"},
+	{models.PermBalanceAdjust, "Adjust leave balances"},
+	{models.PermReportView, "Download the payroll report"},
+	{models.PermUserView, "See user accounts"},
+	{models.PermUserCreate, "Create users"},
+	{models.PermUserCreateAdmin, "Create admin and sysadmin users, or change them and their role"},
+	{models.PermUserUpdate, "Edit users, deactivate them and confirm probation"},
+	{models.PermUserUnlock, "Unlock accounts and send password reset links"},
+	{models.PermUserResetTwoFactor, "Reset users' two-factor authentication"},
+	{models.PermHolidayManage, "Manage public holidays"},
+	{models.PermBlackoutManage, "Manage blackout periods"},
+	{models.PermConfigManage, "Change system settings and leave types"},
+	{models.PermYearEndRun, "Run year-end processing"},
+	{models.PermAuditView, "Read the audit log"},
+	{models.PermNotificationManage, "Manage email templates, notification rules and the outbox"},
+	{models.PermWebhookManage, "Manage webhooks"},
+	{models.PermServiceAccountManage, "Manage service accounts and API keys"},
+	{models.PermPermissionManage, "Change what each role may do"},
+	{models.PermSystemMaintain, "Run system maintenance tasks"},
+}
+
+// PermissionRoles are the roles whose permissions can be edited. Sysadmin isn't one: it holds
+// every permission, so no edit can lock everyone out.
+var PermissionRoles = []models.UserRole{
+	models.RoleAdmin, models.RoleHR, models.RoleHOD, models.RoleManager, models.RoleStaff,
+}
+
+// adminRoles can only be given, or have their users changed, with user.create.admin
+var adminRoles = map[models.UserRole]bool{models.RoleAdmin: true, models.RoleSysAdmin: true}
+
+// defaultRolePermissions is what each role could do before permissions were configurable
+var defaultRolePermissions = map[models.UserRole][]models.Permission{
+	models.RoleAdmin: {
+		models.PermLeaveApprove, models.PermLeaveApproveEscalated, models.PermLeaveViewAll,
+		models.PermBalanceAdjust, models.PermReportView,
+		models.PermUserView, models.PermUserCreate, models.PermUserCreateAdmin, models.PermUserUpdate,
+		models.PermUserUnlock, models.PermUserResetTwoFactor,
+		models.PermHolidayManage, models.PermBlackoutManage, models.PermConfigManage, models.PermYearEndRun,
+		models.PermAuditView, models.PermNotificationManage, models.PermWebhookManage, models.PermServiceAccountManage,
+	},
+	models.RoleHR: {
+		models.PermLeaveApprove, models.PermLeaveApproveEscalated, models.PermLeaveViewAll,
+		models.PermBalanceAdjust, models.PermReportView,
+		models.PermUserView, models.PermUserCreate, models.PermUserUpdate, models.PermUserUnlock,
+	},
+	models.RoleHOD:     {models.PermLeaveApprove},
+	models.RoleManager: {models.PermLeaveApprove},
+	models.RoleStaff:   {},
+}
+
+// PermissionService answers whether a role holds a permission. The role to permission mapping
+// lives in the database and is cached for a short time, like user states.
+type PermissionService struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	grants   map[models.UserRole]map[models.Permission]bool
+	loadedAt time.Time
+	// Bumped on every change, so a read racing a write can't cache the old mapping
+	generation uint64
+}
+
+func NewPermissionService(db *gorm.DB) *PermissionService {
+	return &PermissionService{db: db}
+}
+
+// SeedDefaults grants the default permissions when no role has any yet
+func (ps *PermissionService) SeedDefaults() error {
+	var count int64
+	if err := ps.db.Model(&models.RolePermission{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var grants []models.RolePermission
+	for role, permissions := range defaultRolePermissions {
+		for _, permission := range permissions {
+			grants = append(grants, models.RolePermission{Role: role, Permission: permission, CreatedAt: time.Now()})
+		}
+	}
+	return ps.db.Create(&grants).Error
+}
+
+// Has reports whether the role holds the permission. If the mapping can't be read, only
+// sysadmin is let through.
+func (ps *PermissionService) Has(role models.UserRole, permission models.Permission) bool {
+	if role == models.RoleSysAdmin {
+		return true
+	}
+	grants, err := ps.load()
+	if err != nil {
+		return false
+	}
+	return grants[role][permission]
+}
+
+// Check is Has as an error, for services refusing an action
+func (ps *PermissionService) Check(role models.UserRole, permission models.Permission) error {
+	if !ps.Has(role, permission) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// CanAssignRole reports whether the actor may give a user the role. Admin roles need
+// user.create.admin.
+func (ps *PermissionService) CanAssignRole(actorRole, role models.UserRole) bool {
+	return !adminRoles[role] || ps.Has(actorRole, models.PermUserCreateAdmin)
+}
+
+// RolesWith returns every role holding the permission
+func (ps *PermissionService) RolesWith(permission models.Permission) []models.UserRole {
+	roles := []models.UserRole{models.RoleSysAdmin}
+	for _, role := range PermissionRoles {
+		if ps.Has(role, permission) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// GetRolePermissions returns the permissions of every role, sysadmin included
+func (ps *PermissionService) GetRolePermissions() (map[models.UserRole][]models.Permission, error) {
+	grants, err := ps.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[models.UserRole][]models.Permission, len(PermissionRoles)+1)
+	all := make([]models.Permission, 0, len(Permissions))
+	for _, info := range Permissions {
+		all = append(all, info.Permission)
+	}
+	result[models.RoleSysAdmin] = all
+	for _, role := range PermissionRoles {
+		permissions := []models.Permission{}
+		for permission := range grants[role] {
+			permissions = append(permissions, permission)
+		}
+		sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+		result[role] = permissions
+	}
+	return result, nil
+}
+
+// SetRolePermissions replaces a role's permissions. The change applies at once in this process
+// and within permissionCacheTTL in others.
+func (ps *PermissionService) SetRolePermissions(role models.UserRole, permissions []models.Permission, actorID uuid.UUID, ipAddress, userAgent string) error {
+	if role == models.RoleSysAdmin {
+		return ErrSysAdminNotEditable
+	}
+	if _, ok := defaultRolePermissions[role]; !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownPermissionRole, role)
+	}
+	unique := make(map[models.Permission]bool, len(permissions))
+	for _, permission := range permissions {
+		if !knownPermission(permission) {
+			return fmt.Errorf("%w '%s'", ErrUnknownPermission, permission)
+		}
+		unique[permission] = true
+	}
+
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		var actor models.User
+		if err := tx.First(&actor, "id = ?", actorID).Error; err != nil {
+			return err
+		}
+		var before []models.Permission
+		if err := tx.Model(&models.RolePermission{}).Where("role = ?", role).
+			Order("permission").Pluck("permission", &before).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		after := make([]models.Permission, 0, len(unique))
+		for permission := range unique {
+			after = append(after, permission)
+			if err := tx.Create(&models.RolePermission{Role: role, Permission: permission, CreatedAt: time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+		sort.Slice(after, func(i, j int) bool { return after[i] < after[j] })
+
+		entry := newAuditLog(&actor, models.AuditRolePermissionsChanged, uuid.Nil, ipAddress, userAgent)
+		entry.TargetType = "role"
+		entry.BeforeState = models.JSONMap{"role": role, "permissions": before}
+		entry.AfterState = models.JSONMap{"role": role, "permissions": after}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	ps.invalidate()
+	return nil
+}
+
+// load returns the cached mapping, reading it again once it is older than the TTL
+func (ps *PermissionService) load() (map[models.UserRole]map[models.Permission]bool, error) {
+	ps.mu.RLock()
+	grants, loadedAt, generation := ps.grants, ps.loadedAt, ps.generation
+	ps.mu.RUnlock()
+	if grants != nil && time.Since(loadedAt) < permissionCacheTTL {
+		return grants, nil
+	}
+
+	var rows []models.RolePermission
+	if err := ps.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	grants = make(map[models.UserRole]map[models.Permission]bool)
+	for _, row := range rows {
+		if grants[row.Role] == nil {
+			grants[row.Role] = make(map[models.Permission]bool)
+		}
+		grants[row.Role][row.Permission] = true
+	}
+
+	ps.mu.Lock()
+	if ps.generation == generation {
+		ps.grants, ps.loadedAt = grants, time.Now()
+	}
+	ps.mu.Unlock()
+	return grants, nil
+}
+
+func (ps *PermissionService) invalidate() {
+	ps.mu.Lock()
+	ps.grants = nil
+	ps.generation++
+	ps.mu.Unlock()
+}
+
+func knownPermission(permission models.Permission) bool {
+	for _, info := range Permissions {
+		if info.Permission == permission {
+			return true
+		}
+	}
+	return false
+}
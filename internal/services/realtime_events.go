@@ -0,0 +1,87 @@
+package services
+
+import (
+	"leave-management-system/internal/models"
+	"leave-management-system/internal/realtime"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event type pushed to a user whenever their unread notification count changes
+const EventNotificationCount = "notification.count"
+
+type leaveStreamPayload struct {
+	RequestID    uuid.UUID          `json:"request_id"`
+	UserID       uuid.UUID          `json:"user_id"`
+	EmployeeName string             `json:"employee_name"`
+	ActorID      *uuid.UUID         `json:"actor_id"`
+	LeaveType    models.LeaveType   `json:"leave_type"`
+	Status       models.LeaveStatus `json:"status"`
+	StartDate    time.Time          `json:"start_date"`
+	EndDate      time.Time          `json:"end_date"`
+}
+
+type notificationCountPayload struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// PublishLeaveEvent pushes a committed leave event to the employee, their manager, the approver
+// and every role with leave.view_all, then refreshes the unread counts of the notified users
+// EnqueueLeaveEvent returned. Call it after the transaction that produced the event has committed.
+func (ns *NotificationService) PublishLeaveEvent(event LeaveEvent, notified ...uuid.UUID) {
+	if ns.hub == nil {
+		return
+	}
+
+	var request models.LeaveRequest
+	if err := ns.db.Preload("User").First(&request, "id = ?", event.RequestID).Error; err != nil {
+		return
+	}
+
+	userIDs := []uuid.UUID{request.UserID}
+	if request.User.ManagerID != nil {
+		userIDs = append(userIDs, *request.User.ManagerID)
+	}
+	if request.ApproverID != nil {
+		userIDs = append(userIDs, *request.ApproverID)
+	}
+
+	// Reminders don't change the request, they only add a notification
+	if event.Type != models.EventLeaveReminder {
+		ns.hub.Publish(string(event.Type), leaveStreamPayload{
+			RequestID:    request.ID,
+			UserID:       request.UserID,
+			EmployeeName: request.User.FirstName + " " + request.User.LastName,
+			ActorID:      event.actor(),
+			LeaveType:    request.LeaveType,
+			Status:       request.Status,
+			StartDate:    request.StartDate,
+			EndDate:      request.EndDate,
+		}, realtime.Audience{UserIDs: userIDs, Roles: ns.permissions.RolesWith(models.PermLeaveViewAll)})
+	}
+
+	ns.PublishUnreadCount(notified...)
+}
+
+// PublishUnreadCount pushes each user's current unread notification count to their open streams
+func (ns *NotificationService) PublishUnreadCount(userIDs ...uuid.UUID) {
+	if ns.hub == nil {
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		count, err := ns.GetUnreadCount(userID)
+		if err != nil {
+			continue
+		}
+		ns.hub.Publish(EventNotificationCount, notificationCountPayload{UnreadCount: count},
+			realtime.Audience{UserIDs: []uuid.UUID{userID}})
+	}
+}
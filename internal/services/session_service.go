@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/auth"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+)
+
+// TokenPair is the access and refresh token handed to a client for one session
+type TokenPair struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// SessionService issues short-lived access tokens backed by rotating refresh tokens.
+// A session is a family of refresh tokens started by one login. Refreshing spends the
+// presented token and issues its replacement; presenting a spent token again means it
+// leaked, so the whole family is revoked.
+type SessionService struct {
+	db         *gorm.DB
+	jwtManager *auth.JWTManager
+	userStates *UserStateCache
+	refreshTTL time.Duration
+}
+
+func NewSessionService(db *gorm.DB, jwtManager *auth.JWTManager, userStates *UserStateCache, refreshTTL time.Duration) *SessionService {
+	return &SessionService{db: db, jwtManager: jwtManager, userStates: userStates, refreshTTL: refreshTTL}
+}
+
+// StartSession begins a new session for a user who has just authenticated
+func (ss *SessionService) StartSession(user *models.User, userAgent, ipAddress string) (*TokenPair, error) {
+	var pair *TokenPair
+	err := ss.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		pair, _, err = ss.issue(tx, user, uuid.New(), userAgent, ipAddress)
+		return err
+	})
+	return pair, err
+}
+
+// Refresh spends a refresh token and returns a new pair for the same session
+func (ss *SessionService) Refresh(refreshToken, userAgent, ipAddress string) (*TokenPair, *models.User, error) {
+	var token models.RefreshToken
+	if err := ss.db.First(&token, "token_hash = ?", hashToken(refreshToken)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if token.RevokedAt != nil {
+		if token.ReplacedByID != nil {
+			// A rotated token came back, so it was copied: end the session for everyone holding it
+			if err := ss.endSession(token.FamilyID); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := ss.db.First(&user, "id = ?", token.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	var pair *TokenPair
+	err := ss.db.Transaction(func(tx *gorm.DB) error {
+		var replacement *models.RefreshToken
+		var err error
+		pair, replacement, err = ss.issue(tx, &user, token.FamilyID, userAgent, ipAddress)
+		if err != nil {
+			return err
+		}
+
+		// Only one concurrent refresh of the same token can win
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", token.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by_id": replacement.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidRefreshToken
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &user, nil
+}
+
+// Logout ends the session a refresh token belongs to. Unknown tokens are ignored.
+func (ss *SessionService) Logout(refreshToken string) error {
+	var token models.RefreshToken
+	if err := ss.db.First(&token, "token_hash = ?", hashToken(refreshToken)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return ss.endSession(token.FamilyID)
+}
+
+// endSession ends a session and drops its cached state so its access tokens stop working
+func (ss *SessionService) endSession(familyID uuid.UUID) error {
+	if err := revokeFamily(ss.db, familyID); err != nil {
+		return err
+	}
+	ss.userStates.InvalidateSession(familyID)
+	return nil
+}
+
+// LogoutAll ends every session of a user
+func (ss *SessionService) LogoutAll(userID uuid.UUID) error {
+	err := ss.db.Transaction(func(tx *gorm.DB) error {
+		return RevokeUserSessions(tx, userID)
+	})
+	ss.userStates.Invalidate(userID)
+	return err
+}
+
+// ValidateAccessToken checks that a verified access token hasn't been revoked since it was
+// issued, and returns the user's current state. Role and status come from this state rather
+// than the token, so changes apply to tokens already issued.
+func (ss *SessionService) ValidateAccessToken(claims *auth.Claims) (*UserState, error) {
+	state, err := ss.userStates.Get(claims.UserID)
+	if err != nil {
+		return nil, ErrSessionRevoked
+	}
+	if !state.IsActive || state.TokenVersion != claims.Version {
+		return nil, ErrSessionRevoked
+	}
+
+	active, err := ss.userStates.SessionActive(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSessionRevoked
+	}
+	return state, nil
+}
+
+// RevokeUserSessions invalidates every access and refresh token issued to a user. Call it in the
+// transaction that deactivates the user or changes their password or role, and invalidate the
+// user's cached state once it commits.
+func RevokeUserSessions(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// CleanupExpired deletes refresh tokens that can no longer be used
+func (ss *SessionService) CleanupExpired() (int64, error) {
+	result := ss.db.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+func (ss *SessionService) issue(tx *gorm.DB, user *models.User, familyID uuid.UUID, userAgent, ipAddress string) (*TokenPair, *models.RefreshToken, error) {
+	raw, err := generateToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refresh := models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(ss.refreshTTL),
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return nil, nil, err
+	}
+
+	access, claims, err := ss.jwtManager.Generate(user, familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &TokenPair{
+		Token:            access,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     raw,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}, &refresh, nil
+}
+
+func revokeFamily(tx *gorm.DB, familyID uuid.UUID) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
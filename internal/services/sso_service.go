@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/oidc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSSODisabled      = errors.New("single sign-on is not enabled")
+	ErrInvalidSSOState  = errors.New("the sign-in request has expired or was not started here, please try again")
+	ErrInvalidSSOToken  = errors.New("single sign-on has expired, please sign in again")
+	ErrSSOUserNotFound  = errors.New("no account matches your single sign-on identity, contact HR")
+	ErrSSOEmailRequired = errors.New("the single sign-on provider did not supply a verified email address")
+	ErrSSOUserInactive  = errors.New("account is deactivated")
+	ErrSSOSubjectInUse  = errors.New("this account is already linked to a different single sign-on identity")
+)
+
+const (
+	// ssoLoginTTL is how long the user has at the provider before the attempt expires
+	ssoLoginTTL = 10 * time.Minute
+	// ssoHandoffTTL is how long the web app has to exchange the handoff token for a session
+	ssoHandoffTTL = time.Minute
+)
+
+// SSORoleMapping gives members of a provider group a role
+type SSORoleMapping struct {
+	Group string
+	Role  models.UserRole
+}
+
+// SSOSettings configures single sign-on through an OpenID Connect provider
+type SSOSettings struct {
+	Enabled      bool
+	ProviderName string // Shown on the sign-in button
+	OIDC         oidc.Config
+	// MatchByEmail links an existing user with the same verified email on their first SSO sign in
+	MatchByEmail bool
+	// RequireVerifiedEmail refuses email addresses the provider hasn't marked verified
+	RequireVerifiedEmail bool
+	// ProvisionUsers creates users who don't exist yet
+	ProvisionUsers bool
+	DefaultRole    models.UserRole
+	GroupsClaim    string
+	RoleMappings   []SSORoleMapping
+	// SyncRoles sets the role from the group claim at every sign in, not only at provisioning
+	SyncRoles bool
+	// DisablePasswordLogin stops users linked to the provider signing in with a local password
+	DisablePasswordLogin bool
+	// TrustProviderMFA lets an ID token that shows multi-factor authentication stand in for the
+	// local second factor. The token shows it with "mfa" in amr or an acr in MFAACRValues.
+	TrustProviderMFA bool
+	MFAACRValues     []string
+}
+
+// ssoRoleRank orders roles so a user in several mapped groups gets the most privileged one
+var ssoRoleRank = map[models.UserRole]int{
+	models.RoleStaff:    1,
+	models.RoleManager:  2,
+	models.RoleHOD:      3,
+	models.RoleHR:       4,
+	models.RoleAdmin:    5,
+	models.RoleSysAdmin: 6,
+}
+
+// Validate checks the settings name real roles
+func (s SSOSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.OIDC.Issuer == "" || s.OIDC.ClientID == "" {
+		return errors.New("single sign-on needs an issuer and client ID")
+	}
+	if _, ok := ssoRoleRank[s.DefaultRole]; !ok {
+		return fmt.Errorf("unknown single sign-on default role %q", s.DefaultRole)
+	}
+	for _, mapping := range s.RoleMappings {
+		if _, ok := ssoRoleRank[mapping.Role]; !ok {
+			return fmt.Errorf("unknown role %q mapped from group %q", mapping.Role, mapping.Group)
+		}
+	}
+	return nil
+}
+
+// SSOService signs users in through an OpenID Connect provider with the authorization code
+// flow and PKCE. The provider is discovered on first use, so the API starts even when the
+// provider is down. Users signing in this way still need the local second factor, unless the
+// provider is trusted for it and shows it was done.
+type SSOService struct {
+	db          *gorm.DB
+	userService *UserService
+	settings    SSOSettings
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewSSOService(db *gorm.DB, userService *UserService, settings SSOSettings) *SSOService {
+	return &SSOService{db: db, userService: userService, settings: settings}
+}
+
+// Enabled reports whether single sign-on is configured
+func (ss *SSOService) Enabled() bool {
+	return ss.settings.Enabled
+}
+
+// ProviderName is the name to show on the sign-in button
+func (ss *SSOService) ProviderName() string {
+	return ss.settings.ProviderName
+}
+
+// PasswordLoginAllowed reports whether the user may sign in with a local password
+func (ss *SSOService) PasswordLoginAllowed(user *models.User) bool {
+	return !(ss.settings.Enabled && ss.settings.DisablePasswordLogin && user.SSOSubject != nil)
+}
+
+// Begin starts a sign in and returns the provider URL to send the browser to, and the state
+// the browser must present again on its way back
+func (ss *SSOService) Begin(ctx context.Context, ipAddress string) (string, string, error) {
+	provider, err := ss.getProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	login := models.SSOLogin{
+		ID:           uuid.New(),
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		IPAddress:    ipAddress,
+		ExpiresAt:    time.Now().Add(ssoLoginTTL),
+	}
+	if err := ss.db.Create(&login).Error; err != nil {
+		return "", "", err
+	}
+
+	return provider.AuthCodeURL(state, nonce, oidc.CodeChallenge(verifier)), state, nil
+}
+
+// Callback finishes a sign in when the provider sends the browser back with a code. browserState
+// is the state this browser was given by Begin, which must match the one the provider
+// returned. It returns a one-time handoff token the web app exchanges for a session.
+func (ss *SSOService) Callback(ctx context.Context, state, browserState, code string, attempt LoginAttempt) (string, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return "", ErrInvalidSSOState
+	}
+	provider, err := ss.getProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// The state can only be spent once
+	var login models.SSOLogin
+	if err := ss.db.First(&login, "state_hash = ?", hashToken(state)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrInvalidSSOState
+	} else if err != nil {
+		return "", err
+	}
+	result := ss.db.Model(&models.SSOLogin{}).
+		Where("id = ? AND completed_at IS NULL AND expires_at > ?", login.ID, time.Now()).
+		Update("completed_at", time.Now())
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrInvalidSSOState
+	}
+
+	token, err := provider.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		return "", err
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, login.Nonce)
+	if err != nil {
+		return "", err
+	}
+	if claims.Email == "" && token.AccessToken != "" {
+		ss.fillFromUserInfo(ctx, provider, token.AccessToken, claims)
+	}
+	attempt.Email = claims.Email
+
+	user, err := ss.resolveUser(claims, attempt)
+	if err != nil {
+		ss.db.Create(loginAuditLog(models.AuditSSOLoginRejected, user, attempt, models.JSONMap{
+			"reason":  err.Error(),
+			"subject": claims.Subject,
+		}))
+		return "", err
+	}
+
+	handoff, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	handoffHash := hashToken(handoff)
+	if err := ss.db.Model(&models.SSOLogin{}).Where("id = ?", login.ID).Updates(map[string]interface{}{
+		"user_id":      user.ID,
+		"handoff_hash": handoffHash,
+		"provider_mfa": ss.providerDidMFA(claims),
+		"expires_at":   time.Now().Add(ssoHandoffTTL),
+	}).Error; err != nil {
+		return "", err
+	}
+	return handoff, nil
+}
+
+// providerDidMFA reports whether the ID token shows multi-factor authentication at a provider
+// trusted for it
+func (ss *SSOService) providerDidMFA(claims *oidc.Claims) bool {
+	if !ss.settings.TrustProviderMFA {
+		return false
+	}
+	for _, method := range claims.Strings("amr") {
+		if method == "mfa" {
+			return true
+		}
+	}
+	acr, _ := claims.Raw["acr"].(string)
+	for _, value := range ss.settings.MFAACRValues {
+		if acr != "" && acr == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Exchange spends a handoff token and returns the user it signed in, and whether the provider
+// already did a second factor the local one can be skipped for
+func (ss *SSOService) Exchange(handoff string) (*models.User, bool, error) {
+	var login models.SSOLogin
+	if err := ss.db.First(&login, "handoff_hash = ?", hashToken(handoff)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, ErrInvalidSSOToken
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	result := ss.db.Model(&models.SSOLogin{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", login.ID, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 0 || login.UserID == nil {
+		return nil, false, ErrInvalidSSOToken
+	}
+
+	var user models.User
+	if err := ss.db.First(&user, "id = ?", *login.UserID).Error; err != nil {
+		return nil, false, err
+	}
+	if !user.IsActive {
+		return nil, false, ErrSSOUserInactive
+	}
+	return &user, login.ProviderMFA, nil
+}
+
+// CleanupExpired deletes sign-in attempts that can no longer be completed
+func (ss *SSOService) CleanupExpired() (int64, error) {
+	result := ss.db.Where("expires_at < ?", time.Now()).Delete(&models.SSOLogin{})
+	return result.RowsAffected, result.Error
+}
+
+// getProvider discovers the provider the first time it is needed, and again after a failure
+func (ss *SSOService) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	if !ss.settings.Enabled {
+		return nil, ErrSSODisabled
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.provider != nil {
+		return ss.provider, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	provider, err := oidc.Discover(ctx, nil, ss.settings.OIDC)
+	if err != nil {
+		return nil, err
+	}
+	ss.provider = provider
+	return provider, nil
+}
+
+// fillFromUserInfo adds the email and name from the userinfo endpoint, for providers that
+// leave them out of the ID token
+func (ss *SSOService) fillFromUserInfo(ctx context.Context, provider *oidc.Provider, accessToken string, claims *oidc.Claims) {
+	info, err := provider.UserInfo(ctx, accessToken)
+	if err != nil {
+		return
+	}
+	if sub, _ := info["sub"].(string); sub != claims.Subject {
+		return
+	}
+	claims.Email, _ = info["email"].(string)
+	if verified, ok := info["email_verified"].(bool); ok {
+		claims.EmailVerified = verified
+	}
+	if claims.Name == "" {
+		claims.Name, _ = info["name"].(string)
+	}
+	if claims.GivenName == "" {
+		claims.GivenName, _ = info["given_name"].(string)
+	}
+	if claims.FamilyName == "" {
+		claims.FamilyName, _ = info["family_name"].(string)
+	}
+	if _, ok := claims.Raw[ss.settings.GroupsClaim]; !ok && ss.settings.GroupsClaim != "" {
+		if groups, ok := info[ss.settings.GroupsClaim]; ok {
+			claims.Raw[ss.settings.GroupsClaim] = groups
+		}
+	}
+}
+
+// resolveUser finds the user the provider vouched for: by subject once linked, otherwise by
+// email, otherwise by creating them
+func (ss *SSOService) resolveUser(claims *oidc.Claims, attempt LoginAttempt) (*models.User, error) {
+	var user models.User
+	err := ss.db.First(&user, "sso_subject = ?", claims.Subject).Error
+	if err == nil {
+		if !user.IsActive {
+			return &user, ErrSSOUserInactive
+		}
+		if err := ss.syncRole(&user, claims); err != nil {
+			return &user, err
+		}
+		return &user, ss.db.Create(newAuditLog(&user, models.AuditSSOLogin, user.ID, attempt.IPAddress, attempt.UserAgent)).Error
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || (ss.settings.RequireVerifiedEmail && !claims.EmailVerified) {
+		return nil, ErrSSOEmailRequired
+	}
+
+	if ss.settings.MatchByEmail {
+		err := ss.db.First(&user, "LOWER(email) = LOWER(?)", email).Error
+		if err == nil {
+			return ss.link(&user, claims, attempt)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if !ss.settings.ProvisionUsers {
+		return nil, ErrSSOUserNotFound
+	}
+	return ss.provision(claims, email, attempt)
+}
+
+// link records the provider subject on an existing user found by email
+func (ss *SSOService) link(user *models.User, claims *oidc.Claims, attempt LoginAttempt) (*models.User, error) {
+	if !user.IsActive {
+		return user, ErrSSOUserInactive
+	}
+	if user.SSOSubject != nil && *user.SSOSubject != claims.Subject {
+		return user, ErrSSOSubjectInUse
+	}
+
+	if err := ss.db.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumn("sso_subject", claims.Subject).Error; err != nil {
+		return user, err
+	}
+	user.SSOSubject = &claims.Subject
+	if err := ss.syncRole(user, claims); err != nil {
+		return user, err
+	}
+
+	entry := newAuditLog(user, models.AuditSSOUserLinked, user.ID, attempt.IPAddress, attempt.UserAgent)
+	entry.AfterState = models.JSONMap{"subject": claims.Subject, "issuer": ss.settings.OIDC.Issuer}
+	return user, ss.db.Create(entry).Error
+}
+
+// provision creates a user just in time, with the role their groups map to
+func (ss *SSOService) provision(claims *oidc.Claims, email string, attempt LoginAttempt) (*models.User, error) {
+	role := ss.mapRole(claims)
+	if role == "" {
+		role = ss.settings.DefaultRole
+	}
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName = splitName(claims.Name, email)
+	}
+
+	subject := claims.Subject
+	user := &models.User{
+		ID:         uuid.New(),
+		Email:      email,
+		FirstName:  firstName,
+		LastName:   lastName,
+		Role:       role,
+		IsActive:   true,
+		SSOSubject: &subject,
+	}
+	if err := ss.userService.CreateSSOUser(user); err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	entry := newAuditLog(user, models.AuditSSOUserProvisioned, user.ID, attempt.IPAddress, attempt.UserAgent)
+	entry.AfterState = models.JSONMap{"email": email, "role": role, "subject": subject, "issuer": ss.settings.OIDC.Issuer}
+	return user, ss.db.Create(entry).Error
+}
+
+// syncRole applies the group role mapping to a returning user when roles are kept in sync.
+// Members of no mapped group get the default role.
+func (ss *SSOService) syncRole(user *models.User, claims *oidc.Claims) error {
+	if !ss.settings.SyncRoles {
+		return nil
+	}
+	role := ss.mapRole(claims)
+	if role == "" {
+		role = ss.settings.DefaultRole
+	}
+	if role == user.Role {
+		return nil
+	}
+	// Saved through the user service so a role change ends the user's other sessions
+	user.Role = role
+	return ss.userService.UpdateUser(user)
+}
+
+// mapRole returns the most privileged role the user's groups map to, or "" when none do
+func (ss *SSOService) mapRole(claims *oidc.Claims) models.UserRole {
+	if ss.settings.GroupsClaim == "" {
+		return ""
+	}
+	var best models.UserRole
+	for _, group := range claims.Strings(ss.settings.GroupsClaim) {
+		for _, mapping := range ss.settings.RoleMappings {
+			if strings.EqualFold(group, mapping.Group) && ssoRoleRank[mapping.Role] > ssoRoleRank[best] {
+				best = mapping.Role
+			}
+		}
+	}
+	return best
+}
+
+// splitName makes a first and last name from a display name, or from the email address when
+// the provider gave no name
+func splitName(name, email string) (string, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
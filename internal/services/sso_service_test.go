@@ -0,0 +1,34 @@
+package services
+
+import (
+	"leave-management-system/pkg/oidc"
+	"testing"
+)
+
+func TestProviderDidMFA(t *testing.T) {
+	tests := []struct {
+		name     string
+		trust    bool
+		acrs     []string
+		claims   map[string]interface{}
+		expected bool
+	}{
+		{"not trusted", false, nil, map[string]interface{}{"amr": []interface{}{"pwd", "mfa"}}, false},
+		{"amr mfa", true, nil, map[string]interface{}{"amr": []interface{}{"pwd", "mfa"}}, true},
+		{"amr password only", true, nil, map[string]interface{}{"amr": []interface{}{"pwd"}}, false},
+		{"amr single string", true, nil, map[string]interface{}{"amr": "mfa"}, true},
+		{"configured acr", true, []string{"urn:example:loa:2"}, map[string]interface{}{"acr": "urn:example:loa:2"}, true},
+		{"other acr", true, []string{"urn:example:loa:2"}, map[string]interface{}{"acr": "urn:example:loa:1"}, false},
+		{"acr not configured", true, nil, map[string]interface{}{"acr": "urn:example:loa:2"}, false},
+		{"empty acr never matches", true, []string{""}, map[string]interface{}{}, false},
+		{"no claims", true, []string{"urn:example:loa:2"}, map[string]interface{}{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := NewSSOService(nil, nil, SSOSettings{TrustProviderMFA: tt.trust, MFAACRValues: tt.acrs})
+			if got := ss.providerDidMFA(&oidc.Claims{Raw: tt.claims}); got != tt.expected {
+				t.Errorf("providerDidMFA() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
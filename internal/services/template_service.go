@@ -0,0 +1,430 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"leave-management-system/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+const DefaultLocale = "en"
+
+// SupportedLocales lists the languages notifications can be rendered in
+var SupportedLocales = []string{"en", "ms"}
+
+// EmailTemplateNames lists the templates used by EmailService
+var EmailTemplateNames = []string{
+	"leave_request",
+	"leave_submitted",
+	"leave_amended",
+	"approval",
+	"rejection",
+	"cancellation",
+	"request_cancelled",
+	"manager_fyi",
+	"escalation",
+	"reminder",
+	"carry_forward_expiring",
+	"password_reset",
+}
+
+// EmailTemplateData is the data passed to every email template. Administrators edit templates,
+// so users and requests are passed as views holding only what an email may show.
+type EmailTemplateData struct {
+	Recipient *TemplateUser
+	Request   *TemplateLeaveRequest
+	Employee  *TemplateUser
+	Manager   *TemplateUser
+	Approver  *TemplateUser
+	Comment   string
+	AppURL    string
+	Actions   *ActionLinks // Approve/reject links, only set for the approver
+
+	// Carry-forward expiry reminders
+	Balance      *models.LeaveBalance
+	ExpiringDays float64
+	ExpiresOn    time.Time
+
+	// Password reset links
+	ResetURL          string
+	ResetValidMinutes int
+	ResetByHR         bool // Sent by HR rather than requested by the user
+}
+
+// TemplateUser is what an email template may show about a user
+type TemplateUser struct {
+	FirstName  string
+	LastName   string
+	Email      string
+	Department string
+	Position   string
+}
+
+// TemplateLeaveRequest is what an email template may show about a leave request
+type TemplateLeaveRequest struct {
+	LeaveType       models.LeaveType
+	StartDate       time.Time
+	EndDate         time.Time
+	DurationDays    float64
+	HalfDaySession  models.HalfDaySession
+	Reason          string
+	Status          models.LeaveStatus
+	ApprovedAt      *time.Time
+	RejectionReason string
+	CreatedAt       time.Time
+}
+
+func NewTemplateUser(user *models.User) *TemplateUser {
+	if user == nil {
+		return nil
+	}
+	return &TemplateUser{
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Department: user.Department,
+		Position:   user.Position,
+	}
+}
+
+// SetLeaveRequest fills the request, employee, manager and approver from a request loaded
+// with its user, the user's manager and its approver
+func (d *EmailTemplateData) SetLeaveRequest(request *models.LeaveRequest) {
+	d.Request = &TemplateLeaveRequest{
+		LeaveType:       request.LeaveType,
+		StartDate:       request.StartDate,
+		EndDate:         request.EndDate,
+		DurationDays:    request.DurationDays,
+		HalfDaySession:  request.HalfDaySession,
+		Reason:          request.Reason,
+		Status:          request.Status,
+		ApprovedAt:      request.ApprovedAt,
+		RejectionReason: request.RejectionReason,
+		CreatedAt:       request.CreatedAt,
+	}
+	d.Employee = NewTemplateUser(&request.User)
+	d.Manager = NewTemplateUser(request.User.Manager)
+	d.Approver = NewTemplateUser(request.Approver)
+}
+
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+type EmailTemplateInfo struct {
+	Name         string `json:"name"`
+	Locale       string `json:"locale"`
+	Source       string `json:"source"` // database, disk or default
+	IsOverridden bool   `json:"is_overridden"`
+}
+
+// TemplateService renders notification templates. Templates are resolved from the database,
+// then the override directory on disk, then the built-in defaults.
+type TemplateService struct {
+	db          *gorm.DB
+	overrideDir string
+	appURL      string
+}
+
+func NewTemplateService(db *gorm.DB, overrideDir, appURL string) *TemplateService {
+	return &TemplateService{db: db, overrideDir: overrideDir, appURL: strings.TrimRight(appURL, "/")}
+}
+
+// Render renders the named template in the requested locale, falling back to English
+func (ts *TemplateService) Render(name, locale string, data *EmailTemplateData) (*RenderedEmail, error) {
+	locale = NormalizeLocale(locale)
+	source, _, err := ts.resolve(name, locale)
+	if err != nil && locale != DefaultLocale {
+		locale = DefaultLocale
+		source, _, err = ts.resolve(name, locale)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.renderSource(name, locale, source, data)
+}
+
+// RenderSource renders an unsaved template body, used for previews
+func (ts *TemplateService) RenderSource(name, locale, source string, data *EmailTemplateData) (*RenderedEmail, error) {
+	return ts.renderSource(name, NormalizeLocale(locale), source, data)
+}
+
+// GetTemplateSource returns the effective template body and where it came from
+func (ts *TemplateService) GetTemplateSource(name, locale string) (string, string, error) {
+	if !isKnownTemplate(name) {
+		return "", "", fmt.Errorf("unknown template '%s'", name)
+	}
+	return ts.resolve(name, NormalizeLocale(locale))
+}
+
+func (ts *TemplateService) ListTemplates() ([]EmailTemplateInfo, error) {
+	var templates []EmailTemplateInfo
+	for _, name := range EmailTemplateNames {
+		for _, locale := range SupportedLocales {
+			_, source, err := ts.resolve(name, locale)
+			if err != nil {
+				return nil, err
+			}
+			templates = append(templates, EmailTemplateInfo{
+				Name:         name,
+				Locale:       locale,
+				Source:       source,
+				IsOverridden: source != "default",
+			})
+		}
+	}
+	return templates, nil
+}
+
+// SaveOverride validates and stores a database override for a template
+func (ts *TemplateService) SaveOverride(name, locale, body string, updatedByID uuid.UUID) error {
+	if !isKnownTemplate(name) {
+		return fmt.Errorf("unknown template '%s'", name)
+	}
+	if !isSupportedLocale(locale) {
+		return fmt.Errorf("unsupported locale '%s'", locale)
+	}
+	if _, err := ts.renderSource(name, locale, body, SampleEmailTemplateData(ts.appURL)); err != nil {
+		return err
+	}
+
+	var override models.EmailTemplate
+	err := ts.db.Where("name = ? AND locale = ?", name, locale).First(&override).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		override = models.EmailTemplate{
+			ID:          uuid.New(),
+			Name:        name,
+			Locale:      locale,
+			Body:        body,
+			UpdatedByID: &updatedByID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		return ts.db.Create(&override).Error
+	} else if err != nil {
+		return err
+	}
+
+	override.Body = body
+	override.UpdatedByID = &updatedByID
+	override.UpdatedAt = time.Now()
+	return ts.db.Save(&override).Error
+}
+
+// DeleteOverride removes a database override so the disk or built-in template is used again
+func (ts *TemplateService) DeleteOverride(name, locale string) error {
+	return ts.db.Where("name = ? AND locale = ?", name, locale).Delete(&models.EmailTemplate{}).Error
+}
+
+// AppURL is the base URL of the web application used in email links
+func (ts *TemplateService) AppURL() string {
+	return ts.appURL
+}
+
+func (ts *TemplateService) resolve(name, locale string) (string, string, error) {
+	var override models.EmailTemplate
+	err := ts.db.Where("name = ? AND locale = ?", name, locale).First(&override).Error
+	if err == nil {
+		return override.Body, "database", nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", err
+	}
+
+	if ts.overrideDir != "" {
+		body, err := os.ReadFile(filepath.Join(ts.overrideDir, locale, name+".tmpl"))
+		if err == nil {
+			return string(body), "disk", nil
+		}
+	}
+
+	body, err := defaultTemplates.ReadFile("templates/" + locale + "/" + name + ".tmpl")
+	if err != nil {
+		return "", "", fmt.Errorf("template '%s' not found for locale '%s'", name, locale)
+	}
+	return string(body), "default", nil
+}
+
+// renderSource parses the body twice: with text/template for the subject and plain text part,
+// and with html/template so the HTML part is escaped
+func (ts *TemplateService) renderSource(name, locale, source string, data *EmailTemplateData) (*RenderedEmail, error) {
+	funcs := templateFuncs(locale)
+
+	textTmpl, err := texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs)).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	htmlTmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := textTmpl.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, fmt.Errorf("failed to render text body: %w", err)
+	}
+	if err := htmlTmpl.ExecuteTemplate(&html, "html", data); err != nil {
+		return nil, fmt.Errorf("failed to render html body: %w", err)
+	}
+
+	return &RenderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// SampleEmailTemplateData returns placeholder data used to validate and preview templates
+func SampleEmailTemplateData(appURL string) *EmailTemplateData {
+	now := time.Now()
+	manager := &models.User{ID: uuid.New(), FirstName: "Aisha", LastName: "Rahman", Email: "aisha.rahman@example.com", Locale: DefaultLocale}
+	employee := &models.User{ID: uuid.New(), FirstName: "Daniel", LastName: "Tan", Email: "daniel.tan@example.com",
+		Department: "Finance", Locale: DefaultLocale, Manager: manager, ManagerID: &manager.ID}
+	request := &models.LeaveRequest{
+		ID:           uuid.New(),
+		UserID:       employee.ID,
+		User:         *employee,
+		LeaveType:    models.LeaveTypeAnnual,
+		StartDate:    now.AddDate(0, 0, 14),
+		EndDate:      now.AddDate(0, 0, 16),
+		DurationDays: 3,
+		Reason:       "Family trip",
+		Status:       models.StatusApproved,
+		ApproverID:   &manager.ID,
+		Approver:     manager,
+		ApprovedAt:   &now,
+		CreatedAt:    now.AddDate(0, 0, -2),
+		UpdatedAt:    now,
+	}
+
+	balance := &models.LeaveBalance{
+		ID:               uuid.New(),
+		UserID:           employee.ID,
+		LeaveType:        models.LeaveTypeAnnual,
+		Year:             now.Year(),
+		TotalEntitlement: 14,
+		CarriedForward:   5,
+		Used:             2,
+	}
+
+	data := &EmailTemplateData{
+		Recipient: NewTemplateUser(employee),
+		Comment:   "Enjoy your break",
+		AppURL:    appURL,
+		Actions: &ActionLinks{
+			ApproveURL: appURL + "/api/v1/actions/sample-approve",
+			RejectURL:  appURL + "/api/v1/actions/sample-reject",
+			ExpiresAt:  now.AddDate(0, 0, 3),
+		},
+		Balance:           balance,
+		ExpiringDays:      3,
+		ExpiresOn:         now.AddDate(0, 1, 0),
+		ResetURL:          appURL + "/api/v1/reset-password?token=sample-token",
+		ResetValidMinutes: 60,
+	}
+	data.SetLeaveRequest(request)
+	return data
+}
+
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locale = locale[:i]
+	}
+	if !isSupportedLocale(locale) {
+		return DefaultLocale
+	}
+	return locale
+}
+
+func isSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownTemplate(name string) bool {
+	for _, n := range EmailTemplateNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+var leaveTypeLabels = map[string]map[models.LeaveType]string{
+	"en": {
+		models.LeaveTypeAnnual:          "Annual Leave",
+		models.LeaveTypeSick:            "Sick Leave",
+		models.LeaveTypeMaternity:       "Maternity Leave",
+		models.LeaveTypePaternity:       "Paternity Leave",
+		models.LeaveTypeEmergency:       "Emergency Leave",
+		models.LeaveTypeUnpaid:          "Unpaid Leave",
+		models.LeaveTypeUnrecorded:      "Unrecorded Leave",
+		models.LeaveTypeHospitalization: "Hospitalization Leave",
+	},
+	"ms": {
+		models.LeaveTypeAnnual:          "Cuti Tahunan",
+		models.LeaveTypeSick:            "Cuti Sakit",
+		models.LeaveTypeMaternity:       "Cuti Bersalin",
+		models.LeaveTypePaternity:       "Cuti Paterniti",
+		models.LeaveTypeEmergency:       "Cuti Kecemasan",
+		models.LeaveTypeUnpaid:          "Cuti Tanpa Gaji",
+		models.LeaveTypeUnrecorded:      "Cuti Tidak Direkod",
+		models.LeaveTypeHospitalization: "Cuti Hospitalisasi",
+	},
+}
+
+func localizedLeaveType(leaveType models.LeaveType, locale string) string {
+	if label, ok := leaveTypeLabels[NormalizeLocale(locale)][leaveType]; ok {
+		return label
+	}
+	return leaveTypeLabel(leaveType)
+}
+
+var malayMonths = []string{"Januari", "Februari", "Mac", "April", "Mei", "Jun",
+	"Julai", "Ogos", "September", "Oktober", "November", "Disember"}
+
+func templateFuncs(locale string) map[string]interface{} {
+	return map[string]interface{}{
+		"leaveType": func(leaveType models.LeaveType) string {
+			return localizedLeaveType(leaveType, locale)
+		},
+		"date": func(t time.Time) string {
+			if locale == "ms" {
+				return fmt.Sprintf("%d %s %d", t.Day(), malayMonths[t.Month()-1], t.Year())
+			}
+			return t.Format("January 2, 2006")
+		},
+		"days": func(d float64) string {
+			return fmt.Sprintf("%.1f", d)
+		},
+		"fullName": func(u *TemplateUser) string {
+			if u == nil {
+				return ""
+			}
+			return strings.TrimSpace(u.FirstName + " " + u.LastName)
+		},
+	}
+}
@@ -0,0 +1,526 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/totp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidTwoFactorCode     = errors.New("invalid authentication code")
+	ErrInvalidLoginChallenge    = errors.New("sign-in has expired, please sign in again")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupNotStarted = errors.New("two-factor setup has not been started")
+	ErrTwoFactorRequired        = errors.New("two-factor authentication is required for your role")
+	ErrTwoFactorResetNotAllowed = errors.New("only a system administrator can reset a system administrator's two-factor authentication")
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeAlphabet leaves out i, l and o, which are easily misread. It has 32
+	// characters so every random byte maps to one without bias.
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+	// maxChallengeAttempts is how many codes one sign-in may try before starting over
+	maxChallengeAttempts = 5
+	// totpSkew accepts codes from one step either side of now to allow for clock drift
+	totpSkew = 1
+)
+
+// TwoFactorEnrollment is what an authenticator app needs to be set up
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to show as a QR code
+}
+
+// TwoFactorStatus describes a user's two-factor set up
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// LoginChallengeResponse is returned in place of tokens when a sign-in needs a second factor
+type LoginChallengeResponse struct {
+	TwoFactorRequired  bool      `json:"two_factor_required"`
+	EnrollmentRequired bool      `json:"enrollment_required"` // The user's role requires 2FA and they haven't set it up
+	ChallengeToken     string    `json:"challenge_token"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+// TwoFactorService manages TOTP authenticators and recovery codes, and the second step of
+// signing in for users who have one or whose role requires one.
+type TwoFactorService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	lockouts      *LockoutService
+	issuer        string
+	key           [32]byte
+	challengeTTL  time.Duration
+}
+
+func NewTwoFactorService(db *gorm.DB, configService *ConfigService, lockouts *LockoutService,
+	issuer, encryptionKey string, challengeTTL time.Duration) *TwoFactorService {
+	return &TwoFactorService{
+		db:            db,
+		configService: configService,
+		lockouts:      lockouts,
+		issuer:        issuer,
+		key:           sha256.Sum256([]byte(encryptionKey)),
+		challengeTTL:  challengeTTL,
+	}
+}
+
+// Required reports whether the user's role must sign in with a second factor
+func (ts *TwoFactorService) Required(user *models.User) bool {
+	return ts.configService.RequiresTwoFactor(user.Role)
+}
+
+// NeedsSecondFactor reports whether signing in as the user takes a second step
+func (ts *TwoFactorService) NeedsSecondFactor(user *models.User) bool {
+	return user.TwoFactorEnabled || ts.Required(user)
+}
+
+// Status returns the user's two-factor set up
+func (ts *TwoFactorService) Status(user *models.User) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{Enabled: user.TwoFactorEnabled, Required: ts.Required(user)}
+	if !user.TwoFactorEnabled {
+		return status, nil
+	}
+	err := ts.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&status.RecoveryCodesRemaining).Error
+	return status, err
+}
+
+// StartEnrollment generates a new authenticator secret for the user. It takes effect once
+// confirmed with a code; starting again replaces a pending secret.
+func (ts *TwoFactorService) StartEnrollment(user *models.User) (*TwoFactorEnrollment, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := ts.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	row := models.TwoFactorSecret{UserID: user.ID, EncryptedSecret: sealed}
+	if err := ts.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"encrypted_secret", "confirmed_at", "last_used_step", "updated_at"}),
+	}).Create(&row).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(ts.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment turns on two-factor sign-in once the user enters a code from their new
+// authenticator, and returns their recovery codes. They are only ever shown here.
+func (ts *TwoFactorService) ConfirmEnrollment(user *models.User, code, ipAddress, userAgent string) ([]string, error) {
+	var codes []string
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		var secret models.TwoFactorSecret
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&secret, "user_id = ?", user.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTwoFactorSetupNotStarted
+		} else if err != nil {
+			return err
+		}
+		if secret.ConfirmedAt != nil {
+			return ErrTwoFactorAlreadyEnabled
+		}
+
+		step, err := ts.validateTOTP(&secret, code)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&secret).Updates(map[string]interface{}{
+			"confirmed_at":   now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			UpdateColumn("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+
+		if codes, err = replaceRecoveryCodes(tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Create(newAuditLog(user, models.AuditTwoFactorEnabled, user.ID, ipAddress, userAgent)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.TwoFactorEnabled = true
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a current code
+func (ts *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code, ipAddress, userAgent string) ([]string, error) {
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	var codes []string
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := ts.verify(tx, user, code, ipAddress, userAgent); err != nil {
+			return err
+		}
+		var err error
+		if codes, err = replaceRecoveryCodes(tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Create(newAuditLog(user, models.AuditRecoveryCodesRegenerated, user.ID, ipAddress, userAgent)).Error
+	})
+	return codes, err
+}
+
+// Disable turns off two-factor sign-in after checking a current code. Users whose role
+// requires it can't turn it off.
+func (ts *TwoFactorService) Disable(user *models.User, code, ipAddress, userAgent string) error {
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if ts.Required(user) {
+		return ErrTwoFactorRequired
+	}
+
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := ts.verify(tx, user, code, ipAddress, userAgent); err != nil {
+			return err
+		}
+		if err := removeTwoFactor(tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Create(newAuditLog(user, models.AuditTwoFactorDisabled, user.ID, ipAddress, userAgent)).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.TwoFactorEnabled = false
+	return nil
+}
+
+// Reset removes a user's authenticator and recovery codes on behalf of an administrator, for
+// a user who has lost both. If their role requires 2FA they set it up again at next sign in.
+func (ts *TwoFactorService) Reset(userID, actorID uuid.UUID, ipAddress, userAgent string) error {
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id", "role", "two_factor_enabled").First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		var actor models.User
+		if err := tx.Select("id", "email", "role").First(&actor, "id = ?", actorID).Error; err != nil {
+			return err
+		}
+		if user.Role == models.RoleSysAdmin && actor.Role != models.RoleSysAdmin {
+			return ErrTwoFactorResetNotAllowed
+		}
+
+		if err := removeTwoFactor(tx, user.ID); err != nil {
+			return err
+		}
+		// A sign-in already past the password step has to start over
+		if err := tx.Model(&models.LoginChallenge{}).Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		entry := newAuditLog(&actor, models.AuditTwoFactorReset, user.ID, ipAddress, userAgent)
+		entry.BeforeState = models.JSONMap{"two_factor_enabled": user.TwoFactorEnabled}
+		entry.AfterState = models.JSONMap{"two_factor_enabled": false}
+		return tx.Create(entry).Error
+	})
+}
+
+// BeginLogin records that the user has given the right password and returns the challenge
+// the client completes with a code
+func (ts *TwoFactorService) BeginLogin(user *models.User, ipAddress, userAgent string) (*LoginChallengeResponse, error) {
+	raw, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := models.LoginChallenge{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(ts.challengeTTL),
+	}
+	if err := ts.db.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+
+	return &LoginChallengeResponse{
+		TwoFactorRequired:  true,
+		EnrollmentRequired: !user.TwoFactorEnabled,
+		ChallengeToken:     raw,
+		ExpiresAt:          challenge.ExpiresAt,
+	}, nil
+}
+
+// ChallengeEnrollment starts setting up an authenticator during sign in, for a user whose
+// role requires 2FA and who has none yet
+func (ts *TwoFactorService) ChallengeEnrollment(challengeToken string) (*TwoFactorEnrollment, error) {
+	_, user, err := ts.openChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return ts.StartEnrollment(user)
+}
+
+// CompleteLogin checks the code for a sign-in challenge and returns the signed in user. A user
+// finishing set up during sign in also gets their recovery codes. Wrong codes count towards
+// the account lockout like wrong passwords do.
+func (ts *TwoFactorService) CompleteLogin(challengeToken, code string, attempt LoginAttempt) (*models.User, []string, error) {
+	challenge, user, err := ts.openChallenge(challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ts.lockouts.Check(user, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	if attempt.Email == "" {
+		attempt.Email = user.Email
+	}
+
+	var codes []string
+	if user.TwoFactorEnabled {
+		err = ts.db.Transaction(func(tx *gorm.DB) error {
+			return ts.verify(tx, user, code, attempt.IPAddress, attempt.UserAgent)
+		})
+	} else {
+		codes, err = ts.ConfirmEnrollment(user, code, attempt.IPAddress, attempt.UserAgent)
+	}
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if err := ts.db.Model(challenge).UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+			return nil, nil, err
+		}
+		if err := ts.lockouts.RecordFailure(user, attempt, "invalid_two_factor_code"); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidTwoFactorCode
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	// Only one completion of the challenge can win
+	result := ts.db.Model(&models.LoginChallenge{}).Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrInvalidLoginChallenge
+	}
+	return user, codes, nil
+}
+
+// CleanupExpired deletes sign-in challenges that can no longer be completed
+func (ts *TwoFactorService) CleanupExpired() (int64, error) {
+	result := ts.db.Where("expires_at < ? OR used_at IS NOT NULL", time.Now()).Delete(&models.LoginChallenge{})
+	return result.RowsAffected, result.Error
+}
+
+func (ts *TwoFactorService) openChallenge(challengeToken string) (*models.LoginChallenge, *models.User, error) {
+	var challenge models.LoginChallenge
+	if err := ts.db.First(&challenge, "token_hash = ?", hashToken(challengeToken)).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidLoginChallenge
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		return nil, nil, ErrInvalidLoginChallenge
+	}
+
+	var user models.User
+	if err := ts.db.First(&user, "id = ?", challenge.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrInvalidLoginChallenge
+	}
+	return &challenge, &user, nil
+}
+
+// verify checks an authenticator code, or spends a recovery code, for a user with 2FA on
+func (ts *TwoFactorService) verify(tx *gorm.DB, user *models.User, code, ipAddress, userAgent string) error {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == totp.Digits {
+		var secret models.TwoFactorSecret
+		if err := tx.First(&secret, "user_id = ? AND confirmed_at IS NOT NULL", user.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnabled
+		} else if err != nil {
+			return err
+		}
+		step, err := ts.validateTOTP(&secret, code)
+		if err != nil {
+			return err
+		}
+		// A code is good for one sign in, even within its time step
+		result := tx.Model(&models.TwoFactorSecret{}).
+			Where("user_id = ? AND last_used_step < ?", user.ID, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	var remaining int64
+	if err := tx.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&remaining).Error; err != nil {
+		return err
+	}
+	entry := newAuditLog(user, models.AuditRecoveryCodeUsed, user.ID, ipAddress, userAgent)
+	entry.AfterState = models.JSONMap{"recovery_codes_remaining": remaining}
+	return tx.Create(entry).Error
+}
+
+func (ts *TwoFactorService) validateTOTP(secret *models.TwoFactorSecret, code string) (int64, error) {
+	plain, err := ts.open(secret.EncryptedSecret)
+	if err != nil {
+		return 0, err
+	}
+	step, ok := totp.Validate(plain, code, time.Now(), totpSkew)
+	if !ok {
+		return 0, ErrInvalidTwoFactorCode
+	}
+	return step, nil
+}
+
+// seal encrypts an authenticator secret with AES-GCM for storage
+func (ts *TwoFactorService) seal(secret string) (string, error) {
+	gcm, err := ts.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (ts *TwoFactorService) open(sealed string) (string, error) {
+	gcm, err := ts.cipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid two-factor secret")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func (ts *TwoFactorService) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(ts.key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and issues a new set
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		rows[i] = models.RecoveryCode{
+			ID:       uuid.New(),
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func removeTwoFactor(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorSecret{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("two_factor_enabled", false).Error
+}
+
+// generateRecoveryCode returns a code like "k7wq2-mxp4n"
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 11)
+	for i, v := range b {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
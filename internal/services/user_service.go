@@ -16,14 +16,19 @@ type UserService struct {
 	auditLogger        *logger.AuditLogger
 	leaveTypeConfigSvc *LeaveTypeConfigService
 	leaveCalculator    *LeaveCalculator
+	userStates         *UserStateCache
+	passwords          *PasswordService
 }
 
-func NewUserService(db *gorm.DB, auditLogger *logger.AuditLogger, leaveTypeConfigSvc *LeaveTypeConfigService, leaveCalculator *LeaveCalculator) *UserService {
+func NewUserService(db *gorm.DB, auditLogger *logger.AuditLogger, leaveTypeConfigSvc *LeaveTypeConfigService,
+	leaveCalculator *LeaveCalculator, userStates *UserStateCache, passwords *PasswordService) *UserService {
 	return &UserService{
 		db:                 db,
 		auditLogger:        auditLogger,
 		leaveTypeConfigSvc: leaveTypeConfigSvc,
 		leaveCalculator:    leaveCalculator,
+		userStates:         userStates,
+		passwords:          passwords,
 	}
 }
 
@@ -54,16 +59,33 @@ func (us *UserService) GetAllUsers() ([]models.User, error) {
 	return users, err
 }
 
-func (us *UserService) CreateUser(user *models.User) error {
-	// Only set default password if no password hash was provided
-	if user.PasswordHash == "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("Default@123"), bcrypt.DefaultCost)
-		if err != nil {
+// CreateUser creates a user with a temporary password they must change when they first sign
+// in. With no password the account gets one nobody knows, and the user sets their own through
+// a reset link.
+func (us *UserService) CreateUser(user *models.User, password string) error {
+	if password == "" {
+		if err := us.passwords.SetUnusablePassword(user); err != nil {
 			return err
 		}
-		user.PasswordHash = string(hashedPassword)
+	} else if err := us.passwords.SetInitialPassword(user, password, true); err != nil {
+		return err
+	}
+
+	return us.create(user)
+}
+
+// CreateSSOUser creates a user who signs in through single sign-on. They get an unusable
+// local password and have nothing to change.
+func (us *UserService) CreateSSOUser(user *models.User) error {
+	if err := us.passwords.SetUnusablePassword(user); err != nil {
+		return err
 	}
+	user.MustChangePassword = false
+	return us.create(user)
+}
 
+// create saves a new user with their first leave balances
+func (us *UserService) create(user *models.User) error {
 	// Set default values
 	if user.JoinedDate.IsZero() {
 		user.JoinedDate = time.Now()
@@ -124,6 +146,7 @@ func (us *UserService) createDefaultLeaveBalances(tx *gorm.DB, userID uuid.UUID,
 }
 
 func (us *UserService) ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error {
+	defer us.userStates.Invalidate(userID)
 	return us.db.Transaction(func(tx *gorm.DB) error {
 		var user models.User
 		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
@@ -135,17 +158,13 @@ func (us *UserService) ChangePassword(userID uuid.UUID, currentPassword, newPass
 			return errors.New("current password is incorrect")
 		}
 
-		// Hash new password
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-		if err != nil {
+		// Checks the policy and recent passwords, and clears any forced change
+		if err := us.passwords.SetPassword(tx, &user, newPassword, false); err != nil {
 			return err
 		}
 
-		// Update password
-		user.PasswordHash = string(hashedPassword)
-		user.UpdatedAt = time.Now()
-
-		return tx.Save(&user).Error
+		// Sessions signed in with the old password end here
+		return RevokeUserSessions(tx, userID)
 	})
 }
 
@@ -174,7 +193,28 @@ func (us *UserService) GetTeamMembers(managerID uuid.UUID) ([]models.User, error
 	return users, err
 }
 
+// UpdateUser saves a user. Deactivating them or changing their role revokes their sessions,
+// so they sign in again under the new access.
 func (us *UserService) UpdateUser(user *models.User) error {
 	user.UpdatedAt = time.Now()
-	return us.db.Save(user).Error
+	defer us.userStates.Invalidate(user.ID)
+	return us.db.Transaction(func(tx *gorm.DB) error {
+		var current models.User
+		if err := tx.Select("id", "role", "is_active").First(&current, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+
+		// The token version, lockout counters, two-factor flag and SSO link are only ever
+		// changed in place, never saved from a stale copy
+		if err := tx.Omit("token_version", "failed_login_count", "last_failed_login_at", "locked_until",
+			"two_factor_enabled", "sso_subject").
+			Save(user).Error; err != nil {
+			return err
+		}
+
+		if current.Role != user.Role || (current.IsActive && !user.IsActive) {
+			return RevokeUserSessions(tx, user.ID)
+		}
+		return nil
+	})
 }
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"leave-management-system/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserState is the part of a user that decides what their access token grants
+type UserState struct {
+	Email              string
+	Role               models.UserRole
+	IsActive           bool
+	TokenVersion       int
+	MustChangePassword bool
+}
+
+type userStateEntry struct {
+	state    UserState
+	loadedAt time.Time
+}
+
+type sessionEntry struct {
+	active   bool
+	loadedAt time.Time
+}
+
+// sessionSweepSize is how many cached sessions there can be before expired ones are dropped
+const sessionSweepSize = 1024
+
+// UserStateCache keeps recently read user states, and whether sessions are still live, for a
+// short time so authenticating a request doesn't read the database every time. Writers
+// invalidate the entry so changes apply at once in this process; the TTL bounds how stale
+// another instance can be.
+type UserStateCache struct {
+	db       *gorm.DB
+	ttl      time.Duration
+	mu       sync.RWMutex
+	entries  map[uuid.UUID]userStateEntry
+	sessions map[uuid.UUID]sessionEntry
+	// Bumped on every invalidation, so a read racing a write can't cache the old state
+	generation uint64
+}
+
+func NewUserStateCache(db *gorm.DB, ttl time.Duration) *UserStateCache {
+	return &UserStateCache{
+		db:       db,
+		ttl:      ttl,
+		entries:  make(map[uuid.UUID]userStateEntry),
+		sessions: make(map[uuid.UUID]sessionEntry),
+	}
+}
+
+// Get returns a user's current state, reading it from the database when not cached
+func (uc *UserStateCache) Get(userID uuid.UUID) (*UserState, error) {
+	uc.mu.RLock()
+	entry, ok := uc.entries[userID]
+	generation := uc.generation
+	uc.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < uc.ttl {
+		state := entry.state
+		return &state, nil
+	}
+
+	var user models.User
+	if err := uc.db.Select("id", "email", "role", "is_active", "token_version", "must_change_password").
+		First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	state := UserState{
+		Email:              user.Email,
+		Role:               user.Role,
+		IsActive:           user.IsActive,
+		TokenVersion:       user.TokenVersion,
+		MustChangePassword: user.MustChangePassword,
+	}
+	uc.mu.Lock()
+	if uc.generation == generation {
+		uc.entries[userID] = userStateEntry{state: state, loadedAt: time.Now()}
+	}
+	uc.mu.Unlock()
+	return &state, nil
+}
+
+// Invalidate drops a user's cached state. Call it after the change has been committed.
+func (uc *UserStateCache) Invalidate(userID uuid.UUID) {
+	uc.mu.Lock()
+	delete(uc.entries, userID)
+	uc.generation++
+	uc.mu.Unlock()
+}
+
+// SessionActive reports whether a session still has a refresh token that can be used, reading
+// it from the database when not cached
+func (uc *UserStateCache) SessionActive(familyID uuid.UUID) (bool, error) {
+	uc.mu.RLock()
+	entry, ok := uc.sessions[familyID]
+	generation := uc.generation
+	uc.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < uc.ttl {
+		return entry.active, nil
+	}
+
+	var active int64
+	if err := uc.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", familyID, time.Now()).
+		Count(&active).Error; err != nil {
+		return false, err
+	}
+
+	uc.mu.Lock()
+	if uc.generation == generation {
+		if len(uc.sessions) >= sessionSweepSize {
+			for id, cached := range uc.sessions {
+				if time.Since(cached.loadedAt) >= uc.ttl {
+					delete(uc.sessions, id)
+				}
+			}
+		}
+		uc.sessions[familyID] = sessionEntry{active: active > 0, loadedAt: time.Now()}
+	}
+	uc.mu.Unlock()
+	return active > 0, nil
+}
+
+// InvalidateSession drops a session's cached state. Call it after revoking the session has
+// been committed.
+func (uc *UserStateCache) InvalidateSession(familyID uuid.UUID) {
+	uc.mu.Lock()
+	delete(uc.sessions, familyID)
+	uc.generation++
+	uc.mu.Unlock()
+}
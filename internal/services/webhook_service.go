@@ -0,0 +1,637 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/webhook"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	webhookBatchSize       = 20
+	webhookMaxAttempts     = 8
+	webhookBaseRetryDelay  = 30 * time.Second
+	webhookMaxRetryDelay   = 6 * time.Hour
+	webhookRequestTimeout  = 10 * time.Second
+	webhookResponseLogSize = 2048
+	// How long a claimed delivery is left to its dispatcher before another may post it
+	webhookSendLease = 5 * time.Minute
+)
+
+// Event type sent by the test-ping action
+const WebhookEventPing = "ping"
+
+// WebhookEvents lists the leave events that can be subscribed to
+var WebhookEvents = []models.NotificationEvent{
+	models.EventLeaveSubmitted,
+	models.EventLeaveAmended,
+	models.EventLeaveApproved,
+	models.EventLeaveRejected,
+	models.EventLeaveCancelled,
+	models.EventLeaveEscalated,
+}
+
+// WebhookPayload is the JSON body posted to subscribers
+type WebhookPayload struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookLeaveData struct {
+	RequestID       uuid.UUID              `json:"request_id"`
+	Employee        WebhookEmployee        `json:"employee"`
+	LeaveType       models.LeaveType       `json:"leave_type"`
+	StartDate       string                 `json:"start_date"`
+	EndDate         string                 `json:"end_date"`
+	DurationDays    float64                `json:"duration_days"`
+	HalfDaySession  models.HalfDaySession  `json:"half_day_session,omitempty"`
+	Status          models.LeaveStatus     `json:"status"`
+	PreviousStatus  models.LeaveStatus     `json:"previous_status,omitempty"`
+	ApproverID      *uuid.UUID             `json:"approver_id"`
+	EscalationLevel models.EscalationLevel `json:"escalation_level"`
+	ActorID         *uuid.UUID             `json:"actor_id"` // Null for automatic decisions
+	Comment         string                 `json:"comment,omitempty"`
+	// Approve/reject links acting as the approver, only for subscriptions with include_actions
+	Actions *ActionLinks `json:"actions,omitempty"`
+}
+
+type WebhookEmployee struct {
+	ID         uuid.UUID `json:"id"`
+	Email      string    `json:"email"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Department string    `json:"department"`
+}
+
+// WebhookSubscriptionResponse includes the signing secret, which is only returned when it is created
+type WebhookSubscriptionResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookService struct {
+	db           *gorm.DB
+	actionTokens *ActionTokenService
+	client       *http.Client
+}
+
+func NewWebhookService(db *gorm.DB, actionTokens *ActionTokenService) *WebhookService {
+	return &WebhookService{
+		db:           db,
+		actionTokens: actionTokens,
+		client:       &http.Client{Timeout: webhookRequestTimeout},
+	}
+}
+
+func (ws *WebhookService) CreateSubscription(name, endpoint string, events []string, includeActions bool,
+	createdByID uuid.UUID) (*WebhookSubscriptionResponse, error) {
+	if err := validateWebhook(endpoint, events); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := models.WebhookSubscription{
+		ID:             uuid.New(),
+		Name:           name,
+		URL:            endpoint,
+		Secret:         secret,
+		Events:         models.StringList(events),
+		IncludeActions: includeActions,
+		IsActive:       true,
+		CreatedByID:    createdByID,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if err := ws.db.Create(&subscription).Error; err != nil {
+		return nil, err
+	}
+
+	return &WebhookSubscriptionResponse{WebhookSubscription: subscription, Secret: secret}, nil
+}
+
+func (ws *WebhookService) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := ws.db.Order("created_at ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (ws *WebhookService) GetSubscription(id uuid.UUID) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := ws.db.First(&subscription, "id = ?", id).Error
+	return &subscription, err
+}
+
+func (ws *WebhookService) UpdateSubscription(id uuid.UUID, updates map[string]interface{}) (*models.WebhookSubscription, error) {
+	subscription, err := ws.GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := updates["name"].(string); ok {
+		subscription.Name = v
+	}
+	if v, ok := updates["url"].(string); ok {
+		subscription.URL = v
+	}
+	if v, ok := updates["events"].([]interface{}); ok {
+		events := make(models.StringList, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				events = append(events, s)
+			}
+		}
+		subscription.Events = events
+	}
+	if v, ok := updates["is_active"].(bool); ok {
+		subscription.IsActive = v
+	}
+	if v, ok := updates["include_actions"].(bool); ok {
+		subscription.IncludeActions = v
+	}
+
+	if err := validateWebhook(subscription.URL, subscription.Events); err != nil {
+		return nil, err
+	}
+
+	subscription.UpdatedAt = time.Now()
+	if err := ws.db.Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription removes a subscription together with its delivery history
+func (ws *WebhookService) DeleteSubscription(id uuid.UUID) error {
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&models.WebhookDelivery{}).Select("id").Where("subscription_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&models.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.WebhookSubscription{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// RotateSecret issues a new signing secret; deliveries signed with the old one stop verifying
+func (ws *WebhookService) RotateSecret(id uuid.UUID) (*WebhookSubscriptionResponse, error) {
+	subscription, err := ws.GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Secret = secret
+	subscription.UpdatedAt = time.Now()
+	if err := ws.db.Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return &WebhookSubscriptionResponse{WebhookSubscription: *subscription, Secret: secret}, nil
+}
+
+func validateWebhook(endpoint string, events []string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+
+	for _, event := range events {
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("unknown webhook event '%s'", event)
+		}
+	}
+	return nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if string(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// EnqueueLeaveEvent queues a delivery for every active subscription to the event.
+// Pass the transaction that made the change so nothing is sent if it rolls back.
+func (ws *WebhookService) EnqueueLeaveEvent(tx *gorm.DB, event LeaveEvent) error {
+	if !isWebhookEvent(string(event.Type)) {
+		return nil
+	}
+
+	var subscriptions []models.WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	var matching []models.WebhookSubscription
+	for _, subscription := range subscriptions {
+		if len(subscription.Events) == 0 || subscription.Events.Contains(string(event.Type)) {
+			matching = append(matching, subscription)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
+
+	var request models.LeaveRequest
+	if err := tx.Preload("User").First(&request, "id = ?", event.RequestID).Error; err != nil {
+		return err
+	}
+
+	offersActions := (event.Type == models.EventLeaveSubmitted || event.Type == models.EventLeaveAmended ||
+		event.Type == models.EventLeaveEscalated) &&
+		request.ApproverID != nil && awaitingDecision(&request)
+
+	payload := WebhookPayload{
+		ID:        uuid.New(),
+		Type:      string(event.Type),
+		CreatedAt: time.Now().UTC(),
+		Data: WebhookLeaveData{
+			RequestID: request.ID,
+			Employee: WebhookEmployee{
+				ID:         request.User.ID,
+				Email:      request.User.Email,
+				FirstName:  request.User.FirstName,
+				LastName:   request.User.LastName,
+				Department: request.User.Department,
+			},
+			LeaveType:       request.LeaveType,
+			StartDate:       request.StartDate.Format("2006-01-02"),
+			EndDate:         request.EndDate.Format("2006-01-02"),
+			DurationDays:    request.DurationDays,
+			HalfDaySession:  request.HalfDaySession,
+			Status:          request.Status,
+			PreviousStatus:  event.PreviousStatus,
+			ApproverID:      request.ApproverID,
+			EscalationLevel: request.EscalationLevel,
+			ActorID:         event.actor(),
+			Comment:         event.Comment,
+		},
+	}
+
+	deliveries := make([]*models.WebhookDelivery, 0, len(matching))
+	for _, subscription := range matching {
+		delivery, err := newWebhookDelivery(subscription.ID, payload)
+		if err != nil {
+			return err
+		}
+		// The links act as the approver, so only subscriptions that asked for them get any,
+		// and each gets its own
+		if offersActions && subscription.IncludeActions {
+			token, err := ws.actionTokens.IssueToken(tx, request.ID, *request.ApproverID, ChannelChat)
+			if err != nil {
+				return err
+			}
+			delivery.ActionTokenID = &token.ID
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return tx.Create(&deliveries).Error
+}
+
+func newWebhookDelivery(subscriptionID uuid.UUID, payload WebhookPayload) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		EventID:        payload.ID,
+		EventType:      payload.Type,
+		Payload:        string(body),
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// DispatchPending sends due deliveries. Deliveries are claimed in a short transaction and
+// posted after it commits, so slow endpoints hold no locks and recording one attempt can't undo
+// another. A delivery whose dispatcher died mid-post is retried once its lease ends.
+func (ws *WebhookService) DispatchPending() (succeeded, failed int, err error) {
+	deliveries, err := ws.claimPending()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var errs []error
+	for i := range deliveries {
+		ok, err := ws.attempt(&deliveries[i])
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	return succeeded, failed, errors.Join(errs...)
+}
+
+// claimPending marks a batch of due deliveries as sending under a lease. Rows are locked with
+// SKIP LOCKED so several instances can run the dispatcher without claiming the same delivery.
+func (ws *WebhookService) claimPending() ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := ws.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?",
+				[]models.WebhookDeliveryStatus{models.WebhookDeliveryPending, models.WebhookDeliverySending}, now).
+			Order("next_attempt_at ASC").
+			Limit(webhookBatchSize).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].Attempts++
+			deliveries[i].Status = models.WebhookDeliverySending
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":          models.WebhookDeliverySending,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(webhookSendLease),
+			"updated_at":      now,
+		}).Error
+	})
+	return deliveries, err
+}
+
+// attempt posts a claimed delivery once, then logs the attempt and schedules a retry on failure
+func (ws *WebhookService) attempt(delivery *models.WebhookDelivery) (bool, error) {
+	var subscription models.WebhookSubscription
+	if err := ws.db.First(&subscription, "id = ?", delivery.SubscriptionID).Error; err != nil {
+		return false, err
+	}
+
+	log := ws.post(&subscription, delivery)
+	log.ID = uuid.New()
+	log.DeliveryID = delivery.ID
+	log.Number = delivery.Attempts
+
+	ok := log.Error == "" && log.StatusCode >= 200 && log.StatusCode < 300
+	delivery.LastStatusCode = log.StatusCode
+	delivery.UpdatedAt = time.Now()
+
+	switch {
+	case ok:
+		now := time.Now()
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case !subscription.IsActive:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = "subscription is disabled"
+	default:
+		delivery.LastError = log.Error
+		if delivery.LastError == "" {
+			delivery.LastError = fmt.Sprintf("unexpected status %d", log.StatusCode)
+		}
+		if delivery.Attempts >= webhookMaxAttempts {
+			delivery.Status = models.WebhookDeliveryFailed
+		} else {
+			delivery.Status = models.WebhookDeliveryPending
+			delivery.NextAttemptAt = time.Now().Add(backoffDelay(delivery.Attempts, webhookBaseRetryDelay, webhookMaxRetryDelay))
+		}
+	}
+
+	err := ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&log).Error; err != nil {
+			return err
+		}
+		return tx.Omit("AttemptLog").Save(delivery).Error
+	})
+	return ok, err
+}
+
+func (ws *WebhookService) post(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) models.WebhookAttempt {
+	start := time.Now()
+	log := models.WebhookAttempt{CreatedAt: start}
+	if !subscription.IsActive {
+		log.Error = "subscription is disabled"
+		return log
+	}
+
+	body, err := ws.deliveryBody(delivery)
+	if err != nil {
+		log.Error = err.Error()
+		return log
+	}
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Error = err.Error()
+		return log
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LeaveManagementSystem-Webhook/1.0")
+	req.Header.Set(webhook.EventHeader, delivery.EventType)
+	req.Header.Set(webhook.DeliveryHeader, delivery.ID.String())
+	req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(subscription.Secret, timestamp, body))
+
+	resp, err := ws.client.Do(req)
+	log.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		log.Error = err.Error()
+		return log
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLogSize))
+	log.StatusCode = resp.StatusCode
+	log.ResponseBody = strings.ToValidUTF8(string(response), "")
+	return log
+}
+
+// deliveryBody returns the payload to post, adding the delivery's action links while they can
+// still be used
+func (ws *WebhookService) deliveryBody(delivery *models.WebhookDelivery) ([]byte, error) {
+	body := []byte(delivery.Payload)
+	if delivery.ActionTokenID == nil {
+		return body, nil
+	}
+
+	var token models.ActionToken
+	if err := ws.db.First(&token, "id = ?", *delivery.ActionTokenID).Error; err != nil {
+		return nil, err
+	}
+	if token.UsedAt != nil || !time.Now().Before(token.ExpiresAt) {
+		return body, nil
+	}
+	links, err := ws.actionTokens.Links(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	var data WebhookLeaveData
+	payload := WebhookPayload{Data: &data}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	data.Actions = links
+	return json.Marshal(payload)
+}
+
+// Ping sends a test event to a subscription immediately and returns the logged delivery
+func (ws *WebhookService) Ping(subscriptionID uuid.UUID) (*models.WebhookDelivery, error) {
+	subscription, err := ws.GetSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := newWebhookDelivery(subscription.ID, WebhookPayload{
+		ID:        uuid.New(),
+		Type:      WebhookEventPing,
+		CreatedAt: time.Now().UTC(),
+		Data:      map[string]interface{}{"subscription_id": subscription.ID, "name": subscription.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Claimed from the start, so the dispatcher doesn't post it as well
+	delivery.Status = models.WebhookDeliverySending
+	delivery.Attempts = 1
+	delivery.NextAttemptAt = time.Now().Add(webhookSendLease)
+	if err := ws.db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	if _, err := ws.attempt(delivery); err != nil {
+		return nil, err
+	}
+	return ws.GetDelivery(delivery.ID)
+}
+
+// Redeliver queues a fresh delivery of the same event, keeping the original's history
+func (ws *WebhookService) Redeliver(deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	original, err := ws.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		if original.ActionTokenID != nil {
+			tokenID, err := ws.reissueActions(tx, original)
+			if err != nil {
+				return err
+			}
+			delivery.ActionTokenID = tokenID
+		}
+		return tx.Create(&delivery).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// reissueActions issues fresh links for a redelivery while the original's approver still
+// has the request awaiting their decision and the subscription still wants links
+func (ws *WebhookService) reissueActions(tx *gorm.DB, original *models.WebhookDelivery) (*uuid.UUID, error) {
+	var subscription models.WebhookSubscription
+	if err := tx.First(&subscription, "id = ?", original.SubscriptionID).Error; err != nil {
+		return nil, err
+	}
+	var token models.ActionToken
+	if err := tx.First(&token, "id = ?", *original.ActionTokenID).Error; err != nil {
+		return nil, err
+	}
+	var request models.LeaveRequest
+	if err := tx.First(&request, "id = ?", token.LeaveRequestID).Error; err != nil {
+		return nil, err
+	}
+	if !subscription.IncludeActions || request.ApproverID == nil || *request.ApproverID != token.UserID ||
+		!awaitingDecision(&request) {
+		return nil, nil
+	}
+
+	fresh, err := ws.actionTokens.IssueToken(tx, request.ID, token.UserID, ChannelChat)
+	if err != nil {
+		return nil, err
+	}
+	return &fresh.ID, nil
+}
+
+func (ws *WebhookService) GetDeliveries(subscriptionID uuid.UUID, status string, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := ws.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// GetDelivery returns a delivery with its attempt log
+func (ws *WebhookService) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := ws.db.Preload("AttemptLog", func(db *gorm.DB) *gorm.DB {
+		return db.Order("number ASC")
+	}).First(&delivery, "id = ?", id).Error
+	return &delivery, err
+}
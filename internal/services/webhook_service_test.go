@@ -0,0 +1,79 @@
+package services
+
+import (
+	"io"
+	"leave-management-system/internal/models"
+	"leave-management-system/pkg/webhook"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWebhookPostRoundTrip(t *testing.T) {
+	const secret = "whsec_round_trip"
+	payload := `{"id":"evt","event":"leave.approved","data":{"status":"approved"}}`
+
+	tests := []struct {
+		name       string
+		secret     string // Secret the receiver checks against
+		status     int
+		active     bool
+		wantStatus int
+		wantError  bool
+	}{
+		{"delivered", secret, http.StatusOK, true, http.StatusOK, false},
+		{"receiver rejects signature", "rotated", http.StatusUnauthorized, true, http.StatusUnauthorized, false},
+		{"receiver fails", secret, http.StatusInternalServerError, true, http.StatusInternalServerError, false},
+		{"subscription disabled", secret, http.StatusOK, false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := &models.WebhookDelivery{
+				ID:        uuid.New(),
+				EventType: "leave.approved",
+				Payload:   payload,
+			}
+
+			received := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = true
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != payload {
+					t.Errorf("body = %s, want %s", body, payload)
+				}
+				if got := r.Header.Get(webhook.EventHeader); got != delivery.EventType {
+					t.Errorf("event header = %q, want %q", got, delivery.EventType)
+				}
+				if got := r.Header.Get(webhook.DeliveryHeader); got != delivery.ID.String() {
+					t.Errorf("delivery header = %q, want %q", got, delivery.ID)
+				}
+				err := webhook.Verify(tt.secret, r.Header.Get(webhook.SignatureHeader),
+					r.Header.Get(webhook.TimestampHeader), body, 5*time.Minute)
+				if err != nil {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			ws := NewWebhookService(nil, nil)
+			subscription := &models.WebhookSubscription{URL: server.URL, Secret: secret, IsActive: tt.active}
+			attempt := ws.post(subscription, delivery)
+
+			if attempt.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", attempt.StatusCode, tt.wantStatus)
+			}
+			if (attempt.Error != "") != tt.wantError {
+				t.Errorf("error = %q, wantError %v", attempt.Error, tt.wantError)
+			}
+			if received == tt.wantError {
+				t.Errorf("receiver called = %v, want %v", received, !tt.wantError)
+			}
+		})
+	}
+}
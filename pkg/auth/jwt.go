@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"leave-management-system/internal/models"
+	"leave-management-system/pkg/oidc"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,34 +13,70 @@ import (
 )
 
 type Claims struct {
-	UserID uuid.UUID       `json:"user_id"`
-	Email  string          `json:"email"`
-	Role   models.UserRole `json:"role"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Email     string          `json:"email"`
+	Role      models.UserRole `json:"role"`
+	SessionID uuid.UUID       `json:"sid"` // Refresh token family the access token was issued to
+	Version   int             `json:"ver"` // User's token version when issued
 	jwt.RegisteredClaims
 }
 
+// JWTManager signs access tokens with the active key and verifies them with any configured key,
+// so keys can be rotated without signing everyone out. Tokens name their key in the kid header.
 type JWTManager struct {
-	secretKey     string
+	issuer        string
 	tokenDuration time.Duration
+	active        *SigningKey
+	keys          map[string]*SigningKey
 }
 
-func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
-	return &JWTManager{secretKey: secretKey, tokenDuration: tokenDuration}
+// NewJWTManager signs with the key named by activeKeyID, which must have its private key. The
+// other keys only verify tokens: retired keys until their tokens expire, and new keys published
+// ahead of becoming active so every instance can verify them first.
+func NewJWTManager(keys []*SigningKey, activeKeyID, issuer string, tokenDuration time.Duration) (*JWTManager, error) {
+	manager := &JWTManager{
+		issuer:        issuer,
+		tokenDuration: tokenDuration,
+		keys:          make(map[string]*SigningKey, len(keys)),
+	}
+	for _, key := range keys {
+		if _, exists := manager.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate signing key ID %q", key.ID)
+		}
+		manager.keys[key.ID] = key
+	}
+
+	active, ok := manager.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", activeKeyID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active signing key %q has no private key", activeKeyID)
+	}
+	manager.active = active
+	return manager, nil
 }
 
-func (manager *JWTManager) Generate(user *models.User) (string, *Claims, error) {
+// Generate issues an access token for a user's session
+func (manager *JWTManager) Generate(user *models.User, sessionID uuid.UUID) (string, *Claims, error) {
 	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
+		Version:   user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    manager.issuer,
+			Subject:   user.ID.String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(manager.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(manager.secretKey))
+	token := jwt.NewWithClaims(manager.active.Method, claims)
+	token.Header["kid"] = manager.active.ID
+	signedToken, err := token.SignedString(manager.active.Private)
 	if err != nil {
 		return "", nil, err
 	}
@@ -46,41 +84,53 @@ func (manager *JWTManager) Generate(user *models.User) (string, *Claims, error)
 }
 
 func (manager *JWTManager) Verify(accessToken string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(
-		accessToken,
-		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			_, ok := token.Method.(*jwt.SigningMethodHMAC)
-			if !ok {
-				return nil, fmt.Errorf("unexpected token signing method")
-			}
-			return []byte(manager.secretKey), nil
-		},
-	)
-
+	claims, err := manager.ExtractClaims(accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
-
 	return claims, nil
 }
 
 func (manager *JWTManager) ExtractClaims(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
-
-	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(manager.secretKey), nil
-	})
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(manager.issuer),
+		jwt.WithExpirationRequired(),
+	)
 
+	_, err := parser.ParseWithClaims(tokenString, claims, manager.verificationKey)
 	if err != nil {
 		return nil, err
 	}
 
 	return claims, nil
 }
+
+// verificationKey finds the key a token names. The token's algorithm has to be the key's own,
+// so a token can't pick how its signature is checked.
+func (manager *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := manager.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected token signing method")
+	}
+	return key.Public, nil
+}
+
+// JWKS returns every verification key, for other services checking our tokens
+func (manager *JWTManager) JWKS() (oidc.JSONWebKeySet, error) {
+	set := oidc.JSONWebKeySet{Keys: make([]oidc.JSONWebKey, 0, len(manager.keys))}
+	for _, key := range manager.keys {
+		jwk, err := oidc.NewJSONWebKey(key.ID, key.Method.Alg(), key.Public)
+		if err != nil {
+			return oidc.JSONWebKeySet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
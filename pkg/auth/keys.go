@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for signing tokens
+const minRSABits = 2048
+
+// SigningKey is a key access tokens are signed with, or only verified with once it has been
+// retired. RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer // Nil for a key kept only to verify tokens it already signed
+	Public  crypto.PublicKey
+}
+
+// NewSigningKey wraps an RSA or Ed25519 private or public key
+func NewSigningKey(id string, key interface{}) (*SigningKey, error) {
+	if id == "" {
+		return nil, errors.New("signing key needs an ID")
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key %q is %d bits, at least %d are needed", id, k.N.BitLen(), minRSABits)
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key %q is %d bits, at least %d are needed", id, k.N.BitLen(), minRSABits)
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	}
+	return nil, fmt.Errorf("signing key %q is a %T, only RSA and Ed25519 keys are supported", id, key)
+}
+
+// ParseSigningKey reads a PEM encoded PKCS #8 or PKCS #1 private key, or a PKIX public key
+func ParseSigningKey(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %q is not PEM encoded", id)
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("signing key %q has unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signing key %q: %w", id, err)
+	}
+	return NewSigningKey(id, key)
+}
+
+// LoadSigningKey reads a PEM encoded key from a file
+func LoadSigningKey(id, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %q: %w", id, err)
+	}
+	return ParseSigningKey(id, data)
+}
+
+// GenerateSigningKey makes a new Ed25519 key, for development when none is configured
+func GenerateSigningKey(id string) (*SigningKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(id, private)
+}
@@ -0,0 +1,137 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Method string
+
+const (
+	MethodPublish Method = "PUBLISH"
+	MethodRequest Method = "REQUEST"
+	MethodCancel  Method = "CANCEL"
+)
+
+const prodID = "-//Leave Management System//EN"
+
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+// Event is an all-day VEVENT. End is the last day of the event (inclusive).
+type Event struct {
+	UID          string
+	Sequence     int
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	Cancelled    bool
+	Transparent  bool // Does not block the attendee's free/busy time
+	Organizer    *Attendee
+	Attendees    []Attendee
+	LastModified time.Time
+}
+
+type Calendar struct {
+	Name   string
+	Method Method
+	Events []Event
+}
+
+// String renders the calendar as an RFC 5545 document
+func (c *Calendar) String() string {
+	var b strings.Builder
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+prodID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		writeLine(&b, "METHOD:"+string(c.Method))
+	}
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	stamp := formatDateTime(time.Now())
+	for _, event := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+stamp)
+		writeLine(&b, fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+		writeLine(&b, "DTSTART;VALUE=DATE:"+formatDate(event.Start))
+		// DTEND is exclusive for all-day events
+		writeLine(&b, "DTEND;VALUE=DATE:"+formatDate(event.End.AddDate(0, 0, 1)))
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Organizer != nil {
+			writeLine(&b, fmt.Sprintf("ORGANIZER;CN=%s:mailto:%s", quoteParam(event.Organizer.Name), event.Organizer.Email))
+		}
+		for _, attendee := range event.Attendees {
+			writeLine(&b, fmt.Sprintf("ATTENDEE;CN=%s;ROLE=REQ-PARTICIPANT:mailto:%s", quoteParam(attendee.Name), attendee.Email))
+		}
+		if !event.LastModified.IsZero() {
+			writeLine(&b, "LAST-MODIFIED:"+formatDateTime(event.LastModified))
+		}
+		if event.Transparent {
+			writeLine(&b, "TRANSP:TRANSPARENT")
+		} else {
+			writeLine(&b, "TRANSP:OPAQUE")
+		}
+		if event.Cancelled {
+			writeLine(&b, "STATUS:CANCELLED")
+		} else {
+			writeLine(&b, "STATUS:CONFIRMED")
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// writeLine folds content lines longer than 75 octets as required by RFC 5545
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// Do not split a multi-byte UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ";", "\\;")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "\r\n", "\\n")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return s
+}
+
+func quoteParam(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
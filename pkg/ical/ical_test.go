@@ -0,0 +1,164 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Annual leave", "Annual leave"},
+		{"comma and semicolon", "Leave; a, b", `Leave\; a\, b`},
+		{"backslash", `C:\path`, `C:\\path`},
+		{"newline", "line one\nline two", `line one\nline two`},
+		{"crlf", "line one\r\nline two", `line one\nline two`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeText(tt.in); got != tt.want {
+				t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Annual leave"},
+		{"exactly 75", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"long ascii", "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{"long multi-byte", "SUMMARY:" + strings.Repeat("cuti é ", 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeLine(&b, tt.line)
+			out := b.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("line not terminated with CRLF: %q", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, line := range lines {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d octets, want at most 75", i, len(line))
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d does not start with a space: %q", i, line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, line)
+				}
+			}
+
+			// Unfolding gives back the original line
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != tt.line {
+				t.Errorf("unfolded = %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestCalendarString(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	event := Event{
+		UID:       "0b6f2a56-6f0e-4f62-9a4a-2d3b7f4c1e01@lms",
+		Sequence:  2,
+		Summary:   "Annual leave, Jane Doe",
+		Start:     start,
+		End:       end,
+		Organizer: &Attendee{Name: `HR "Team"`, Email: "hr@example.com"},
+		Attendees: []Attendee{{Name: "Jane Doe", Email: "jane@example.com"}},
+	}
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "request",
+			calendar: Calendar{Method: MethodRequest, Events: []Event{event}},
+			want: []string{
+				"METHOD:REQUEST",
+				"UID:" + event.UID,
+				"SEQUENCE:2",
+				"DTSTART;VALUE=DATE:20260302",
+				// The end is exclusive, the day after the last day of leave
+				"DTEND;VALUE=DATE:20260305",
+				`SUMMARY:Annual leave\, Jane Doe`,
+				`ORGANIZER;CN="HR 'Team'":mailto:hr@example.com`,
+				`ATTENDEE;CN="Jane Doe";ROLE=REQ-PARTICIPANT:mailto:jane@example.com`,
+				"TRANSP:OPAQUE",
+				"STATUS:CONFIRMED",
+			},
+			notWant: []string{"X-WR-CALNAME", "DESCRIPTION", "LAST-MODIFIED"},
+		},
+		{
+			name: "cancel",
+			calendar: Calendar{Method: MethodCancel, Events: []Event{func() Event {
+				e := event
+				e.Cancelled = true
+				return e
+			}()}},
+			want:    []string{"METHOD:CANCEL", "STATUS:CANCELLED"},
+			notWant: []string{"STATUS:CONFIRMED"},
+		},
+		{
+			name: "feed",
+			calendar: Calendar{Name: "Team leave", Events: []Event{func() Event {
+				e := event
+				e.Transparent = true
+				e.LastModified = time.Date(2026, 2, 1, 9, 30, 0, 0, time.UTC)
+				return e
+			}()}},
+			want:    []string{"X-WR-CALNAME:Team leave", "TRANSP:TRANSPARENT", "LAST-MODIFIED:20260201T093000Z"},
+			notWant: []string{"METHOD:"},
+		},
+		{
+			name:     "empty",
+			calendar: Calendar{Method: MethodPublish},
+			want:     []string{"BEGIN:VCALENDAR", "VERSION:2.0", "METHOD:PUBLISH", "END:VCALENDAR"},
+			notWant:  []string{"BEGIN:VEVENT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := tt.calendar.String()
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+				t.Fatalf("calendar is not wrapped in VCALENDAR:\n%s", out)
+			}
+			for _, want := range tt.want {
+				if !containsLine(lines, want) {
+					t.Errorf("missing line %q in:\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("unexpected %q in:\n%s", notWant, out)
+				}
+			}
+		})
+	}
+}
+
+func containsLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,504 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "lms-test"
+	testKeyID    = "test-key"
+)
+
+// testProvider is an OpenID provider served by httptest that signs ID tokens with one RSA key
+type testProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu sync.Mutex
+	// Code challenges and ID tokens of issued authorization codes
+	codes map[string]testGrant
+}
+
+type testGrant struct {
+	challenge string
+	idToken   string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{key: key, codes: make(map[string]testGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                        p.server.URL,
+			AuthorizationEndpoint:         p.server.URL + "/authorize",
+			TokenEndpoint:                 p.server.URL + "/token",
+			JWKSURI:                       p.server.URL + "/jwks",
+			CodeChallengeMethodsSupported: []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, err := NewJSONWebKey(testKeyID, "RS256", &p.key.PublicKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(JSONWebKeySet{Keys: []JSONWebKey{jwk}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		grant, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(Token{AccessToken: "access", TokenType: "Bearer", IDToken: grant.idToken, ExpiresIn: 300})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *testProvider) config() Config {
+	return Config{
+		Issuer:      p.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "https://lms.example.com/api/v1/sso/callback",
+		Scopes:      []string{"openid", "email"},
+	}
+}
+
+// claims returns valid ID token claims for the nonce
+func (p *testProvider) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func (p *testProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestDiscover(t *testing.T) {
+	p := newTestProvider(t)
+
+	provider, err := Discover(context.Background(), p.server.Client(), p.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Metadata().TokenEndpoint != p.server.URL+"/token" {
+		t.Errorf("token endpoint = %q", provider.Metadata().TokenEndpoint)
+	}
+
+	config := p.config()
+	config.Issuer = p.server.URL + "/"
+	if _, err := Discover(context.Background(), p.server.Client(), config); err == nil {
+		t.Error("Discover() accepted an issuer that does not match exactly")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	p := newTestProvider(t)
+	provider, err := Discover(context.Background(), p.server.Client(), p.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const nonce = "nonce-1"
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		nonce   string
+		wantErr bool
+		check   func(t *testing.T, claims *Claims)
+	}{
+		{
+			name:  "valid",
+			token: func() string { return p.sign(t, p.claims(nonce)) },
+			nonce: nonce,
+			check: func(t *testing.T, claims *Claims) {
+				if claims.Subject != "user-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+					t.Errorf("claims = %+v", claims)
+				}
+			},
+		},
+		{
+			name: "email_verified as a string",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["email_verified"] = "true"
+				return p.sign(t, claims)
+			},
+			nonce: nonce,
+			check: func(t *testing.T, claims *Claims) {
+				if !claims.EmailVerified {
+					t.Error("email_verified \"true\" was not accepted")
+				}
+			},
+		},
+		{
+			name: "several audiences naming this client as authorized party",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["aud"] = []string{testClientID, "other"}
+				claims["azp"] = testClientID
+				return p.sign(t, claims)
+			},
+			nonce: nonce,
+		},
+		{
+			name: "several audiences without authorized party",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["aud"] = []string{testClientID, "other"}
+				return p.sign(t, claims)
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name:    "wrong nonce",
+			token:   func() string { return p.sign(t, p.claims(nonce)) },
+			nonce:   "nonce-2",
+			wantErr: true,
+		},
+		{
+			name:    "missing nonce",
+			token:   func() string { return p.sign(t, p.claims("")) },
+			nonce:   "",
+			wantErr: true,
+		},
+		{
+			name: "other audience",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["aud"] = "other"
+				return p.sign(t, claims)
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "other issuer",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["iss"] = "https://evil.example.com"
+				return p.sign(t, claims)
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "expired beyond leeway",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["exp"] = time.Now().Add(-2 * time.Minute).Unix()
+				return p.sign(t, claims)
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "expired within leeway",
+			token: func() string {
+				claims := p.claims(nonce)
+				claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+				return p.sign(t, claims)
+			},
+			nonce: nonce,
+		},
+		{
+			name: "no expiry",
+			token: func() string {
+				claims := p.claims(nonce)
+				delete(claims, "exp")
+				return p.sign(t, claims)
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "no subject",
+			token: func() string {
+				claims := p.claims(nonce)
+				delete(claims, "sub")
+				return p.sign(t, claims)
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "signed by another key",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims(nonce))
+				token.Header["kid"] = testKeyID
+				signed, _ := token.SignedString(otherKey)
+				return signed
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "unknown key ID",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims(nonce))
+				token.Header["kid"] = "rotated-away"
+				signed, _ := token.SignedString(p.key)
+				return signed
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "HMAC with the public key as secret",
+			token: func() string {
+				jwk, _ := NewJSONWebKey(testKeyID, "RS256", &p.key.PublicKey)
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, p.claims(nonce))
+				token.Header["kid"] = testKeyID
+				signed, _ := token.SignedString([]byte(jwk.N))
+				return signed
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "unsigned",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, p.claims(nonce))
+				signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return signed
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+		{
+			name: "tampered payload",
+			token: func() string {
+				parts := strings.Split(p.sign(t, p.claims(nonce)), ".")
+				claims := p.claims(nonce)
+				claims["sub"] = "admin"
+				payload, _ := json.Marshal(claims)
+				parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+				return strings.Join(parts, ".")
+			},
+			nonce:   nonce,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(context.Background(), tt.token(), tt.nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyIDToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, claims)
+			}
+		})
+	}
+}
+
+// TestAuthorizationCodeFlow runs the whole flow against the test provider: discovery, the
+// authorization URL, the code exchange with PKCE and verifying the ID token
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	provider, err := Discover(ctx, p.server.Client(), p.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, _ := NewState()
+	nonce, _ := NewState()
+	verifier, _ := NewCodeVerifier()
+	authURL, err := url.Parse(provider.AuthCodeURL(state, nonce, CodeChallenge(verifier)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          p.config().RedirectURL,
+		"scope":                 "openid email",
+		"state":                 state,
+		"nonce":                 nonce,
+		"code_challenge":        CodeChallenge(verifier),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("authorization URL %s = %q, want %q", key, got, value)
+		}
+	}
+
+	// The provider signs the user in and redirects back with a code
+	issue := func(code string) {
+		p.mu.Lock()
+		p.codes[code] = testGrant{challenge: query.Get("code_challenge"), idToken: p.sign(t, p.claims(query.Get("nonce")))}
+		p.mu.Unlock()
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		verifier string
+		wantErr  bool
+	}{
+		{"valid", "code-1", verifier, false},
+		{"wrong verifier", "code-2", "not-the-verifier", true},
+		{"unknown code", "", verifier, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.code != "" {
+				issue(tt.code)
+			}
+			token, err := provider.Exchange(ctx, tt.code, tt.verifier)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exchange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			claims, err := provider.VerifyIDToken(ctx, token.IDToken, nonce)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != "user-1" {
+				t.Errorf("subject = %q, want user-1", claims.Subject)
+			}
+		})
+	}
+
+	// A code can only be exchanged once
+	issue("code-3")
+	if _, err := provider.Exchange(ctx, "code-3", verifier); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(ctx, "code-3", verifier); err == nil {
+		t.Error("Exchange() accepted a code twice")
+	}
+}
+
+func TestJSONWebKeyRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		alg  string
+		key  interface{}
+	}{
+		{"RSA", "RS256", &rsaKey.PublicKey},
+		{"EC", "ES256", &ecKey.PublicKey},
+		{"Ed25519", "EdDSA", edKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwk, err := NewJSONWebKey("kid", tt.alg, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := jwk.PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(key, tt.key) {
+				t.Errorf("PublicKey() = %v, want %v", key, tt.key)
+			}
+		})
+	}
+
+	if _, err := (JSONWebKey{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}).PublicKey(); err == nil {
+		t.Error("PublicKey() accepted a point that is not on the curve")
+	}
+	if _, err := (JSONWebKey{Kty: "oct"}).PublicKey(); err == nil {
+		t.Error("PublicKey() accepted a symmetric key")
+	}
+}
+
+func TestClaimsStrings(t *testing.T) {
+	claims := &Claims{Raw: map[string]interface{}{
+		"single": "hr",
+		"list":   []interface{}{"hr", 7, "managers"},
+		"number": 7.0,
+	}}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"single", []string{"hr"}},
+		{"list", []string{"hr", "managers"}},
+		{"number", nil},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := claims.Strings(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Strings(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
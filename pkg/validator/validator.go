@@ -22,13 +22,11 @@ func ValidateStruct(s interface{}) error {
 
 func validatePassword(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
+	return len(password) >= 8 && CharacterClasses(password) == 4
+}
 
-	// Check length
-	if len(password) < 8 {
-		return false
-	}
-
-	// Check for at least one uppercase letter
+// CharacterClasses counts how many of upper case, lower case, digits and symbols a password uses
+func CharacterClasses(password string) int {
 	hasUpper := false
 	hasLower := false
 	hasNumber := false
@@ -42,12 +40,18 @@ func validatePassword(fl validator.FieldLevel) bool {
 			hasLower = true
 		case unicode.IsNumber(char):
 			hasNumber = true
-		case unicode.IsPunct(char) || unicode.IsSymbol(char):
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
 			hasSpecial = true
 		}
 	}
 
-	return hasUpper && hasLower && hasNumber && hasSpecial
+	classes := 0
+	for _, has := range []bool{hasUpper, hasLower, hasNumber, hasSpecial} {
+		if has {
+			classes++
+		}
+	}
+	return classes
 }
 
 // Email validation regex
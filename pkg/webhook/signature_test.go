@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"leave.approved"}`)
+
+	first := Sign("secret", 1700000000, body)
+	if first != Sign("secret", 1700000000, body) {
+		t.Fatal("signing the same payload twice gave different signatures")
+	}
+	if len(first) != len("sha256=")+64 {
+		t.Errorf("signature %q is not sha256= followed by 64 hex digits", first)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+	}{
+		{"other secret", "other", 1700000000, body},
+		{"other timestamp", "secret", 1700000001, body},
+		{"other body", "secret", 1700000000, []byte(`{"event":"leave.rejected"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Sign(tt.secret, tt.timestamp, tt.body) == first {
+				t.Error("signature did not change")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"event":"leave.approved"}`)
+	now := time.Now().Unix()
+	nowHeader := strconv.FormatInt(now, 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		wantErr   bool
+	}{
+		{"valid", secret, Sign(secret, now, body), nowHeader, body, false},
+		{"within tolerance", secret, Sign(secret, now-240, body), strconv.FormatInt(now-240, 10), body, false},
+		{"wrong secret", "other", Sign(secret, now, body), nowHeader, body, true},
+		{"tampered body", secret, Sign(secret, now, body), nowHeader, []byte(`{"event":"leave.rejected"}`), true},
+		{"replayed timestamp", secret, Sign(secret, now, body), strconv.FormatInt(now+1, 10), body, true},
+		{"too old", secret, Sign(secret, now-600, body), strconv.FormatInt(now-600, 10), body, true},
+		{"too far ahead", secret, Sign(secret, now+600, body), strconv.FormatInt(now+600, 10), body, true},
+		{"bad timestamp", secret, Sign(secret, now, body), "yesterday", body, true},
+		{"unknown scheme", secret, "sha1=" + Sign(secret, now, body)[len("sha256="):], nowHeader, body, true},
+		{"empty signature", secret, "", nowHeader, body, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, 5*time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}